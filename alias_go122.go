@@ -0,0 +1,27 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.22
+
+package moduledoc
+
+import "go/types"
+
+// unalias returns the type that typ denotes, following any aliases,
+// so that an alias (such as any, or a user's type X = Y) is treated
+// exactly like the type it stands for. Since Go 1.22, the type checker
+// may represent aliases explicitly, with *types.Alias.
+func unalias(typ types.Type) types.Type {
+	return types.Unalias(typ)
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import "strings"
+
+// DiffValues compares the value trees old and new, which should be deeply
+// dereferenced, and returns the changes from old to new: added, removed,
+// and retyped struct fields (or other values), and changed docs and module
+// namespaces. Changes are keyed by path, which is like a config path, where
+// map keys and array or map elements are denoted by "(keys)" and "(elems)".
+// If either value still has a SameAs reference, the references are compared
+// by type name (regardless of version) instead of descending into them.
+func DiffValues(old, new *Value) []Change {
+	var changes []Change
+	diffValues("", old, new, &changes)
+	return changes
+}
+
+func diffValues(path string, old, new *Value, changes *[]Change) {
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		*changes = append(*changes, Change{Path: path, Kind: Added, New: new})
+		return
+	case new == nil:
+		*changes = append(*changes, Change{Path: path, Kind: Removed, Old: old})
+		return
+	}
+
+	if old.Type != new.Type ||
+		old.GoType != new.GoType ||
+		baseTypeName(old.TypeName) != baseTypeName(new.TypeName) ||
+		baseTypeName(old.SameAs) != baseTypeName(new.SameAs) {
+		*changes = append(*changes, Change{Path: path, Kind: TypeChanged, Old: old, New: new})
+		return
+	}
+	if strings.TrimSpace(old.Doc) != strings.TrimSpace(new.Doc) {
+		*changes = append(*changes, Change{Path: path, Kind: DocChanged, Old: old, New: new})
+	}
+	if stringPtrValue(old.ModuleNamespace) != stringPtrValue(new.ModuleNamespace) {
+		*changes = append(*changes, Change{Path: path, Kind: NamespaceChanged, Old: old, New: new})
+	}
+
+	// references were already compared by name
+	if old.SameAs != "" || new.SameAs != "" {
+		return
+	}
+
+	// match struct fields by key, in the order of the old
+	// fields, followed by fields which are only in new
+	newFields := make(map[string]*StructField)
+	for _, sf := range new.StructFields {
+		newFields[sf.Key] = sf
+	}
+	oldFields := make(map[string]*StructField)
+	for _, oldField := range old.StructFields {
+		oldFields[oldField.Key] = oldField
+		var newVal *Value
+		if newField, ok := newFields[oldField.Key]; ok {
+			newVal = newField.Value
+		}
+		diffValues(joinPath(path, oldField.Key), oldField.Value, newVal, changes)
+	}
+	for _, newField := range new.StructFields {
+		if _, ok := oldFields[newField.Key]; !ok {
+			diffValues(joinPath(path, newField.Key), nil, newField.Value, changes)
+		}
+	}
+
+	diffValues(joinPath(path, "(keys)"), old.MapKeys, new.MapKeys, changes)
+	diffValues(joinPath(path, "(elems)"), old.Elems, new.Elems, changes)
+}
+
+// Change describes a difference between two values.
+type Change struct {
+	// The path to the value that changed.
+	Path string `json:"path"`
+
+	// The kind of change.
+	Kind ChangeKind `json:"kind"`
+
+	// The value before and after the change; Old is
+	// nil if the value was added, and New is nil if
+	// it was removed.
+	Old *Value `json:"old,omitempty"`
+	New *Value `json:"new,omitempty"`
+}
+
+// ChangeKind is a kind of change between two values.
+type ChangeKind string
+
+// Kinds of changes between values.
+const (
+	Added            ChangeKind = "added"
+	Removed          ChangeKind = "removed"
+	TypeChanged      ChangeKind = "type_changed"
+	DocChanged       ChangeKind = "doc_changed"
+	NamespaceChanged ChangeKind = "namespace_changed"
+)
+
+// joinPath appends part to path, with a separator if needed,
+// escaping it as a config path segment (see JoinConfigPath).
+func joinPath(path, part string) string {
+	part = escapeConfigPathPart(part)
+	if path == "" {
+		return part
+	}
+	return path + "/" + part
+}
+
+// stringPtrValue returns the value s points to,
+// or empty string if s is nil.
+func stringPtrValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	ns, otherNS := "http.handlers", "http.matchers"
+
+	type diff struct {
+		path string
+		kind ChangeKind
+	}
+	for i, tc := range []struct {
+		old, new *Value
+		expect   []diff
+	}{
+		{
+			old: &Value{Type: String, Doc: "A string."},
+			new: &Value{Type: String, Doc: " A string.\n"},
+		},
+		{
+			old:    nil,
+			new:    &Value{Type: String},
+			expect: []diff{{"", Added}},
+		},
+		{
+			old:    &Value{Type: String},
+			new:    &Value{Type: Int},
+			expect: []diff{{"", TypeChanged}},
+		},
+		{
+			old:    &Value{Type: Module, ModuleNamespace: &ns, Doc: "A module."},
+			new:    &Value{Type: Module, ModuleNamespace: &otherNS, Doc: "A matcher."},
+			expect: []diff{{"", DocChanged}, {"", NamespaceChanged}},
+		},
+		{
+			// references are compared by name, regardless of version
+			old: &Value{SameAs: "example.com/foo.Bar@v1.0.0"},
+			new: &Value{SameAs: "example.com/foo.Bar@v1.1.0"},
+		},
+		{
+			old:    &Value{SameAs: "example.com/foo.Bar@v1.0.0"},
+			new:    &Value{SameAs: "example.com/foo.Baz@v1.0.0"},
+			expect: []diff{{"", TypeChanged}},
+		},
+		{
+			old: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "kept", Value: &Value{Type: String}},
+			}},
+			new: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "kept", Value: &Value{Type: String}},
+				{Key: "added", Value: &Value{Type: String}},
+			}},
+			expect: []diff{{"added", Added}},
+		},
+		{
+			old: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "kept", Value: &Value{Type: String}},
+				{Key: "removed", Value: &Value{Type: String}},
+			}},
+			new: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "kept", Value: &Value{Type: String}},
+			}},
+			expect: []diff{{"removed", Removed}},
+		},
+		{
+			old: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "retyped", Value: &Value{Type: String}},
+			}},
+			new: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "retyped", Value: &Value{Type: Int}},
+			}},
+			expect: []diff{{"retyped", TypeChanged}},
+		},
+		{
+			// a field that becomes a reference to a named type
+			old: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "retyped", Value: &Value{Type: String}},
+			}},
+			new: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "retyped", Value: &Value{SameAs: "example.com/foo.Bar"}},
+			}},
+			expect: []diff{{"retyped", TypeChanged}},
+		},
+		{
+			old: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "kept", Value: &Value{Type: String}},
+				{Key: "removed", Value: &Value{Type: String}},
+				{Key: "retyped", Value: &Value{Type: String}},
+			}},
+			new: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "added", Value: &Value{Type: String}},
+				{Key: "retyped", Value: &Value{Type: Bool}},
+				{Key: "kept", Value: &Value{Type: String}},
+			}},
+			expect: []diff{{"removed", Removed}, {"retyped", TypeChanged}, {"added", Added}},
+		},
+		{
+			old: &Value{Type: Map, MapKeys: &Value{Type: String}, Elems: &Value{Type: Array, Elems: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "a/b", Value: &Value{Type: String}},
+			}}}},
+			new: &Value{Type: Map, MapKeys: &Value{Type: Int}, Elems: &Value{Type: Array, Elems: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "a/b", Value: &Value{Type: String, Doc: "Changed."}},
+			}}}},
+			expect: []diff{{"(keys)", TypeChanged}, {`(elems)/(elems)/a\/b`, DocChanged}},
+		},
+	} {
+		var actual []diff
+		for _, change := range DiffValues(tc.old, tc.new) {
+			actual = append(actual, diff{change.Path, change.Kind})
+			if (change.Kind == Added) != (change.Old == nil) || (change.Kind == Removed) != (change.New == nil) {
+				t.Errorf("Test %d: %s change at '%s' has old value %v and new value %v", i, change.Kind, change.Path, change.Old, change.New)
+			}
+		}
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d: expected changes %v, got %v", i, tc.expect, actual)
+		}
+	}
+}
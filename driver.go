@@ -15,9 +15,18 @@
 package moduledoc
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -28,89 +37,858 @@ import (
 //
 // An empty value is not valid; use New to obtain a valid value.
 type Driver struct {
+	// If true, the TypeName of documented types will include
+	// the version of the type's module (fqtn@version), so that
+	// each value self-describes its exact origin. By default,
+	// only SameAs includes the version.
+	VersionedTypeNames bool
+
+	// Build tags to use when listing and loading packages, so
+	// that modules registered in files behind build constraints
+	// can be discovered.
+	BuildTags []string
+
+	// If true, cgo will be enabled when listing and loading
+	// packages. By default, cgo is disabled, which means that
+	// files which import "C" are skipped (and a warning is
+	// logged for packages that have such files).
+	EnableCgo bool
+
+	// Functions, in addition to caddy.RegisterModule, which
+	// register modules when called with the module value.
+	RegistrationFuncs []RegistrationFunc
+
+	// Module types whose IDs are computed at run time, rather
+	// than being static, along with the IDs they are known to
+	// take. Such modules are otherwise skipped, since their IDs
+	// can't be determined from source. For example, core Caddy's
+	// config adapters are all registered as the same type:
+	//
+	//     ComputedModuleID{
+	//         PackagePath: "github.com/caddyserver/caddy/v2/caddyconfig",
+	//         TypeName:    "adapterModule",
+	//         Prefix:      "caddy.adapters.",
+	//         Names:       []string{"caddyfile"},
+	//     }
+	ComputedModuleIDs []ComputedModuleID
+
+	// The maximum duration of each 'go get' invocation,
+	// which is the step that downloads modules. Default:
+	// no timeout.
+	GoGetTimeout time.Duration
+
+	// How many times to retry 'go get' if it fails for
+	// reasons other than the module, version, or package
+	// not existing (e.g. a flaky proxy), with exponential
+	// backoff between attempts. Default: 0 (no retries).
+	GoGetRetries int
+
+	// Types, keyed by their fully-qualified type name, which
+	// are documented as module maps instead of by their Go
+	// structure, because that is how they appear in JSON.
+	ModuleMapTypes map[string]ModuleMapType
+
+	// Types, keyed by their fully-qualified type name, whose
+	// representation is fixed, instead of derived from their
+	// Go structure, because they appear as simple values in
+	// JSON (e.g. types with custom JSON encodings). These are
+	// in addition to, and take precedence over, the
+	// DefaultTypeOverrides.
+	TypeOverrides map[string]*Value
+
+	// If true, comments on the statement that registers a
+	// module (e.g. the caddy.RegisterModule call in init)
+	// are included as supplementary docs for the module.
+	IncludeRegistrationDocs bool
+
+	// If set, each module that is found is passed through this
+	// function before it is associated with its type in storage
+	// (and before it is returned as a loaded module). The module's
+	// Representation is its complete stored type, which can be
+	// changed (e.g. to redact fields), as can its Name (e.g. to
+	// move it to another namespace); the changed type, if any,
+	// is stored. If the function returns false, the module is
+	// dropped; its type remains stored, but not as a module.
+	ModuleFilter func(CaddyModule) (CaddyModule, bool)
+
+	// The names of methods (of module types) whose godocs are
+	// included as supplementary docs for the modules, e.g.
+	// "ServeHTTP" or "Provision"; see CaddyModule.MethodDocs.
+	MethodDocs []string
+
+	// If true, the package doc comment (usually in doc.go)
+	// of the package in which a module is registered is
+	// included as supplementary docs for the module.
+	IncludePackageDocs bool
+
+	// If set, packages are loaded from within this existing
+	// module directory, instead of a temporary workspace into
+	// which modules are downloaded; thus no network access is
+	// needed if the module's dependencies are already present.
+	// If the module has a vendor directory, -mod=vendor is used,
+	// and versions come from the vendored module graph; GOFLAGS
+	// (see Env) can choose the -mod mode explicitly, too. Vendor
+	// mode requires ModuleDir, since nothing can be downloaded.
+	// A go.work file in the directory is honored as usual.
+	ModuleDir string
+
+	// Directories of local modules which are used, with a go.work
+	// file, by the temporary workspace into which modules are
+	// downloaded. Thus, these modules need not be published, and
+	// they resolve each other (and modules that depend on them
+	// resolve them) from their local copies; they are unversioned.
+	// Not used with ModuleDir.
+	WorkspaceModules []string
+
+	// A directory to use as a template for the temporary
+	// workspace into which modules are downloaded, instead
+	// of initializing a new module each time. It should
+	// contain a go.mod (and go.sum) which already requires
+	// common dependencies, such as core Caddy; its contents
+	// are copied into each workspace, and those requirements
+	// are not downloaded again. The template itself is never
+	// modified or removed. Not used with ModuleDir.
+	WorkspaceTemplate string
+
+	// The directory in which temporary workspaces are
+	// created. Default: the OS's temporary directory (see
+	// os.TempDir). Not used with ModuleDir.
+	WorkspaceBaseDir string
+
+	// Additional environment variables (in "KEY=value" form)
+	// for go commands and when loading packages; for example,
+	// GOFLAGS, GOPRIVATE, GONOSUMDB, or GOPROXY.
+	Env []string
+
+	// If true, a struct field whose type representation cannot
+	// be built (for example, because its package has errors)
+	// does not fail the whole load; instead, the field is
+	// documented as an Any value, and the error is recorded
+	// so it can be retrieved with TakeFieldErrors.
+	TolerateFieldErrors bool
+
+	// If true, a type representation that is not well-formed
+	// (see Value.Validate) is not stored, and fails the load;
+	// by default, it is stored anyway, and a warning is logged.
+	RejectInvalidRepresentations bool
+
+	// If true, inconsistencies between the module registrations
+	// and CaddyModule methods of a package (for example, a type
+	// which has a CaddyModule method but is never registered)
+	// do not fail the load of the package; instead, the types
+	// in question are skipped, and reported as diagnostics of
+	// SeverityError (see TakeDiagnostics).
+	TolerateModuleInconsistencies bool
+
+	// The maximum depth to which values are dereferenced when
+	// loading complete type information, as a safeguard against
+	// corrupted or pathologically deep type graphs. Recursive
+	// types do not count against this, since references back
+	// to a type being dereferenced are not followed. Default:
+	// 100.
+	MaxDereferenceDepth int
+
+	// If true, unexported fields of structs are documented
+	// too (with StructField.Unexported set), even though
+	// they are not part of the config. This can be useful
+	// for internal documentation.
+	IncludeUnexportedFields bool
+
+	// If true, values that are fulfilled by modules get the
+	// IDs of the modules in their namespace as candidates
+	// (see Value.Candidates) when they are dereferenced
+	// deeply, e.g. by LoadTypeByPath. The candidates are
+	// only the modules that are stored, so the modules
+	// should be loaded first (see BootstrapCore).
+	IncludeModuleCandidates bool
+
+	// Package paths whose types are not documented: a type from
+	// one of these packages, or any package within one of them
+	// (e.g. a large cloud SDK), is represented as an Any value
+	// with its type name and a note, instead of by its structure.
+	// This keeps representations from growing out of bounds.
+	// ModuleMapTypes and TypeOverrides still apply to such types.
+	ExcludedPackages []string
+
+	// The logger to which warnings are written, for example
+	// about modules or files that are skipped. Default: the
+	// standard logger of the log package.
+	Logger *log.Logger
+
+	// If set, this function is called at milestones of loading
+	// docs, such as each package that is loaded or each type
+	// that is stored, so that progress can be shown during long
+	// loads. It is called synchronously, possibly while the
+	// driver is locked, so it must return quickly and must not
+	// call the driver's methods.
+	OnProgress func(ProgressEvent)
+
+	// If true, only modules registered by the packages that
+	// are matched by the package pattern are loaded, instead
+	// of those registered by any package in their import
+	// graph (which is what happens when they are imported).
+	OnlyMatchedPackages bool
+
+	// If set, godoc of types and struct fields is searched for
+	// a match of this expression, which indicates the version
+	// since which the type or field exists; the version is the
+	// first submatch. The match is removed from the doc, and
+	// the version is recorded in Since instead. For example,
+	// DefaultSinceMarker matches "(since v2.6)".
+	SinceMarker *regexp.Regexp
+
 	db Storage
 
 	// TODO: use this, there's DEFINITELY A CONFIRMED race on discoveredTypes
-	mu sync.RWMutex
+	mu *sync.RWMutex
 
 	// a cache of type definitions we've processed, keyed
 	// by the type's fqtn@version string.
 	discoveredTypes map[string]*Value
+
+	// the version of the Caddy core module that was
+	// in the import graph of the most recent load
+	resolvedCoreVersion string
+
+	// the version of the module of the most recently loaded package
+	resolvedVersion string
+
+	// errors recorded because of TolerateFieldErrors
+	fieldErrors []FieldError
+
+	// diagnostics about the modules of loaded packages
+	diagnostics []Diagnostic
 }
 
+// DefaultSinceMarker is a since marker (see Driver.SinceMarker)
+// which matches a parenthesized "since" followed by a version,
+// e.g. "(since v2.6)" or "(Since: 2.6.0)".
+var DefaultSinceMarker = regexp.MustCompile(`\(\s*[Ss]ince:?\s+(v?[0-9]+(?:\.[0-9]+)*)\s*\)`)
+
+// defaultMaxDereferenceDepth is the default value of
+// Driver.MaxDereferenceDepth.
+const defaultMaxDereferenceDepth = 100
+
 // New constructs a new documentation system.
 func New(database Storage) *Driver {
 	return &Driver{
 		db:              database,
+		mu:              new(sync.RWMutex),
 		discoveredTypes: make(map[string]*Value),
 	}
 }
 
+// logger returns the logger to write warnings to.
+func (d *Driver) logger() *log.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return log.Default()
+}
+
 // LoadModulesFromImportingPackage returns the Caddy modules (plugins) registered when
-// package at its given version is imported.
+// package at its given version is imported. Like with 'go get', the version may be
+// a query rather than an exact version, such as a branch name or a commit hash; the
+// types are stored by the version it resolves to (see ResolvedVersion).
 func (d *Driver) LoadModulesFromImportingPackage(packagePattern, version string) ([]CaddyModule, error) {
+	var allModules []CaddyModule
+	err := d.LoadModulesFromImportingPackageFunc(packagePattern, version, func(mod CaddyModule) error {
+		// TODO: remove duplicates?
+		allModules = append(allModules, mod)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allModules, nil
+}
+
+// LoadModulesFromImportingPackageFunc is like LoadModulesFromImportingPackage,
+// except that instead of returning all the modules at the end, it calls fn for
+// each module as soon as it is loaded, which uses less memory and allows the
+// caller to stream results. If fn returns an error, loading stops and the
+// error is returned.
+func (d *Driver) LoadModulesFromImportingPackageFunc(packagePattern, version string, fn func(CaddyModule) error) error {
+	ws, err := d.openWorkspace()
+	if err != nil {
+		return fmt.Errorf("opening workspace: %w", err)
+	}
+	defer ws.Close()
+
+	return d.loadModules(ws, packagePattern, version, fn)
+}
+
+// NamespacesForPackage loads the modules registered when the package(s) at
+// packagePattern and version are imported, as LoadModulesFromImportingPackage
+// does, and returns the distinct namespaces of their module IDs, sorted. The
+// root namespace is empty string.
+func (d *Driver) NamespacesForPackage(packagePattern, version string) ([]string, error) {
+	seen := make(map[string]bool)
+	var namespaces []string
+	err := d.LoadModulesFromImportingPackageFunc(packagePattern, version, func(mod CaddyModule) error {
+		namespace, _ := ParseModuleID(mod.Name)
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// LoadModulesFromLocalDir returns the Caddy modules registered by the
+// packages of the Go module in dir, which is a module on disk that need
+// not be published, for example a plugin in development. The packages
+// are loaded from within dir, so its dependencies are resolved by its
+// own go.mod; the module itself has no version.
+func (d *Driver) LoadModulesFromLocalDir(dir string) ([]CaddyModule, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving directory %s: %w", dir, err)
+	}
+	ws := d.newWorkspace(absDir, true)
+	defer ws.Close()
+
+	var allModules []CaddyModule
+	err = d.loadModules(ws, "./...", "", func(mod CaddyModule) error {
+		allModules = append(allModules, mod)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allModules, nil
+}
+
+// LoadModulesFromModule returns the Caddy modules registered by the
+// packages of the Go module at modulePath and version, e.g. a plugin.
+// This is like LoadModulesFromImportingPackage with the pattern
+// "modulePath/...", except that only the modules registered by the
+// packages of the module itself are loaded (not those of its
+// dependencies, nor of modules nested within it). modulePath must be
+// the path of the module, not of a package within it.
+func (d *Driver) LoadModulesFromModule(modulePath, version string) ([]CaddyModule, error) {
 	ws, err := d.openWorkspace()
 	if err != nil {
-		return nil, fmt.Errorf("opening workspace: %v", err)
+		return nil, fmt.Errorf("opening workspace: %w", err)
 	}
 	defer ws.Close()
 
+	var ownPackages int
+	inModule := func(pkg *packages.Package) bool {
+		if pkg.Module == nil || pkg.Module.Path != modulePath {
+			return false
+		}
+		ownPackages++
+		return true
+	}
+
+	var allModules []CaddyModule
+	err = d.loadModulesOf(ws, modulePath+"/...", version, inModule, func(mod CaddyModule) error {
+		allModules = append(allModules, mod)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ownPackages == 0 {
+		return nil, fmt.Errorf("%w: no packages of module %s (is it the path of a module?)", ErrNoPackages, modulePath)
+	}
+	return allModules, nil
+}
+
+// loadModules loads the modules registered when the packages matched by
+// packagePattern at version are imported into ws, calling fn for each one.
+func (d *Driver) loadModules(ws workspace, packagePattern, version string, fn func(CaddyModule) error) error {
+	return d.loadModulesOf(ws, packagePattern, version, nil, fn)
+}
+
+// loadModulesOf is like loadModules, but if include is not nil, only
+// the modules registered by the packages for which it returns true
+// are loaded.
+func (d *Driver) loadModulesOf(ws workspace, packagePattern, version string,
+	include func(*packages.Package) bool, fn func(CaddyModule) error) error {
 	pkgs, err := ws.getPackages(packagePattern, version)
 	if err != nil {
-		return nil, fmt.Errorf("loading package %s: %v", packagePattern, err)
+		return fmt.Errorf("loading package %s: %w", packagePattern, err)
+	}
+
+	if err := ws.preloadDepVersions(pkgs); err != nil {
+		return fmt.Errorf("resolving dependency versions of %s: %w", packagePattern, err)
 	}
 
 	rb := ws.representationBuilder()
 
-	var allModules []CaddyModule
+	var coreVersion, resolvedVersion string
+	if pkgs[0].Module != nil {
+		resolvedVersion = pkgs[0].Module.Version
+	}
+
+	matched := make(map[*packages.Package]bool)
+	for _, pkg := range pkgs {
+		matched[pkg] = true
+	}
 
 	var visitErr error
 	packages.Visit(pkgs, func(pkg *packages.Package) bool {
 		return visitErr == nil
 	}, func(pkg *packages.Package) {
-		pkgModules, err := rb.loadModulesFromSinglePackage(pkg)
-		if err != nil {
-			visitErr = err
+		if pkg.PkgPath == CaddyCorePackage && pkg.Module != nil {
+			coreVersion = pkg.Module.Version
+		}
+
+		if visitErr != nil || (d.OnlyMatchedPackages && !matched[pkg]) || (include != nil && !include(pkg)) {
 			return
 		}
-		// TODO: remove duplicates?
-		allModules = append(allModules, pkgModules...)
+		if visitErr = ws.ctx.Err(); visitErr != nil {
+			return
+		}
+		visitErr = rb.loadModulesFromSinglePackage(pkg, fn)
 	})
 	if visitErr != nil {
-		return nil, visitErr
+		return visitErr
 	}
 
-	return allModules, nil
+	d.mu.Lock()
+	d.resolvedCoreVersion = coreVersion
+	d.resolvedVersion = resolvedVersion
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Ingest loads the modules of each of the given packages, in order, as with
+// LoadModulesFromImportingPackage. It is intended for bulk ingests, which
+// can take a long time, so it can be canceled with ctx, which also cancels
+// the go commands that are running; and, if checkpoint is not nil, progress
+// is recorded after each package so that a subsequent ingest (after a
+// failure, cancellation, or restart) can resume where it left off by
+// skipping packages already done. A package that was being loaded when
+// ctx was canceled is not marked done, so it is loaded again on resume.
+func (d *Driver) Ingest(ctx context.Context, pkgs []PackageVersion, checkpoint Checkpoint) error {
+	for _, pv := range pkgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if checkpoint != nil {
+			done, err := checkpoint.Done(pv.Pattern, pv.Version)
+			if err != nil {
+				return fmt.Errorf("checking ingest progress of %s@%s: %w", pv.Pattern, pv.Version, err)
+			}
+			if done {
+				continue
+			}
+		}
+		err := d.ingest(ctx, pv)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the error may be from a go command that was killed
+			err = ctxErr
+		}
+		if err != nil {
+			return fmt.Errorf("ingesting %s@%s: %w", pv.Pattern, pv.Version, err)
+		}
+		if checkpoint != nil {
+			if err := checkpoint.MarkDone(pv.Pattern, pv.Version); err != nil {
+				return fmt.Errorf("recording ingest progress of %s@%s: %w", pv.Pattern, pv.Version, err)
+			}
+		}
+	}
+	return nil
 }
 
-func (rb representationBuilder) loadModulesFromSinglePackage(pkg *packages.Package) ([]CaddyModule, error) {
-	caddyModuleIdents, err := rb.ws.driver.findCaddyModuleIdents(pkg)
+// ingest loads the modules of the package(s) pv in a workspace of its own,
+// whose go commands are canceled when ctx is.
+func (d *Driver) ingest(ctx context.Context, pv PackageVersion) error {
+	ws, err := d.openWorkspaceContext(ctx)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("opening workspace: %w", err)
+	}
+	defer ws.Close()
+
+	return d.loadModules(ws, pv.Pattern, pv.Version, func(CaddyModule) error { return nil })
+}
+
+// PackageVersion is a package pattern and a version of it.
+type PackageVersion struct {
+	Pattern string
+	Version string
+}
+
+// Checkpoint records the progress of a bulk ingest, so that it can be
+// resumed. It may be implemented by a Storage implementation, or be
+// provided separately. A package pattern and version should only be
+// marked done once all of its modules have been stored.
+type Checkpoint interface {
+	// Done returns true if the package pattern at the
+	// given version was already marked done.
+	Done(pattern, version string) (bool, error)
+
+	// MarkDone records that the package pattern at the
+	// given version has been ingested.
+	MarkDone(pattern, version string) error
+}
+
+// ResolvedCoreVersion returns the version of the Caddy core module that
+// was resolved in the dependency graph during the most recent call to
+// LoadModulesFromImportingPackage. This is the version of core Caddy
+// (and thus its Config type and module namespaces) that the loaded
+// modules were built against. It returns empty string if no load has
+// happened yet or if the loaded package did not depend on core Caddy.
+func (d *Driver) ResolvedCoreVersion() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.resolvedCoreVersion
+}
+
+// ResolvedVersion returns the version of the module of the package that was
+// loaded by the most recent call to LoadModulesFromImportingPackage, i.e. the
+// version that the version given to it resolved to. For example, a commit
+// hash or branch name resolves to a pseudo-version like
+// "v0.0.0-20220101000000-abcdef123456". This is the version by which the
+// package's types are stored. It returns empty string if no load has happened
+// yet, or if the package's module has no version (e.g. a local module).
+func (d *Driver) ResolvedVersion() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.resolvedVersion
+}
+
+// TakeFieldErrors returns the field errors that were recorded because
+// TolerateFieldErrors is enabled, since the last call to TakeFieldErrors.
+func (d *Driver) TakeFieldErrors() []FieldError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fieldErrors := d.fieldErrors
+	d.fieldErrors = nil
+	return fieldErrors
+}
+
+// TakeDiagnostics returns the diagnostics about problems with the Caddy
+// modules of loaded packages that did not stop them from being loaded
+// (see Diagnostic), since the last call to TakeDiagnostics.
+func (d *Driver) TakeDiagnostics() []Diagnostic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diags := d.diagnostics
+	d.diagnostics = nil
+	return diags
+}
+
+// recordDiagnostics logs the diagnostics found in pkg, and
+// keeps them so they can be retrieved with TakeDiagnostics.
+func (d *Driver) recordDiagnostics(pkg *packages.Package, diags []Diagnostic) {
+	if len(diags) == 0 {
+		return
+	}
+	for _, diag := range diags {
+		position := token.Position{Filename: diag.File, Line: diag.Line, Column: diag.Column}
+		d.logger().Printf("[%s] %s: %s: %s", strings.ToUpper(string(diag.Severity)), pkg.PkgPath, position, diag.Message)
+	}
+	d.mu.Lock()
+	d.diagnostics = append(d.diagnostics, diags...)
+	d.mu.Unlock()
+}
+
+// ModuleInfo returns information about the package at packagePattern
+// and version, and its module, as reported by 'go list'. If the pattern
+// matches more than one package, the information is about the package
+// at the root of the pattern.
+func (d *Driver) ModuleInfo(packagePattern, version string) (ModuleInfo, error) {
+	ws, err := d.openWorkspace()
+	if err != nil {
+		return ModuleInfo{}, fmt.Errorf("opening workspace: %w", err)
+	}
+	defer ws.Close()
+
+	ws.mu.Lock()
+	err = ws.getModule(packagePattern, version)
+	ws.mu.Unlock()
+	if err != nil {
+		return ModuleInfo{}, fmt.Errorf("getting module of %s: %w", packagePattern, err)
+	}
+
+	pkgInfo, err := ws.runGoList(packagePattern)
+	if err != nil {
+		return ModuleInfo{}, fmt.Errorf("listing package %s: %w", packagePattern, err)
+	}
+	return ModuleInfo{
+		ImportPath:      pkgInfo.ImportPath,
+		Name:            pkgInfo.Name,
+		Dir:             pkgInfo.Dir,
+		Standard:        pkgInfo.Standard,
+		ModulePath:      pkgInfo.Module.Path,
+		ModuleVersion:   pkgInfo.Module.Version,
+		ModuleDir:       pkgInfo.Module.Dir,
+		ModuleGoVersion: pkgInfo.Module.GoVersion,
+		ModuleTime:      pkgInfo.Module.Time,
+		ReplacePath:     pkgInfo.Module.Replace.Path,
+		ReplaceDir:      pkgInfo.Module.Replace.Dir,
+		Stale:           pkgInfo.Stale,
+		StaleReason:     pkgInfo.StaleReason,
+		GoFiles:         pkgInfo.GoFiles,
+		IgnoredGoFiles:  pkgInfo.IgnoredGoFiles,
+		Imports:         pkgInfo.Imports,
+		Deps:            pkgInfo.Deps,
+	}, nil
+}
+
+// ModuleInfo is information about a package and its module.
+type ModuleInfo struct {
+	// The package's import path, name, and directory
+	// on disk, and whether it is in the standard library.
+	ImportPath string `json:"import_path,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Dir        string `json:"dir,omitempty"`
+	Standard   bool   `json:"standard,omitempty"`
+
+	// The path, version, and directory of the package's module,
+	// the minimum Go version it declares, and when the version
+	// was published.
+	ModulePath      string    `json:"module_path,omitempty"`
+	ModuleVersion   string    `json:"module_version,omitempty"`
+	ModuleDir       string    `json:"module_dir,omitempty"`
+	ModuleGoVersion string    `json:"module_go_version,omitempty"`
+	ModuleTime      time.Time `json:"module_time,omitempty"`
+
+	// If the module is replaced, the path of the replacement
+	// module, or its directory if it is replaced by a directory.
+	ReplacePath string `json:"replace_path,omitempty"`
+	ReplaceDir  string `json:"replace_dir,omitempty"`
+
+	// Whether the package would be rebuilt by 'go install', and why.
+	Stale       bool   `json:"stale,omitempty"`
+	StaleReason string `json:"stale_reason,omitempty"`
+
+	// The package's Go source files (excluding tests), the ones
+	// ignored because of build constraints, the packages it
+	// imports directly, and all of its transitive dependencies.
+	GoFiles        []string `json:"go_files,omitempty"`
+	IgnoredGoFiles []string `json:"ignored_go_files,omitempty"`
+	Imports        []string `json:"imports,omitempty"`
+	Deps           []string `json:"deps,omitempty"`
+}
+
+// loadModulesFromSinglePackage loads the modules in pkg,
+// calling fn for each one.
+func (rb representationBuilder) loadModulesFromSinglePackage(pkg *packages.Package, fn func(CaddyModule) error) error {
+	caddyModuleIdents, diags, err := rb.ws.driver.findCaddyModuleIdents(pkg)
+	rb.ws.driver.recordDiagnostics(pkg, diags)
+	if err != nil {
+		return err
+	}
+
+	var packageDoc string
+	if rb.ws.driver.IncludePackageDocs {
+		packageDoc = packageDocComment(pkg)
 	}
 
-	var modules []CaddyModule
-	for ident, caddyModName := range caddyModuleIdents {
+	for ident, modDecl := range caddyModuleIdents {
+		caddyModName := modDecl.id
 		caddyModuleObj := pkg.TypesInfo.Uses[ident]
 
-		rep, err := rb.buildRepresentation(caddyModuleObj.Type())
-		if err != nil {
-			return nil, err
+		var rep *Value
+		var typeName, typeVersion string
+		var typePos token.Pos
+		var caddyModuleType types.Type
+		if caddyModuleObj != nil {
+			// a module type may be an instance of a generic type, in
+			// which case it is the instance that we want to document
+			caddyModuleType = unalias(caddyModuleObj.Type())
+			if inst, ok := pkg.TypesInfo.Instances[ident]; ok {
+				caddyModuleType = inst.Type
+			}
+			rep, err = rb.buildRepresentation(caddyModuleType)
+		} else {
+			err = fmt.Errorf("no type information for %s", ident.Name)
+		}
+		if caddyModuleObj == nil || (err != nil && hasTypeErrors(pkg)) {
+			// the package has type errors, which probably got in the way;
+			// rather than leaving the module out of the docs altogether,
+			// document what we can from the syntax alone
+			rb.ws.driver.logger().Printf("[WARNING] %s: unable to resolve type of module %s (%v); documenting it partially from source",
+				pkg.PkgPath, caddyModName, err)
+			typeName = ident.Name
+			if pkg.Module != nil {
+				typeVersion = pkg.Module.Version
+			}
+			rep, typePos, err = rb.partialRepresentation(pkg, typeName, typeVersion)
+			if err != nil {
+				return err
+			}
+		} else {
+			if err != nil {
+				return err
+			}
+
+			typeName = localTypeName(caddyModuleType)
+			typePos = caddyModuleObj.Pos()
+
+			// the module name is associated with the same version of
+			// the type as the one its representation is stored with
+			if named, ok := caddyModuleType.(*types.Named); ok {
+				typeVersion, err = rb.getDepVersion(named)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// a module whose ID is computed is documented with each of its
+		// known IDs; since a type is associated with only one module
+		// name, each of them gets a copy of the type of its own
+		caddyModNames := []string{caddyModName}
+		if len(modDecl.computedIDs) > 0 {
+			caddyModNames = modDecl.computedIDs
 		}
+		for _, caddyModName := range caddyModNames {
+			modRep, modTypeName := rep, typeName
+			if len(modDecl.computedIDs) > 0 {
+				modTypeName, modRep, err = rb.storeComputedModuleType(pkg, typeName, typeVersion, rep, caddyModName)
+				if err != nil {
+					return err
+				}
+			}
+
+			mod := CaddyModule{
+				Name:            caddyModName,
+				Representation:  modRep,
+				RegistrationDoc: modDecl.registrationDoc,
+				MethodDocs:      modDecl.methodDocs,
+				PackageDoc:      packageDoc,
+			}
+			mod.SourceFile, mod.ModulePath, mod.ModuleVersion = sourceLocation(pkg, typePos)
+
+			if filter := rb.ws.driver.ModuleFilter; filter != nil {
+				var keep bool
+				mod, keep, err = rb.filterModule(filter, pkg, modTypeName, typeVersion, mod)
+				if err != nil {
+					return err
+				}
+				if !keep {
+					continue
+				}
+				caddyModName = mod.Name
+			}
+
+			err = rb.ws.driver.setCaddyModuleName(pkg, modTypeName, typeVersion, caddyModName)
+			if errors.Is(err, ErrModuleNameConflict) {
+				// don't let one inconsistent module spoil the whole load
+				rb.ws.driver.recordDiagnostics(pkg, []Diagnostic{newDiagnostic(pkg.Fset, SeverityWarning, typePos,
+					"%s: not associating type with Caddy module name %s: %v", modTypeName, caddyModName, err)})
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("saving Caddy module name to type: %w", err)
+			}
+
+			rb.ws.driver.progress(ProgressEvent{
+				Kind:        ProgressModuleDiscovered,
+				PackagePath: pkg.PkgPath,
+				TypeName:    modTypeName,
+				Version:     typeVersion,
+				ModuleName:  caddyModName,
+			})
+
+			if err := fn(mod); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		typeName := localTypeName(caddyModuleObj.Type())
+// filterModule passes mod, whose type is typeName in pkg at version,
+// through filter with its complete stored type as its representation.
+// If filter changes the type, the changed type is stored instead. It
+// returns the filtered module, with its original representation (which
+// refers to the stored type), and whether to keep it.
+func (rb representationBuilder) filterModule(filter func(CaddyModule) (CaddyModule, bool),
+	pkg *packages.Package, typeName, version string, mod CaddyModule) (CaddyModule, bool, error) {
+	stored, err := rb.ws.driver.dereference(mod.Representation)
+	if err != nil {
+		return mod, false, fmt.Errorf("loading type of module %s: %w", mod.Name, err)
+	}
+	fingerprint := stored.Fingerprint()
 
-		modules = append(modules, CaddyModule{
-			Name:           caddyModName,
-			Representation: rep,
-		})
+	unfiltered := mod
+	unfiltered.Representation = stored.Clone()
+	filtered, keep := filter(unfiltered)
+	if !keep {
+		return mod, false, nil
+	}
 
-		err = rb.ws.driver.db.SetCaddyModuleName(pkg, typeName, caddyModName)
+	if filtered.Representation != nil && filtered.Representation.Fingerprint() != fingerprint {
+		err := rb.ws.driver.storeType(pkg.PkgPath, typeName, version, filtered.Representation)
 		if err != nil {
-			return nil, fmt.Errorf("saving Caddy module name to type: %v", err)
+			return mod, false, fmt.Errorf("storing filtered type of module %s: %w", filtered.Name, err)
+		}
+		if sameAs := mod.Representation.SameAs; sameAs != "" {
+			rb.ws.driver.discoveredTypes[sameAs] = filtered.Representation
+		}
+	}
+	filtered.Representation = mod.Representation
+
+	return filtered, true, nil
+}
+
+// storeComputedModuleType stores a copy of rep, the representation of
+// typeName in pkg at version, for the module ID id, which is one of the
+// IDs of that computed-ID module type (see ComputedModuleID). The copy's
+// type name has the ID in brackets, e.g. "adapterModule[caddy.adapters.json]".
+// It returns that type name and a reference to the copy.
+func (rb representationBuilder) storeComputedModuleType(pkg *packages.Package, typeName, version string,
+	rep *Value, id string) (string, *Value, error) {
+	idTypeName := typeName + "[" + id + "]"
+	if rep == nil {
+		return idTypeName, nil, nil
+	}
+
+	stored, err := rb.ws.driver.dereference(rep)
+	if err != nil {
+		return "", nil, fmt.Errorf("loading type of module %s: %w", id, err)
+	}
+	stored = stored.Clone()
+
+	sameAs := pkg.PkgPath + "." + idTypeName
+	stored.TypeName = sameAs
+	if version != "" {
+		sameAs += "@" + version
+		if rb.ws.driver.VersionedTypeNames {
+			stored.TypeName = sameAs
+		}
+	}
+
+	rb.ws.driver.discoveredTypes[sameAs] = stored
+	if err := rb.ws.driver.storeType(pkg.PkgPath, idTypeName, version, stored); err != nil {
+		return "", nil, fmt.Errorf("storing type of module %s: %w", id, err)
+	}
+	return idTypeName, &Value{SameAs: sameAs}, nil
+}
+
+// packageDocComment returns the doc comment of pkg. Like go/doc, if
+// more than one file has a package comment, they are joined together.
+func packageDocComment(pkg *packages.Package) string {
+	var docs []string
+	for _, file := range pkg.Syntax {
+		if doc := normalizeDoc(file.Doc.Text()); doc != "" {
+			docs = append(docs, doc)
 		}
 	}
-	return modules, nil
+	return strings.Join(docs, "\n\n")
+}
+
+// hasTypeErrors returns true if pkg failed to type-check.
+func hasTypeErrors(pkg *packages.Package) bool {
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.TypeError {
+			return true
+		}
+	}
+	return false
 }
 
 // AddType loads, parses, inspects, and stores the type representation for the given
@@ -119,13 +897,13 @@ func (rb representationBuilder) loadModulesFromSinglePackage(pkg *packages.Packa
 func (d *Driver) AddType(packageName, typeName, version string) (*Value, error) {
 	ws, err := d.openWorkspace()
 	if err != nil {
-		return nil, fmt.Errorf("opening workspace: %v", err)
+		return nil, fmt.Errorf("opening workspace: %w", err)
 	}
 	defer ws.Close()
 
 	pkgs, err := ws.getPackages(packageName, version)
 	if err != nil {
-		return nil, fmt.Errorf("getting package %s: %v", packageName, err)
+		return nil, fmt.Errorf("getting package %s: %w", packageName, err)
 	}
 	if len(pkgs) != 1 {
 		return nil, fmt.Errorf("expected 1 package, but got %d from pattern '%s'", len(pkgs), packageName)
@@ -134,45 +912,261 @@ func (d *Driver) AddType(packageName, typeName, version string) (*Value, error)
 
 	obj := pkg.Types.Scope().Lookup(typeName)
 	if obj == nil {
-		return nil, fmt.Errorf("type %s not found in %s", typeName, packageName)
+		return nil, fmt.Errorf("%w: %s in %s", ErrTypeNotFound, typeName, packageName)
 	}
 
 	rep, err := ws.representationBuilder().buildRepresentation(obj.Type())
 	if err != nil {
-		return nil, fmt.Errorf("building representation of %s: %v", obj.Name(), err)
+		return nil, fmt.Errorf("building representation of %s: %w", obj.Name(), err)
 	}
 
 	return rep, nil
 }
 
+// BootstrapCore initializes the docs for the given version of Caddy: it
+// stores the Config type, which is the root of all config, along with
+// every type reachable from it, and then loads all of Caddy's standard
+// modules (including the apps), since the parts of the config that are
+// provided by modules are only reachable through them. It returns a
+// summary of what is documented as a result.
+func (d *Driver) BootstrapCore(version string) (BootstrapSummary, error) {
+	config, err := d.AddType(CaddyCorePackage, "Config", version)
+	if err != nil {
+		return BootstrapSummary{}, fmt.Errorf("adding Config type: %w", err)
+	}
+	mods, err := d.LoadModulesFromImportingPackage(caddyStandardModulesPackage, version)
+	if err != nil {
+		return BootstrapSummary{}, fmt.Errorf("loading standard modules: %w", err)
+	}
+
+	summary := BootstrapSummary{Modules: len(mods)}
+	seenRefs, seenTypes := make(map[string]bool), make(map[string]bool)
+	if err := d.countReachable(config, seenRefs, seenTypes, &summary); err != nil {
+		return summary, err
+	}
+	for _, mod := range mods {
+		if err := d.countReachable(mod.Representation, seenRefs, seenTypes, &summary); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// BootstrapSummary summarizes what was documented by BootstrapCore.
+type BootstrapSummary struct {
+	// The number of distinct named types reachable from
+	// the Config type or from the standard modules.
+	Types int
+
+	// The number of places in those types where modules
+	// (or maps of modules) go in the config.
+	ModuleSlots int
+
+	// The number of standard modules.
+	Modules int
+}
+
+// countReachable adds the named types and module slots that are reachable
+// from val, following SameAs references, to summary. Types that are in
+// seenTypes, or are referred to by a SameAs in seenRefs, are not counted
+// again; both sets are updated.
+func (d *Driver) countReachable(val *Value, seenRefs, seenTypes map[string]bool, summary *BootstrapSummary) error {
+	if val == nil {
+		return nil
+	}
+	if val.SameAs != "" {
+		if seenRefs[val.SameAs] {
+			return nil
+		}
+		seenRefs[val.SameAs] = true
+		fqtn, version := splitVersion(val.SameAs)
+		typ, err := d.getTypeByFullName(fqtn, version)
+		if err != nil {
+			return fmt.Errorf("loading type %s: %w", val.SameAs, err)
+		}
+		if typ == nil {
+			return fmt.Errorf("%w: %s", ErrTypeNotFound, val.SameAs)
+		}
+		val = typ
+	}
+	if val.TypeName != "" {
+		if seenTypes[val.TypeName] {
+			return nil
+		}
+		seenTypes[val.TypeName] = true
+		summary.Types++
+	}
+	if val.Type == Module || val.Type == ModuleMap {
+		summary.ModuleSlots++
+	}
+	for _, sf := range val.StructFields {
+		if err := d.countReachable(sf.Value, seenRefs, seenTypes, summary); err != nil {
+			return err
+		}
+	}
+	if err := d.countReachable(val.MapKeys, seenRefs, seenTypes, summary); err != nil {
+		return err
+	}
+	return d.countReachable(val.Elems, seenRefs, seenTypes, summary)
+}
+
+// FieldDoc returns the godoc for the struct field named goFieldName (the
+// field's identifier in Go source, not its JSON key) of the struct type
+// typeName in the given package.
+func (d *Driver) FieldDoc(packagePath, typeName, goFieldName, version string) (string, error) {
+	ws, err := d.openWorkspace()
+	if err != nil {
+		return "", fmt.Errorf("opening workspace: %w", err)
+	}
+	defer ws.Close()
+
+	pkgs, err := ws.getPackages(packagePath, version)
+	if err != nil {
+		return "", fmt.Errorf("getting package %s: %w", packagePath, err)
+	}
+	if len(pkgs) != 1 {
+		return "", fmt.Errorf("expected 1 package, but got %d from pattern '%s'", len(pkgs), packagePath)
+	}
+	pkg := pkgs[0]
+
+	obj := pkg.Types.Scope().Lookup(typeName)
+	if obj == nil {
+		return "", fmt.Errorf("%w: %s in %s", ErrTypeNotFound, typeName, packagePath)
+	}
+	if _, ok := unalias(obj.Type()).(*types.Named); !ok {
+		return "", fmt.Errorf("%s is not a named type", typeName)
+	}
+	structType, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return "", fmt.Errorf("%s is not a struct type", typeName)
+	}
+	var found bool
+	for i := 0; i < structType.NumFields(); i++ {
+		if structType.Field(i).Name() == goFieldName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("struct field %s not found in %s", goFieldName, typeName)
+	}
+
+	fieldDocs, err := ws.representationBuilder().getStructFieldGodocs(obj.Type())
+	if err != nil {
+		return "", fmt.Errorf("getting struct field godocs of %s: %w", typeName, err)
+	}
+
+	return fieldDocs[goFieldName], nil
+}
+
 // LoadTypeByPath loads the type representation at the given config path.
 // It returns the exact value at that path and the nearest named type.
 func (d *Driver) LoadTypeByPath(configPath, version string) (exact, nearest *Value, err error) {
-	val, err := d.db.GetTypeByName(CaddyCorePackage, "Config", version)
+	return d.LoadTypeByPathFrom(CaddyCorePackage, "Config", configPath, version)
+}
+
+// LoadTypeByPathFrom is like LoadTypeByPath, except that configPath starts
+// at the type rootType in the package rootPackage, at version, rather than
+// at Caddy's Config struct. This is useful for programs that embed Caddy
+// in their own config, or to document a subsystem on its own. The root type
+// must already be stored (e.g. with AddType).
+func (d *Driver) LoadTypeByPathFrom(rootPackage, rootType, configPath, version string) (exact, nearest *Value, err error) {
+	val, err := d.startType(rootPackage, rootType, version)
 	if err != nil {
-		return nil, nil, fmt.Errorf("getting start type: %v", err)
+		return nil, nil, err
 	}
-	if val == nil {
-		return nil, nil, fmt.Errorf("start type not found")
+	exact, nearest, err = d.TraverseTypeAt(configPath, val, version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("traversing type: %w", err)
+	}
+	exact, err = d.deepDereference(exact)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dereferencing type path %s: %w", configPath, err)
 	}
-	exact, nearest, err = d.TraverseType(configPath, val)
+	return
+}
+
+// LoadTypeByPathFromModule is like LoadTypeByPath, except that configPath
+// starts at the type of the Caddy module with the given ID, rather than at
+// Caddy's Config struct. For example, the path "routes" from the module
+// "http.handlers.subroute" is the routes field of the subroute handler.
+func (d *Driver) LoadTypeByPathFromModule(moduleID, configPath, version string) (exact, nearest *Value, err error) {
+	val, err := d.moduleType(moduleID, version)
 	if err != nil {
-		return nil, nil, fmt.Errorf("traversing type: %v", err)
+		return nil, nil, err
+	}
+	exact, nearest, err = d.TraverseTypeAt(configPath, val, version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("traversing type: %w", err)
 	}
 	exact, err = d.deepDereference(exact)
 	if err != nil {
-		return nil, nil, fmt.Errorf("dereferencing type path %s: %v", configPath, err)
+		return nil, nil, fmt.Errorf("dereferencing type path %s of module %s: %w", configPath, moduleID, err)
 	}
 	return
 }
 
+// moduleType returns the stored type of the Caddy module with the given
+// ID at version (or at any version, if empty). If more than one type is
+// stored for the module, they must all be (versions of) the same type,
+// otherwise it is ambiguous which one is meant.
+func (d *Driver) moduleType(moduleID, version string) (*Value, error) {
+	vals, err := d.getTypesByCaddyModuleID(moduleID, version)
+	if err != nil {
+		return nil, fmt.Errorf("loading type for module %s: %w", moduleID, err)
+	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrModuleNotFound, moduleID)
+	}
+	for _, v := range vals[1:] {
+		if baseTypeName(v.TypeName) != baseTypeName(vals[0].TypeName) {
+			return nil, fmt.Errorf("%w: %s is implemented by both %s and %s",
+				ErrAmbiguousModule, moduleID, vals[0].TypeName, v.TypeName)
+		}
+	}
+	return vals[0], nil // TODO: support multiple values (two modules with same ID)... how? if in the middle, maybe find the one that matches; if at end...? maybe return a slice of them?
+}
+
+// configType returns the type of Caddy's Config struct at the given
+// version, which is the start of all config paths.
+func (d *Driver) configType(version string) (*Value, error) {
+	return d.startType(CaddyCorePackage, "Config", version)
+}
+
+// startType returns the stored type typeName in the package at
+// packagePath, at version, from which config paths are traversed.
+func (d *Driver) startType(packagePath, typeName, version string) (*Value, error) {
+	val, err := d.db.GetTypeByName(packagePath, typeName, version)
+	if err != nil {
+		return nil, fmt.Errorf("getting start type: %w", err)
+	}
+	if val == nil {
+		return nil, fmt.Errorf("%w: start type %s.%s@%s", ErrTypeNotFound, packagePath, typeName, version)
+	}
+	return val, nil
+}
+
 // TraverseType traverses the start value according to path until the
 // end of path is reached or the value is no longer traverseable, in
 // which case it returns an error. On success, it returns the value
 // at the given path, along with its nearest (containing) defined type.
+// A value of type Any may be anything, so it accepts the rest of the
+// path, and is returned as the value at the path. Modules along the
+// path may be stored at any version; use TraverseTypeAt to prefer
+// the modules of a particular version.
 func (d *Driver) TraverseType(path string, start *Value) (val, nearestType *Value, err error) {
+	return d.TraverseTypeAt(path, start, "")
+}
+
+// TraverseTypeAt is like TraverseType, but modules along the path
+// are those stored at version, if any, otherwise at any version
+// (since plugins are versioned separately from the modules that hold
+// them); if version is empty, modules at any version are used. Which
+// modules are of which version is only known if the driver's storage
+// is a VersionedStorage.
+func (d *Driver) TraverseTypeAt(path string, start *Value, version string) (val, nearestType *Value, err error) {
 	if start.Type == "" || start.TypeName == "" {
-		return nil, nil, fmt.Errorf("must start at an actual type")
+		return nil, nil, fmt.Errorf("%w: must start at an actual type", ErrPathNotTraversable)
 	}
 	if path == "" {
 		return start, start, nil
@@ -187,9 +1181,16 @@ func (d *Driver) TraverseType(path string, start *Value) (val, nearestType *Valu
 		part := parts[i]
 
 		// dereference this "pointer" (if it is one) to its actual type
-		val, err = d.dereference(val)
+		derefVal, err := d.dereference(val)
 		if err != nil {
-			return nil, nil, fmt.Errorf("dereferencing type to %s: %v", val.SameAs, err)
+			return nil, nil, fmt.Errorf("dereferencing type to %s: %w", val.SameAs, err)
+		}
+		val = derefVal
+
+		// the part to satisfy is within this type, if it's a defined
+		// one (e.g. the struct type of an array's elements)
+		if val.TypeName != "" {
+			nearestType = val
 		}
 
 		// see if we can satisfy the next part with this type
@@ -197,38 +1198,43 @@ func (d *Driver) TraverseType(path string, start *Value) (val, nearestType *Valu
 		switch val.Type {
 		case Struct:
 			for _, sf := range val.StructFields {
-				if sf.Key == part {
+				if sf.Key == part && !sf.Unexported {
 					val = sf.Value
 					if i == len(parts)-1 {
 						// normally, the doc for the struct field would be irrelevant
 						// while we traverse deeper in the structure, but if we're at
 						// the target, we should include the struct field's docs, which
 						// can provide crucial information that is otherwise missed
-						if val.Doc != "" {
-							val.Doc += "\n\n"
-						}
-						val.Doc += sf.Doc
+						// (on a copy, since the field belongs to the stored type);
+						// like deepDereference, the field doc comes first, and when
+						// val is dereferenced, the type's doc follows it
+						val = val.Clone()
+						val.Doc = joinDocs(sf.Doc, val.Doc)
 					}
 					break typeSwitch
 				}
 			}
-			return nil, nil, fmt.Errorf("struct field '%s' not found at: %s",
-				part, strings.Join(parts[:i], "/"))
+			return nil, nil, fmt.Errorf("%w: struct field '%s' not found at: %s",
+				ErrPathNotTraversable, part, JoinConfigPath(parts[:i]...))
 
 		case Module, ModuleMap:
-			caddyModuleID := part
-			if val.ModuleNamespace != nil && *val.ModuleNamespace != "" {
-				caddyModuleID = *val.ModuleNamespace + "." + part
+			var namespace string
+			if val.ModuleNamespace != nil {
+				namespace = *val.ModuleNamespace
 			}
+			caddyModuleID := JoinModuleID(namespace, part)
 			var moduleInlineKey *string
 			if i == len(parts)-1 {
 				moduleInlineKey = val.ModuleInlineKey
 			}
-			vals, err := d.db.GetTypesByCaddyModuleID(caddyModuleID)
+			modVal, err := d.moduleType(caddyModuleID, version)
+			if errors.Is(err, ErrModuleNotFound) && version != "" {
+				modVal, err = d.moduleType(caddyModuleID, "")
+			}
 			if err != nil {
-				return nil, nil, fmt.Errorf("loading type for module %s: %v", caddyModuleID, err)
+				return nil, nil, err
 			}
-			val = vals[0] // TODO: support multiple values (two modules with same ID)... how? if in the middle, maybe find the one that matches; if at end...? maybe return a slice of them?
+			val = modVal.Clone()
 			val.ModuleInlineKey = moduleInlineKey
 
 		case Map, Array:
@@ -236,9 +1242,18 @@ func (d *Driver) TraverseType(path string, start *Value) (val, nearestType *Valu
 			val = val.Elems
 			i--
 
+		case Any:
+			// arbitrary JSON could have anything below it,
+			// but there is nothing more we know about it
+			// (it may be a defined type with a custom encoding)
+			if val.TypeName != "" {
+				nearestType = val
+			}
+			return val, nearestType, nil
+
 		default:
-			return nil, nil, fmt.Errorf("%s: traversal not supported for type %#v",
-				strings.Join(parts[:i], "/"), val)
+			return nil, nil, fmt.Errorf("%w: %s: traversal not supported for type %#v",
+				ErrPathNotTraversable, JoinConfigPath(parts[:i]...), val)
 		}
 
 		// if this is an actual defined type, we need
@@ -259,20 +1274,129 @@ func (d *Driver) LoadTypesByModuleID(moduleName string) ([]*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrModuleNotFound, moduleName)
+	}
 	for i := range vals {
-		vals[i], err = d.deepDereference(vals[i])
+		derefVal, err := d.deepDereference(vals[i])
 		if err != nil {
-			return nil, fmt.Errorf("dereferencing module type %s: %v", vals[i].TypeName, err)
+			return nil, fmt.Errorf("dereferencing module type %s: %w", vals[i].TypeName, err)
 		}
+		vals[i] = derefVal
 	}
 	return vals, nil
 }
 
+// ResolveType returns the complete type information of the stored type
+// with the given fully-qualified name (e.g. "github.com/caddyserver/caddy/v2.Config")
+// at version. Like LoadTypesByModuleID, it deeply dereferences the type.
+func (d *Driver) ResolveType(fqtn, version string) (*Value, error) {
+	val, err := d.getTypeByFullName(fqtn, version)
+	if err != nil {
+		return nil, fmt.Errorf("getting type %s@%s: %w", fqtn, version, err)
+	}
+	if val == nil {
+		return nil, fmt.Errorf("%w: %s@%s", ErrTypeNotFound, fqtn, version)
+	}
+	derefVal, err := d.deepDereference(val)
+	if err != nil {
+		return nil, fmt.Errorf("dereferencing type %s@%s: %w", fqtn, version, err)
+	}
+	return derefVal, nil
+}
+
+// ListModulesInNamespace returns the Caddy modules in the given namespace
+// (see Storage.GetModuleIDsByNamespace), sorted by module ID. Unlike
+// LoadTypesByModuleID, the representations are as stored, i.e. they
+// are not dereferenced.
+func (d *Driver) ListModulesInNamespace(namespace string) ([]CaddyModule, error) {
+	moduleIDs, err := d.db.GetModuleIDsByNamespace(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("getting modules in namespace '%s': %w", namespace, err)
+	}
+	sort.Strings(moduleIDs)
+
+	var mods []CaddyModule
+	for _, moduleID := range moduleIDs {
+		vals, err := d.db.GetTypesByCaddyModuleID(moduleID)
+		if err != nil {
+			return nil, fmt.Errorf("loading type for module %s: %w", moduleID, err)
+		}
+		for _, val := range vals {
+			mods = append(mods, CaddyModule{
+				Name:           moduleID,
+				Representation: val,
+			})
+		}
+	}
+	return mods, nil
+}
+
 // CaddyModule represents a Caddy module.
 type CaddyModule struct {
 	Name           string `json:"module_name,omitempty"`
 	Representation *Value `json:"structure,omitempty"`
+
+	// The file in which the module's type is defined, relative
+	// to the root of its Go module, and the path and version of
+	// that Go module. The version is empty if the module was
+	// replaced by a local directory.
+	SourceFile    string `json:"source_file,omitempty"`
+	ModulePath    string `json:"module_path,omitempty"`
+	ModuleVersion string `json:"module_version,omitempty"`
+
+	// Comments on the statement that registers the module,
+	// if enabled with Driver.IncludeRegistrationDocs. These
+	// can provide supplementary usage notes.
+	RegistrationDoc string `json:"registration_doc,omitempty"`
+
+	// The godocs of the module type's methods that are named
+	// in Driver.MethodDocs, keyed by method name. These can
+	// describe how the module behaves, e.g. how it handles
+	// requests (ServeHTTP) or what it sets up (Provision).
+	MethodDocs map[string]string `json:"method_docs,omitempty"`
+
+	// The doc comment of the package in which the module
+	// is registered, if enabled with Driver.IncludePackageDocs.
+	// It often gives an overview that the type's godoc lacks.
+	PackageDoc string `json:"package_doc,omitempty"`
+}
+
+// SourceURL returns a URL to view the source of the module's type
+// definition, if its Go module is hosted on a well-known host;
+// otherwise it returns empty string.
+func (cm CaddyModule) SourceURL() string {
+	return SourceURL(cm.ModulePath, cm.ModuleVersion, cm.SourceFile)
+}
+
+// sourceLocation returns the file in which obj is defined, relative to the
+// root of its Go module, along with the module's path and version. If the
+// module was replaced by another module, the replacement's path/version is
+// returned, since that is where the source actually comes from; if replaced
+// by a local directory, the version is empty as there is no public source.
+func sourceLocation(pkg *packages.Package, pos token.Pos) (file, modulePath, moduleVersion string) {
+	if pkg.Module == nil {
+		return
+	}
+	mod := pkg.Module
+	modulePath, moduleVersion = mod.Path, mod.Version
+	if mod.Replace != nil {
+		mod = mod.Replace
+		modulePath, moduleVersion = mod.Path, mod.Version
+		if moduleVersion == "" {
+			modulePath = pkg.Module.Path
+		}
+	}
+	filename := pkg.Fset.Position(pos).Filename
+	if rel, err := filepath.Rel(mod.Dir, filename); err == nil && mod.Dir != "" {
+		file = filepath.ToSlash(rel)
+	}
+	return
 }
 
 // CaddyCorePackage is the import path of the Caddy core package.
 const CaddyCorePackage = "github.com/caddyserver/caddy/v2"
+
+// caddyStandardModulesPackage is the package which, when imported,
+// registers all of Caddy's standard modules.
+const caddyStandardModulesPackage = CaddyCorePackage + "/modules/standard"
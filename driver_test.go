@@ -0,0 +1,1257 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// newTestDriver returns a driver that loads packages from the
+// fixture module in testdata and stores types in memory.
+func newTestDriver(t testing.TB) (*Driver, *MemoryStorage) {
+	t.Helper()
+	db := NewMemoryStorage()
+	return newTestDriverWithStorage(t, db), db
+}
+
+// newTestDriverWithStorage is like newTestDriver, but the
+// driver stores types in db.
+func newTestDriverWithStorage(t testing.TB, db Storage) *Driver {
+	t.Helper()
+	dir, err := filepath.Abs(filepath.Join("testdata", "fixture"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := New(db)
+	d.ModuleDir = dir
+	d.RegistrationFuncs = []RegistrationFunc{
+		{PackagePath: "example.com/fixture/registry", Name: "RegisterModule"},
+	}
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	return d
+}
+
+func TestTraverseTypeVersion(t *testing.T) {
+	d, db := newTestDriver(t)
+
+	// the module's type has a different field in each version
+	ns := "fixture.handlers"
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		root := &Value{
+			Type:     Struct,
+			TypeName: "example.com/fixture.Root",
+			StructFields: []*StructField{
+				{Key: "handler", Value: &Value{Type: Module, ModuleNamespace: &ns}},
+			},
+		}
+		handler := &Value{
+			Type:     Struct,
+			TypeName: "example.com/fixture.Handler",
+			StructFields: []*StructField{
+				{Key: "field_" + version[:2], Value: &Value{Type: String}},
+			},
+		}
+		if err := db.StoreType("example.com/fixture", "Root", version, root); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.StoreType("example.com/fixture", "Handler", version, handler); err != nil {
+			t.Fatal(err)
+		}
+		pkg := &packages.Package{PkgPath: "example.com/fixture"}
+		if err := db.SetCaddyModuleNameAt(pkg, "Handler", version, "fixture.handlers.handler"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, tc := range []struct {
+		path      string
+		version   string
+		expectErr bool
+	}{
+		{path: "handler/handler/field_v1", version: "v1.0.0"},
+		{path: "handler/handler/field_v2", version: "v2.0.0"},
+		{path: "handler/handler/field_v2", version: "v1.0.0", expectErr: true},
+		{path: "handler/handler/field_v1", version: "v2.0.0", expectErr: true},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFrom("example.com/fixture", "Root", tc.path, tc.version)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: %v", i, err)
+			continue
+		}
+		if exact.Type != String {
+			t.Errorf("Test %d: expected a string, got %s", i, exact.Type)
+		}
+		if nearest.TypeName != "example.com/fixture.Handler" {
+			t.Errorf("Test %d: expected nearest type Handler, got %s", i, nearest.TypeName)
+		}
+	}
+}
+
+func TestLoadTypeByPathFromCustomRoot(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Gizmo", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		rootPackage, rootType string
+		path                  string
+		expectType            Type
+		expectNearest         string
+		expectErr             error
+	}{
+		{rootPackage: "example.com/fixture/gizmos", rootType: "Gizmo", path: "", expectType: Struct, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{rootPackage: "example.com/fixture/gizmos", rootType: "Gizmo", path: "parts/size", expectType: Int, expectNearest: "example.com/fixture/gizmos.Part"},
+		{rootPackage: "example.com/fixture/gizmos", rootType: "Gizmo", path: "parts/nope", expectErr: ErrPathNotTraversable},
+
+		// a root type must be stored, unlike core Caddy's Config here
+		{rootPackage: "example.com/fixture/gizmos", rootType: "Widget", path: "name", expectErr: ErrTypeNotFound},
+		{rootPackage: CaddyCorePackage, rootType: "Config", path: "apps", expectErr: ErrTypeNotFound},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFrom(tc.rootPackage, tc.rootType, tc.path, "")
+		if tc.expectErr != nil {
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("Test %d (%s %s): expected error to be %v, got %v", i, tc.rootType, tc.path, tc.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s %s): %v", i, tc.rootType, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType || nearest.TypeName != tc.expectNearest {
+			t.Errorf("Test %d (%s %s): expected %s in %s, got %s in %s",
+				i, tc.rootType, tc.path, tc.expectType, tc.expectNearest, exact.Type, nearest.TypeName)
+		}
+	}
+
+	// LoadTypeByPath starts at core Caddy's Config
+	if _, _, err := d.LoadTypeByPath("apps", ""); !errors.Is(err, ErrTypeNotFound) {
+		t.Errorf("expected LoadTypeByPath to need core Caddy's Config, got %v", err)
+	}
+}
+
+func TestLoadTypeByPathFromModule(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/aliases", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		moduleID      string
+		path          string
+		expectType    Type
+		expectNearest string
+		expectErr     bool
+	}{
+		{moduleID: "fixture.gizmos.gizmo", path: "", expectType: Struct, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{moduleID: "fixture.gizmos.gizmo", path: "name", expectType: String, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{moduleID: "fixture.gizmos.gizmo", path: "parts", expectType: Array, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{moduleID: "fixture.gizmos.gizmo", path: "parts/size", expectType: Int, expectNearest: "example.com/fixture/gizmos.Part"},
+		{moduleID: "fixture.gizmos.gizmo", path: "parts/weight", expectErr: true},
+		{moduleID: "fixture.aliases.widget", path: "", expectType: Struct, expectNearest: "example.com/fixture/aliases.Widget"},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFromModule(tc.moduleID, tc.path, "")
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("Test %d (%s): expected error, got none", i, tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+		if nearest.TypeName != tc.expectNearest {
+			t.Errorf("Test %d (%s): expected nearest type %s, got %s", i, tc.path, tc.expectNearest, nearest.TypeName)
+		}
+	}
+}
+
+func TestLoadTypeByPathFieldDocOnce(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Limited", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		path      string
+		fieldDoc  string
+		expectDoc string // besides the field doc, if any
+	}{
+		{path: "limits", fieldDoc: "Limits from this module.", expectDoc: "Limits limit a gizmo."},
+		{path: "limits/max_parts", fieldDoc: "The maximum number of parts."},
+		{path: "governed/quota", fieldDoc: "The quota.", expectDoc: "Quota is a quota."},
+	} {
+		exact, _, err := d.LoadTypeByPathFrom("example.com/fixture/gizmos", "Limited", tc.path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if count := strings.Count(exact.Doc, tc.fieldDoc); count != 1 {
+			t.Errorf("Test %d (%s): expected the field doc once, got it %d times in %q", i, tc.path, count, exact.Doc)
+		}
+		if !strings.Contains(exact.Doc, tc.expectDoc) {
+			t.Errorf("Test %d (%s): expected the doc to contain %q, got %q", i, tc.path, tc.expectDoc, exact.Doc)
+		}
+	}
+}
+
+func TestFieldDoc(t *testing.T) {
+	d, _ := newTestDriver(t)
+
+	for i, tc := range []struct {
+		typeName  string
+		field     string
+		expect    string
+		expectErr bool
+		errIs     error
+	}{
+		{typeName: "Gizmo", field: "Name", expect: "The name of the gizmo."},
+		{typeName: "Gizmo", field: "Parts", expect: "The gizmo's parts."},
+		{typeName: "Part", field: "Size", expect: "The size of the part."},
+		{typeName: "Gizmo", field: "Size", expectErr: true},
+		{typeName: "Widget", field: "Name", expectErr: true, errIs: ErrTypeNotFound},
+	} {
+		actual, err := d.FieldDoc("example.com/fixture/gizmos", tc.typeName, tc.field, "")
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			} else if tc.errIs != nil && !errors.Is(err, tc.errIs) {
+				t.Errorf("Test %d: expected error to be %v, got %v", i, tc.errIs, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: %v", i, err)
+			continue
+		}
+		if actual != tc.expect {
+			t.Errorf("Test %d: expected doc %q, got %q", i, tc.expect, actual)
+		}
+	}
+}
+
+func TestResolveType(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/versioned", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/gizmos", "Gizmo", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		fqtn      string
+		version   string
+		expectErr error
+	}{
+		{fqtn: "example.com/fixture/gizmos.Gizmo"},
+		{fqtn: "example.com/fixture/versioned.Config"},
+		{fqtn: "example.com/dep.Limits", version: "v1.2.0"},
+		{fqtn: "example.com/dep.Limits", version: "v1.3.0", expectErr: ErrTypeNotFound},
+		{fqtn: "example.com/fixture/gizmos.Widget", expectErr: ErrTypeNotFound},
+	} {
+		val, err := d.ResolveType(tc.fqtn, tc.version)
+		if tc.expectErr != nil {
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("Test %d (%s@%s): expected error to be %v, got %v", i, tc.fqtn, tc.version, tc.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s@%s): %v", i, tc.fqtn, tc.version, err)
+			continue
+		}
+		if val.TypeName != tc.fqtn {
+			t.Errorf("Test %d (%s@%s): expected type %s, got %s", i, tc.fqtn, tc.version, tc.fqtn, val.TypeName)
+		}
+
+		// the type is completely resolved
+		var unresolved []string
+		var walk func(*Value)
+		walk = func(v *Value) {
+			if v == nil {
+				return
+			}
+			if v.SameAs != "" {
+				unresolved = append(unresolved, v.SameAs)
+			}
+			for _, sf := range v.StructFields {
+				walk(sf.Value)
+			}
+			walk(v.MapKeys)
+			walk(v.Elems)
+		}
+		walk(val)
+		if len(unresolved) > 0 {
+			t.Errorf("Test %d (%s@%s): expected no references, got %v", i, tc.fqtn, tc.version, unresolved)
+		}
+	}
+
+	// but the stored type still refers to its parts
+	stored, _ := db.GetTypeByName("example.com/fixture/gizmos", "Gizmo", "")
+	if parts := stored.StructFields[1].Value; parts.Elems == nil || parts.Elems.SameAs == "" {
+		t.Errorf("expected the stored type to be unchanged, got %s", dumpString(stored))
+	}
+}
+
+func TestUnusualVersions(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "versions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewMemoryStorage()
+	d := New(db)
+	d.ModuleDir = dir
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	if _, err := d.AddType("example.com/versions", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := db.GetTypeByName("example.com/versions", "Config", "")
+	if config == nil || len(config.StructFields) != 2 {
+		t.Fatalf("expected Config to be stored with 2 fields, got %v", config)
+	}
+
+	// types of modules required at +incompatible and pseudo-versions
+	// are stored at, and referred to with, those versions
+	for i, tc := range []struct {
+		key, pkg, version string
+	}{
+		{key: "legacy", pkg: "example.com/legacy", version: "v2.0.0+incompatible"},
+		{key: "pseudo", pkg: "example.com/pseudo", version: "v0.0.0-20220101000000-abcdef123456"},
+	} {
+		if rep, _ := db.GetTypeByName(tc.pkg, "Options", tc.version); rep == nil || len(rep.StructFields) != 1 {
+			t.Errorf("Test %d (%s): expected Options to be stored at %s, got %v", i, tc.key, tc.version, rep)
+		}
+		field := config.StructFields[i]
+		if expect := tc.pkg + ".Options@" + tc.version; field.Key != tc.key || field.Value.SameAs != expect {
+			t.Errorf("Test %d (%s): expected field %s to refer to %s, got %s", i, tc.key, tc.key, expect, dumpString(field.Value))
+		}
+	}
+
+	// and the references can be followed
+	resolved, err := d.ResolveType("example.com/versions.Config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		path           string
+		expectType     Type
+		expectTypeName string
+	}{
+		{path: "legacy/name", expectType: String, expectTypeName: "example.com/legacy.Options"},
+		{path: "pseudo/size", expectType: Int, expectTypeName: "example.com/pseudo.Options"},
+	} {
+		field := resolved.StructFields[i].Value
+		if field.TypeName != tc.expectTypeName || len(field.StructFields) != 1 {
+			t.Errorf("Test %d (%s): expected resolved %s, got %s", i, tc.path, tc.expectTypeName, dumpString(field))
+		}
+		val, nearest, err := d.TraverseType(tc.path, config)
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if val.Type != tc.expectType || nearest.TypeName != tc.expectTypeName {
+			t.Errorf("Test %d (%s): expected %s in %s, got %s in %s",
+				i, tc.path, tc.expectType, tc.expectTypeName, val.Type, nearest.TypeName)
+		}
+	}
+}
+
+// unversionedStorage is a Storage which is not a VersionedStorage.
+type unversionedStorage struct {
+	Storage
+}
+
+func TestTraverseTypeUnversionedStorage(t *testing.T) {
+	db := unversionedStorage{NewMemoryStorage()}
+	d := newTestDriverWithStorage(t, db)
+
+	ns := "fixture.handlers"
+	root := &Value{
+		Type:     Struct,
+		TypeName: "example.com/fixture.Root",
+		StructFields: []*StructField{
+			{Key: "handler", Value: &Value{Type: Module, ModuleNamespace: &ns}},
+		},
+	}
+	handler := &Value{
+		Type:     Struct,
+		TypeName: "example.com/fixture.Handler",
+		StructFields: []*StructField{
+			{Key: "field", Value: &Value{Type: String}},
+		},
+	}
+	if err := db.StoreType("example.com/fixture", "Root", "v1.0.0", root); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.StoreType("example.com/fixture", "Handler", "v1.0.0", handler); err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{PkgPath: "example.com/fixture", Module: &packages.Module{Version: "v1.0.0"}}
+	if err := d.setCaddyModuleName(pkg, "Handler", "v1.0.0", "fixture.handlers.handler"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the storage can't tell versions apart, so the module is found
+	// regardless of the version asked for
+	for i, version := range []string{"v1.0.0", "v2.0.0", ""} {
+		exact, _, err := d.TraverseTypeAt("handler/handler/field", root, version)
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, version, err)
+			continue
+		}
+		if exact.Type != String {
+			t.Errorf("Test %d (%s): expected a string, got %s", i, version, exact.Type)
+		}
+	}
+	if _, _, err := d.TraverseType("handler/handler/field", root); err != nil {
+		t.Errorf("expected traversal without a version to succeed, got %v", err)
+	}
+}
+
+func TestTraverseTypeAny(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/encodings", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// a type with a custom JSON encoding could be anything,
+	// so any path into it ends there; but text is a string
+	for i, tc := range []struct {
+		path          string
+		expectType    Type
+		expectNearest string
+		expectErr     bool
+	}{
+		{path: "custom", expectType: Any, expectNearest: "example.com/fixture/encodings.Config"},
+		{path: "custom/value", expectType: Any, expectNearest: "example.com/fixture/encodings.Custom"},
+		{path: "custom/value/deeper/0", expectType: Any, expectNearest: "example.com/fixture/encodings.Custom"},
+		{path: "text", expectType: String, expectNearest: "example.com/fixture/encodings.Config"},
+		{path: "text/value", expectErr: true},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFrom("example.com/fixture/encodings", "Config", tc.path, "")
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("Test %d (%s): expected error, got none", i, tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+		if nearest.TypeName != tc.expectNearest {
+			t.Errorf("Test %d (%s): expected nearest type %s, got %s", i, tc.path, tc.expectNearest, nearest.TypeName)
+		}
+	}
+}
+
+func TestTraverseTypeModuleMapType(t *testing.T) {
+	d, _ := newTestDriver(t)
+	d.ModuleMapTypes = map[string]ModuleMapType{
+		"example.com/fixture/encodings.Gizmos": {Namespace: "fixture.gizmos"},
+	}
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/encodings", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wrapper's custom encoding is keyed by module
+	// name, so modules are resolved through it
+	for i, tc := range []struct {
+		path          string
+		expectType    Type
+		expectNearest string
+	}{
+		{path: "gizmos", expectType: ModuleMap, expectNearest: "example.com/fixture/encodings.Gizmos"},
+		{path: "gizmos/gizmo", expectType: Struct, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{path: "gizmos/gizmo/name", expectType: String, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{path: "gizmos/gizmo/parts", expectType: Array, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFrom("example.com/fixture/encodings", "Config", tc.path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+		if nearest.TypeName != tc.expectNearest {
+			t.Errorf("Test %d (%s): expected nearest type %s, got %s", i, tc.path, tc.expectNearest, nearest.TypeName)
+		}
+	}
+	if _, _, err := d.LoadTypeByPathFrom("example.com/fixture/encodings", "Config", "gizmos/nope", ""); err == nil {
+		t.Error("expected error for a module that isn't in the namespace, got none")
+	}
+}
+
+func TestTraverseTypeNestedModuleMaps(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/encodings", "Containers", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the modules in the maps are found through the arrays
+	// around them, since the namespace is on the maps
+	for i, tc := range []struct {
+		path          string
+		expectType    Type
+		expectNearest string
+	}{
+		{path: "list_of_maps", expectType: Array, expectNearest: "example.com/fixture/encodings.Containers"},
+		{path: "list_of_maps/gizmo", expectType: Struct, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{path: "list_of_maps/gizmo/name", expectType: String, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{path: "nested/gizmo", expectType: Struct, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+		{path: "nested/gizmo/name", expectType: String, expectNearest: "example.com/fixture/gizmos.Gizmo"},
+	} {
+		exact, nearest, err := d.LoadTypeByPathFrom("example.com/fixture/encodings", "Containers", tc.path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+		if nearest.TypeName != tc.expectNearest {
+			t.Errorf("Test %d (%s): expected nearest type %s, got %s", i, tc.path, tc.expectNearest, nearest.TypeName)
+		}
+	}
+	if _, _, err := d.LoadTypeByPathFrom("example.com/fixture/encodings", "Containers", "nested/nope", ""); err == nil {
+		t.Error("expected error for a module that isn't in the namespace, got none")
+	}
+}
+
+func TestTraverseTypeTwice(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/encodings", "Containers", ""); err != nil {
+		t.Fatal(err)
+	}
+	start, _ := db.GetTypeByName("example.com/fixture/encodings", "Containers", "")
+	if start == nil {
+		t.Fatal("Containers was not stored")
+	}
+	startFingerprint := start.Fingerprint()
+
+	// traversing doesn't change the start value, nor what is
+	// stored, so the same traversal gives the same results
+	for i, path := range []string{"", "map_of_lists", "map_of_lists/gizmo", "list_of_maps/gizmo/parts", "nested/gizmo/name"} {
+		firstVal, firstNearest, err := d.TraverseType(path, start)
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, path, err)
+		}
+		secondVal, secondNearest, err := d.TraverseType(path, start)
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, path, err)
+		}
+		if !reflect.DeepEqual(firstVal, secondVal) {
+			t.Errorf("Test %d (%s): expected the same value twice, got %s and %s", i, path, dumpString(firstVal), dumpString(secondVal))
+		}
+		if !reflect.DeepEqual(firstNearest, secondNearest) {
+			t.Errorf("Test %d (%s): expected the same nearest type twice, got %s and %s", i, path, dumpString(firstNearest), dumpString(secondNearest))
+		}
+		if start.Fingerprint() != startFingerprint {
+			t.Fatalf("Test %d (%s): the start value was changed", i, path)
+		}
+	}
+	stored, _ := db.GetTypeByName("example.com/fixture/encodings", "Containers", "")
+	if stored.Fingerprint() != startFingerprint {
+		t.Error("expected the stored type to be unchanged")
+	}
+}
+
+func TestTraverseTypeAliases(t *testing.T) {
+	d, db := newTestDriver(t)
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/aliases", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, mod := range mods {
+		found = found || mod.Name == "fixture.aliases.widget"
+	}
+	if !found {
+		t.Fatalf("expected the widget module, got %+v", mods)
+	}
+
+	// aliases are represented like the types they stand for
+	rep, _ := db.GetTypeByName("example.com/fixture/aliases", "Widget", "")
+	if rep == nil {
+		t.Fatal("Widget was not stored")
+	}
+	expectFields := []*StructField{
+		{Key: "settings", Value: &Value{Type: Any}, Doc: "The widget's settings.", TypeName: "example.com/fixture/aliases.Settings"},
+		{Key: "parts", Value: &Value{Type: Array, Elems: &Value{SameAs: "example.com/fixture/gizmos.Part"}}, Doc: "The widget's parts.", TypeName: "example.com/fixture/aliases.PartList"},
+		{Key: "main", Value: &Value{SameAs: "example.com/fixture/gizmos.Part"}, Doc: "The widget's main part.", TypeName: "*example.com/fixture/aliases.MainPart"},
+	}
+	if !reflect.DeepEqual(rep.StructFields, expectFields) {
+		expectJSON, _ := json.Marshal(expectFields)
+		actualJSON, _ := json.Marshal(rep.StructFields)
+		t.Errorf("expected fields:\n%s\ngot:\n%s", expectJSON, actualJSON)
+	}
+	if part, _ := db.GetTypeByName("example.com/fixture/gizmos", "Part", ""); part == nil {
+		t.Error("expected the aliased type to be stored")
+	}
+
+	for i, tc := range []struct {
+		path       string
+		expectType Type
+	}{
+		{path: "settings", expectType: Any},
+		{path: "settings/anything/at/all", expectType: Any},
+		{path: "parts/size", expectType: Int},
+		{path: "main/size", expectType: Int},
+	} {
+		exact, _, err := d.LoadTypeByPathFromModule("fixture.aliases.widget", tc.path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+	}
+}
+
+func TestTraverseTypeEscapedKey(t *testing.T) {
+	d, db := newTestDriver(t)
+
+	// JSON field names may contain slashes and backslashes; map keys are
+	// not part of config paths, since maps are traversed transparently
+	root := &Value{
+		Type:     Struct,
+		TypeName: "example.com/fixture.Files",
+		StructFields: []*StructField{
+			{Key: "etc/caddy", Value: &Value{Type: Map, MapKeys: &Value{Type: String}, Elems: &Value{
+				Type:         Struct,
+				StructFields: []*StructField{{Key: `C:\size`, Value: &Value{Type: Int}}},
+			}}},
+		},
+	}
+	if err := db.StoreType("example.com/fixture", "Files", "", root); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		path       string
+		expectType Type
+		expectErr  bool
+	}{
+		{path: `etc\/caddy`, expectType: Map},
+		{path: `etc\/caddy/C:\\size`, expectType: Int},
+		{path: JoinConfigPath("etc/caddy", `C:\size`), expectType: Int},
+		{path: "etc/caddy", expectErr: true},
+		{path: `etc\/caddy/C:\size`, expectErr: true},
+	} {
+		exact, _, err := d.LoadTypeByPathFrom("example.com/fixture", "Files", tc.path, "")
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("Test %d (%s): expected error, got none", i, tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		if exact.Type != tc.expectType {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.path, tc.expectType, exact.Type)
+		}
+	}
+}
+
+func TestTraverseTypeEmptySegments(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// empty segments of a path are ignored, rather than
+	// being looked up as a struct field with no name
+	for i, path := range []string{"parts/size", "parts//size", "/parts/size/", "//parts///size//"} {
+		exact, _, err := d.LoadTypeByPathFromModule("fixture.gizmos.gizmo", path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, path, err)
+			continue
+		}
+		if exact.Type != Int {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, path, Int, exact.Type)
+		}
+	}
+}
+
+func TestIngestResume(t *testing.T) {
+	d, db := newTestDriver(t)
+	checkpoint := NewMemoryCheckpoint()
+	pkgs := []PackageVersion{
+		{Pattern: "example.com/fixture/gizmos"},
+		{Pattern: "example.com/fixture/aliases"},
+		{Pattern: "example.com/fixture/versioned"},
+	}
+
+	// the first ingest is canceled while the second package is loaded
+	var loaded []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.OnProgress = func(event ProgressEvent) {
+		if event.Kind == ProgressPackageLoaded {
+			loaded = append(loaded, event.PackagePath)
+			if event.PackagePath == "example.com/fixture/aliases" {
+				cancel()
+			}
+		}
+	}
+	if err := d.Ingest(ctx, pkgs, checkpoint); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ingest to be canceled, got %v", err)
+	}
+	if expect := []string{"example.com/fixture/gizmos", "example.com/fixture/aliases"}; !reflect.DeepEqual(loaded, expect) {
+		t.Errorf("expected to load %v, got %v", expect, loaded)
+	}
+	for i, tc := range []struct {
+		pattern string
+		expect  bool
+	}{
+		{pattern: "example.com/fixture/gizmos", expect: true},
+		{pattern: "example.com/fixture/aliases", expect: false},
+		{pattern: "example.com/fixture/versioned", expect: false},
+	} {
+		if done, _ := checkpoint.Done(tc.pattern, ""); done != tc.expect {
+			t.Errorf("Test %d (%s): expected done to be %t, got %t", i, tc.pattern, tc.expect, done)
+		}
+	}
+	if mods, _ := db.GetTypesByCaddyModuleID("fixture.aliases.widget"); len(mods) != 0 {
+		t.Errorf("expected the canceled package's module not to be stored, got %v", mods)
+	}
+
+	// resuming skips the package that is done, and completes the rest
+	loaded = nil
+	if err := d.Ingest(context.Background(), pkgs, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"example.com/fixture/aliases", "example.com/fixture/versioned"}; !reflect.DeepEqual(loaded, expect) {
+		t.Errorf("expected to load %v on resume, got %v", expect, loaded)
+	}
+	for _, pv := range pkgs {
+		if done, _ := checkpoint.Done(pv.Pattern, pv.Version); !done {
+			t.Errorf("expected %s to be done", pv.Pattern)
+		}
+	}
+	for _, moduleID := range []string{"fixture.gizmos.gizmo", "fixture.aliases.widget"} {
+		if mods, _ := db.GetTypesByCaddyModuleID(moduleID); len(mods) != 1 {
+			t.Errorf("expected module %s to be stored once, got %d", moduleID, len(mods))
+		}
+	}
+
+	// and once everything is done, there is nothing left to do
+	loaded = nil
+	if err := d.Ingest(context.Background(), pkgs, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 0 {
+		t.Errorf("expected nothing to be loaded again, got %v", loaded)
+	}
+}
+
+func TestResolvedCoreVersion(t *testing.T) {
+	for i, tc := range []struct {
+		pkg    string
+		expect string
+	}{
+		// the fixture requires (a stand-in for) core Caddy v2.7.6
+		{pkg: "example.com/fixture/plugin", expect: "v2.7.6"},
+
+		// and this package doesn't import it
+		{pkg: "example.com/fixture/gizmos", expect: ""},
+	} {
+		d, _ := newTestDriver(t)
+		if _, err := d.LoadModulesFromImportingPackage(tc.pkg, ""); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if actual := d.ResolvedCoreVersion(); actual != tc.expect {
+			t.Errorf("Test %d (%s): expected core version %q, got %q", i, tc.pkg, tc.expect, actual)
+		}
+	}
+}
+
+func TestModuleSourceLocation(t *testing.T) {
+	d, _ := newTestDriver(t)
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/plugin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 {
+		t.Fatalf("expected 1 module, got %d", len(mods))
+	}
+	mod := mods[0]
+	if mod.SourceFile != "plugin/plugin.go" || mod.ModulePath != "example.com/fixture" {
+		t.Errorf("expected source plugin/plugin.go in example.com/fixture, got %s in %s", mod.SourceFile, mod.ModulePath)
+	}
+
+	// the fixture is a local directory, so there is no public source
+	if mod.ModuleVersion != "" || mod.SourceURL() != "" {
+		t.Errorf("expected no version or source URL, got %q and %q", mod.ModuleVersion, mod.SourceURL())
+	}
+}
+
+func TestLoadModulesFromImportingPackageFunc(t *testing.T) {
+	d, _ := newTestDriver(t)
+
+	// the callback is called once for each module
+	calls := make(map[string]int)
+	err := d.LoadModulesFromImportingPackageFunc("example.com/fixture/registry/ranged", "", func(mod CaddyModule) error {
+		calls[mod.Name]++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]int{
+		"fixture.ranged.first":  1,
+		"fixture.ranged.second": 1,
+		"fixture.ranged.third":  1,
+	}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("expected calls %v, got %v", expect, calls)
+	}
+
+	// and an error from it stops the load
+	errStop := errors.New("stop")
+	var count int
+	err = d.LoadModulesFromImportingPackageFunc("example.com/fixture/registry/ranged", "", func(mod CaddyModule) error {
+		count++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 call before stopping, got %d", count)
+	}
+}
+
+func TestLoadModulesFromLocalDir(t *testing.T) {
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+
+	mods, err := d.LoadModulesFromLocalDir(filepath.Join("testdata", "local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 {
+		t.Fatalf("expected 1 module, got %d", len(mods))
+	}
+	mod := mods[0]
+	if mod.Name != "http.handlers.echo" || mod.ModulePath != "example.com/local" || mod.SourceFile != "echo/echo.go" {
+		t.Errorf("expected http.handlers.echo in echo/echo.go of example.com/local, got %s in %s of %s",
+			mod.Name, mod.SourceFile, mod.ModulePath)
+	}
+	rep, _ := db.GetTypeByName("example.com/local/echo", "Handler", "")
+	if rep == nil || len(rep.StructFields) != 1 || rep.StructFields[0].Key != "headers" {
+		t.Errorf("expected the module's type to be stored, got %v", rep)
+	}
+
+	// the versions come from the local go.mod
+	if actual := d.ResolvedCoreVersion(); actual != "v2.7.6" {
+		t.Errorf("expected core version v2.7.6, got %q", actual)
+	}
+}
+
+func TestLoadModulesFromModule(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	dir, err := filepath.Abs(filepath.Join("testdata", "multi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := NewMemoryStorage()
+	d := New(db)
+	d.ModuleDir = dir
+	d.Env = []string{"GOPROXY=off"}
+	d.Logger = log.New(ioutil.Discard, "", 0)
+
+	// the modules of all the module's packages, but not those of its
+	// dependencies (example.com/local) or of nested modules
+	mods, err := d.LoadModulesFromModule("example.com/multi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := make(map[string]string)
+	for _, mod := range mods {
+		actual[mod.Name] = mod.SourceFile
+	}
+	expect := map[string]string{
+		"multi.first":  "first/first.go",
+		"multi.second": "second/deeper/second.go",
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("expected modules %v, got %v", expect, actual)
+	}
+	if vals, _ := db.GetTypesByCaddyModuleID("http.handlers.echo"); len(vals) != 0 {
+		t.Errorf("expected the dependency's module not to be stored, got %d types", len(vals))
+	}
+
+	// the path of a package is not the path of a module
+	if _, err := d.LoadModulesFromModule("example.com/multi/first", ""); !errors.Is(err, ErrNoPackages) {
+		t.Errorf("expected %v, got %v", ErrNoPackages, err)
+	}
+}
+
+func TestModuleInfo(t *testing.T) {
+	for i, tc := range []struct {
+		pkg           string
+		expectName    string
+		expectModule  string
+		expectVersion string
+		expectReplace string
+		expectImports []string
+	}{
+		{
+			pkg:           "example.com/fixture/plugin",
+			expectName:    "plugin",
+			expectModule:  "example.com/fixture",
+			expectImports: []string{"github.com/caddyserver/caddy/v2"},
+		},
+		{
+			// a dependency is required at a version, even if replaced
+			pkg:           "example.com/dep",
+			expectName:    "dep",
+			expectModule:  "example.com/dep",
+			expectVersion: "v1.2.0",
+			expectReplace: filepath.Join("testdata", "dep"),
+			expectImports: []string{"example.com/transitive"},
+		},
+	} {
+		d, _ := newTestDriver(t)
+		info, err := d.ModuleInfo(tc.pkg, "")
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.pkg, err)
+		}
+		if info.ImportPath != tc.pkg || info.Name != tc.expectName {
+			t.Errorf("Test %d (%s): expected package %s named %s, got %s named %s",
+				i, tc.pkg, tc.pkg, tc.expectName, info.ImportPath, info.Name)
+		}
+		if info.ModulePath != tc.expectModule || info.ModuleVersion != tc.expectVersion {
+			t.Errorf("Test %d (%s): expected module %s@%s, got %s@%s",
+				i, tc.pkg, tc.expectModule, tc.expectVersion, info.ModulePath, info.ModuleVersion)
+		}
+		if tc.expectReplace != "" && !strings.HasSuffix(info.ReplaceDir, tc.expectReplace) {
+			t.Errorf("Test %d (%s): expected module to be replaced by %s, got %q", i, tc.pkg, tc.expectReplace, info.ReplaceDir)
+		}
+		if !reflect.DeepEqual(info.Imports, tc.expectImports) {
+			t.Errorf("Test %d (%s): expected imports %v, got %v", i, tc.pkg, tc.expectImports, info.Imports)
+		}
+		if info.Standard || len(info.GoFiles) == 0 {
+			t.Errorf("Test %d (%s): expected a non-standard package with Go files, got %+v", i, tc.pkg, info)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	d, _ := newTestDriver(t)
+	var buf bytes.Buffer
+	d.Logger = log.New(&buf, "", 0)
+
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/computed", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 0 {
+		t.Errorf("expected the module with a computed ID to be skipped, got %v", mods)
+	}
+
+	// the warning goes to the driver's logger
+	logged := strings.TrimSpace(buf.String())
+	if strings.Count(logged, "\n") != 0 || !strings.HasPrefix(logged, "[WARNING] example.com/fixture/registry/computed: ") {
+		t.Fatalf("expected one warning about the computed package, got %q", logged)
+	}
+	if expect := "CaddyModule() method of Adapter returns ModuleInfo with unsupported ID value"; !strings.Contains(logged, expect) {
+		t.Errorf("expected the warning to contain %q, got %q", expect, logged)
+	}
+}
+
+func TestOnlyMatchedPackages(t *testing.T) {
+	// the aliases package imports the gizmos package,
+	// which registers a module of its own
+	for i, tc := range []struct {
+		onlyMatched bool
+		expect      []string
+	}{
+		{onlyMatched: false, expect: []string{"fixture.aliases.widget", "fixture.gizmos.gizmo"}},
+		{onlyMatched: true, expect: []string{"fixture.aliases.widget"}},
+	} {
+		d, _ := newTestDriver(t)
+		d.OnlyMatchedPackages = tc.onlyMatched
+		mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/aliases", "")
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		var actual []string
+		for _, mod := range mods {
+			actual = append(actual, mod.Name)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (only matched: %t): expected modules %v, got %v", i, tc.onlyMatched, tc.expect, actual)
+		}
+	}
+}
+
+func TestTypeErrorsDegradeToSyntax(t *testing.T) {
+	d, db := newTestDriver(t)
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/degraded", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, mod := range mods {
+		names = append(names, mod.Name)
+	}
+	sort.Strings(names)
+	if expect := []string{"fixture.degraded.damaged", "fixture.degraded.intact"}; !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected modules %v, got %v", expect, names)
+	}
+
+	// a module that isn't affected by the errors is documented
+	// fully, and one that is, partially from its syntax
+	for i, tc := range []struct {
+		typeName      string
+		expectPartial bool
+		expectFields  map[string]Type
+	}{
+		{typeName: "Intact", expectFields: map[string]Type{"name": String}},
+		{typeName: "Damaged", expectPartial: true, expectFields: map[string]Type{"name": Any, "settings": Any}},
+	} {
+		rep, _ := db.GetTypeByName("example.com/fixture/registry/degraded", tc.typeName, "")
+		if rep == nil {
+			t.Errorf("Test %d: %s was not stored", i, tc.typeName)
+			continue
+		}
+		if rep.Partial != tc.expectPartial {
+			t.Errorf("Test %d (%s): expected partial: %t, got %t", i, tc.typeName, tc.expectPartial, rep.Partial)
+		}
+		fields := make(map[string]Type)
+		for _, sf := range rep.StructFields {
+			fields[sf.Key] = sf.Value.Type
+			if sf.Doc == "" {
+				t.Errorf("Test %d (%s): expected field %s to have a doc", i, tc.typeName, sf.Key)
+			}
+		}
+		if !reflect.DeepEqual(fields, tc.expectFields) {
+			t.Errorf("Test %d (%s): expected fields %v, got %v", i, tc.typeName, tc.expectFields, fields)
+		}
+	}
+}
+
+func TestNamespacesForPackage(t *testing.T) {
+	for i, tc := range []struct {
+		pkg    string
+		expect []string
+	}{
+		// the modules of imported packages count too
+		{pkg: "example.com/fixture/aliases", expect: []string{"fixture.aliases", "fixture.gizmos"}},
+
+		// and namespaces are listed once
+		{pkg: "example.com/fixture/registry/ranged", expect: []string{"fixture.ranged"}},
+	} {
+		d, _ := newTestDriver(t)
+		actual, err := d.NamespacesForPackage(tc.pkg, "")
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.pkg, err)
+		}
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected namespaces %v, got %v", i, tc.pkg, tc.expect, actual)
+		}
+	}
+}
+
+func TestGenericModule(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/generic", ""); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := d.LoadTypesByModuleID("fixture.generic.wrapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 {
+		t.Fatalf("expected 1 type, got %d", len(vals))
+	}
+
+	// the module is documented as the instance, with
+	// the concrete type of its type parameter
+	wrapper := vals[0]
+	if expect := "example.com/fixture/registry/generic.Wrapper[example.com/fixture/registry/generic.Settings]"; wrapper.TypeName != expect {
+		t.Errorf("expected type %s, got %s", expect, wrapper.TypeName)
+	}
+	if len(wrapper.StructFields) != 2 || wrapper.StructFields[1].Key != "config" {
+		t.Fatalf("expected fields name and config, got %s", dumpString(wrapper))
+	}
+	config := wrapper.StructFields[1].Value
+	if config.Type != Struct || config.TypeName != "example.com/fixture/registry/generic.Settings" ||
+		len(config.StructFields) != 1 || config.StructFields[0].Key != "timeout" {
+		t.Errorf("expected the config to be Settings, got %s", dumpString(config))
+	}
+}
+
+func TestMethodDocs(t *testing.T) {
+	for i, tc := range []struct {
+		methods []string
+		expect  map[string]string
+	}{
+		{
+			// methods with value and pointer receivers alike, but
+			// not ones that are undocumented or weren't asked for
+			methods: []string{"Provision", "ServeHTTP", "Validate"},
+			expect: map[string]string{
+				"Provision": "Provision sets up the handler.",
+				"ServeHTTP": "ServeHTTP writes the response.",
+			},
+		},
+		{
+			methods: nil,
+			expect:  nil,
+		},
+	} {
+		d, _ := newTestDriver(t)
+		d.MethodDocs = tc.methods
+		mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/behavior", "")
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if len(mods) != 1 {
+			t.Fatalf("Test %d: expected 1 module, got %v", i, mods)
+		}
+		if !reflect.DeepEqual(mods[0].MethodDocs, tc.expect) {
+			t.Errorf("Test %d: expected method docs %v, got %v", i, tc.expect, mods[0].MethodDocs)
+		}
+	}
+}
+
+func TestPackageDocs(t *testing.T) {
+	for i, tc := range []struct {
+		include bool
+		expect  string
+	}{
+		{
+			include: true,
+			expect:  "Package behavior has a module whose behavior is\ndocumented on its methods.\n\nThis overview is attached to the package's modules.",
+		},
+		{include: false, expect: ""},
+	} {
+		d, _ := newTestDriver(t)
+		d.IncludePackageDocs = tc.include
+		mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/behavior", "")
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if len(mods) != 1 {
+			t.Fatalf("Test %d: expected 1 module, got %v", i, mods)
+		}
+		if mods[0].PackageDoc != tc.expect {
+			t.Errorf("Test %d: expected package doc %q, got %q", i, tc.expect, mods[0].PackageDoc)
+		}
+	}
+}
+
+func TestModuleFilter(t *testing.T) {
+	d, db := newTestDriver(t)
+	d.ModuleFilter = func(mod CaddyModule) (CaddyModule, bool) {
+		switch mod.Name {
+		case "fixture.documented.unnoted":
+			return mod, false
+		case "fixture.documented.noted":
+			mod.Name = "fixture.notes.noted"
+		}
+		return mod, true
+	}
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/documented", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 || mods[0].Name != "fixture.notes.noted" {
+		t.Fatalf("expected only the renamed module, got %v", mods)
+	}
+
+	// the modules are stored as filtered
+	for i, tc := range []struct {
+		moduleID string
+		expect   int
+	}{
+		{moduleID: "fixture.notes.noted", expect: 1},
+		{moduleID: "fixture.documented.noted", expect: 0},
+		{moduleID: "fixture.documented.unnoted", expect: 0},
+	} {
+		vals, err := db.GetTypesByCaddyModuleID(tc.moduleID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) != tc.expect {
+			t.Errorf("Test %d (%s): expected %d types, got %d", i, tc.moduleID, tc.expect, len(vals))
+		}
+	}
+	for i, tc := range []struct {
+		namespace string
+		expect    []string
+	}{
+		{namespace: "fixture.notes", expect: []string{"fixture.notes.noted"}},
+		{namespace: "fixture.documented", expect: nil},
+	} {
+		actual, err := db.GetModuleIDsByNamespace(tc.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected module IDs %v, got %v", i, tc.namespace, tc.expect, actual)
+		}
+	}
+
+	// and the type of the dropped module is still stored, just not as a module
+	if rep, _ := db.GetTypeByName("example.com/fixture/registry/documented", "Unnoted", ""); rep == nil {
+		t.Error("expected the dropped module's type to be stored")
+	}
+}
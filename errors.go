@@ -0,0 +1,132 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+)
+
+// Errors that may be returned (possibly wrapped) by this package,
+// so that callers can distinguish between failure modes with
+// errors.Is; for example, to tell "not found" apart from other
+// errors when serving docs.
+var (
+	// ErrTypeNotFound is returned when a type cannot be
+	// found, either in its package or in storage.
+	ErrTypeNotFound = errors.New("type not found")
+
+	// ErrModuleNotFound is returned when no type is
+	// known for a Caddy module ID.
+	ErrModuleNotFound = errors.New("module not found")
+
+	// ErrAmbiguousModule is returned when a Caddy module ID
+	// is claimed by more than one type, but exactly one
+	// is required.
+	ErrAmbiguousModule = errors.New("ambiguous module")
+
+	// ErrPathNotTraversable is returned when a config path
+	// cannot be followed through a type's structure.
+	ErrPathNotTraversable = errors.New("path not traversable")
+
+	// ErrNoPackages is returned when a package pattern
+	// does not match any packages with Go files to build.
+	ErrNoPackages = errors.New("no packages matched")
+
+	// ErrModuleNameConflict should be returned (possibly wrapped)
+	// by Storage implementations when a type is associated with a
+	// Caddy module name, but it is already associated with a
+	// different one.
+	ErrModuleNameConflict = errors.New("conflicting module name")
+
+	// ErrInvalidRepresentation is returned (possibly wrapped)
+	// when a type representation is not well-formed, e.g.
+	// because it has a value that was never resolved.
+	ErrInvalidRepresentation = errors.New("invalid representation")
+)
+
+// FieldError describes a struct field whose type representation
+// could not be built. It is returned (possibly wrapped), unless
+// Driver.TolerateFieldErrors is enabled, in which case these are
+// collected instead.
+type FieldError struct {
+	// The type of the struct which has the field.
+	StructType string
+
+	// The Go name of the field.
+	Field string
+
+	// Why its representation could not be built.
+	Err error
+}
+
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("%s.%s: %v", fe.StructType, fe.Field, fe.Err)
+}
+
+func (fe FieldError) Unwrap() error { return fe.Err }
+
+// Diagnostic describes a problem with the Caddy modules of a package
+// which was found in its source code, but which did not stop the package
+// from being loaded; the module in question might have been skipped.
+// Diagnostics are collected, to be retrieved with Driver.TakeDiagnostics,
+// in addition to being logged.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+
+	// Where in the source code the problem is.
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// newDiagnostic returns a diagnostic of the given severity at pos, with a
+// message formatted from format and args, as with fmt.Sprintf.
+func newDiagnostic(fset *token.FileSet, severity Severity, pos token.Pos, format string, args ...interface{}) Diagnostic {
+	position := fset.Position(pos)
+	return Diagnostic{
+		Severity: severity,
+		File:     position.Filename,
+		Line:     position.Line,
+		Column:   position.Column,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (d Diagnostic) String() string {
+	position := token.Position{Filename: d.File, Line: d.Line, Column: d.Column}
+	if d.File == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", position, d.Severity, d.Message)
+}
+
+// Severity is how serious a Diagnostic is.
+type Severity string
+
+// Severities of diagnostics.
+const (
+	// Something that is probably not a problem, or
+	// which is only a problem for these docs.
+	SeverityWarning Severity = "warning"
+
+	// Something that would be an error, i.e. which stops
+	// the package from being loaded, if the driver was
+	// not configured to tolerate it.
+	SeverityError Severity = "error"
+)
@@ -0,0 +1,185 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestErrors(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// two different types claim the same module ID
+	for _, pkgPath := range []string{"example.com/fixture/one", "example.com/fixture/two"} {
+		if err := db.StoreType(pkgPath, "Handler", "", &Value{Type: Struct, TypeName: pkgPath + ".Handler"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetCaddyModuleName(&packages.Package{PkgPath: pkgPath}, "Handler", "fixture.handlers.twice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, tc := range []struct {
+		call   func() error
+		expect error
+	}{
+		{
+			call: func() error {
+				_, err := d.AddType("example.com/fixture/gizmos", "Nope", "")
+				return err
+			},
+			expect: ErrTypeNotFound,
+		},
+		{
+			call: func() error {
+				_, err := d.ResolveType("example.com/fixture/gizmos.Nope", "")
+				return err
+			},
+			expect: ErrTypeNotFound,
+		},
+		{
+			call: func() error {
+				_, _, err := d.LoadTypeByPathFrom("example.com/fixture/gizmos", "Nope", "name", "")
+				return err
+			},
+			expect: ErrTypeNotFound,
+		},
+		{
+			call: func() error {
+				_, err := d.LoadTypesByModuleID("fixture.gizmos.nope")
+				return err
+			},
+			expect: ErrModuleNotFound,
+		},
+		{
+			call: func() error {
+				_, _, err := d.LoadTypeByPathFromModule("fixture.gizmos.nope", "name", "")
+				return err
+			},
+			expect: ErrModuleNotFound,
+		},
+		{
+			call: func() error {
+				_, _, err := d.LoadTypeByPathFromModule("fixture.handlers.twice", "", "")
+				return err
+			},
+			expect: ErrAmbiguousModule,
+		},
+		{
+			call: func() error {
+				_, _, err := d.LoadTypeByPathFromModule("fixture.gizmos.gizmo", "name/first", "")
+				return err
+			},
+			expect: ErrPathNotTraversable,
+		},
+		{
+			call: func() error {
+				_, _, err := d.LoadTypeByPathFromModule("fixture.gizmos.gizmo", "nope", "")
+				return err
+			},
+			expect: ErrPathNotTraversable,
+		},
+	} {
+		err := tc.call()
+		if !errors.Is(err, tc.expect) {
+			t.Errorf("Test %d: expected error to be %v, got %v", i, tc.expect, err)
+		}
+	}
+}
+
+func TestFieldErrorAs(t *testing.T) {
+	d, _ := newTestDriver(t)
+	_, err := d.AddType("example.com/fixture/broken", "Config", "")
+	var fieldErr FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected a FieldError, got %v", err)
+	}
+	if fieldErr.StructType != "example.com/fixture/broken.Config" || fieldErr.Field != "Broken" {
+		t.Errorf("expected the error to be about Config.Broken, got %s.%s", fieldErr.StructType, fieldErr.Field)
+	}
+	if fieldErr.Err == nil {
+		t.Error("expected the field error to have an underlying error")
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	for i, tc := range []struct {
+		pkg            string
+		tolerate       bool
+		expectSeverity Severity
+		expectFile     string
+		expectLine     int
+		expectPrefix   string
+	}{
+		{
+			// a module that is skipped
+			pkg:            "computed",
+			expectSeverity: SeverityWarning,
+			expectFile:     "computed.go",
+			expectLine:     19,
+			expectPrefix:   "CaddyModule() method of Adapter returns ModuleInfo with unsupported ID value",
+		},
+		{
+			// a module type that isn't known to be registered, which
+			// is an error, unless inconsistencies are tolerated
+			pkg:            "unranged",
+			tolerate:       true,
+			expectSeverity: SeverityError,
+			expectFile:     "unranged.go",
+			expectLine:     21,
+			expectPrefix:   "Hidden: type has CaddyModule method, but does not get registered",
+		},
+	} {
+		d, _ := newTestDriver(t)
+		d.TolerateModuleInconsistencies = tc.tolerate
+		if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/"+tc.pkg, ""); err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.pkg, err)
+		}
+		diags := d.TakeDiagnostics()
+		var diag Diagnostic
+		for _, dg := range diags {
+			if strings.HasPrefix(dg.Message, tc.expectPrefix) {
+				diag = dg
+			}
+		}
+		if diag.Message == "" {
+			t.Errorf("Test %d (%s): expected a diagnostic starting with %q, got %v", i, tc.pkg, tc.expectPrefix, diags)
+			continue
+		}
+		if diag.Severity != tc.expectSeverity {
+			t.Errorf("Test %d (%s): expected severity %s, got %s", i, tc.pkg, tc.expectSeverity, diag.Severity)
+		}
+		if filepath.Base(diag.File) != tc.expectFile || diag.Line != tc.expectLine || diag.Column == 0 {
+			t.Errorf("Test %d (%s): expected a diagnostic at %s:%d, got %s:%d:%d",
+				i, tc.pkg, tc.expectFile, tc.expectLine, diag.File, diag.Line, diag.Column)
+		}
+		if !strings.HasSuffix(diag.String(), diag.Message) || !strings.Contains(diag.String(), tc.expectFile) {
+			t.Errorf("Test %d (%s): expected the diagnostic's position and message, got %q", i, tc.pkg, diag.String())
+		}
+
+		// diagnostics are only taken once
+		if again := d.TakeDiagnostics(); len(again) != 0 {
+			t.Errorf("Test %d (%s): expected no more diagnostics, got %v", i, tc.pkg, again)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExportAll returns everything in storage as a single JSON document:
+// all stored types, of all versions, with their Caddy module names.
+// The types are sorted by package path, type name, and then version,
+// so exporting the same docs always yields the same document. It can
+// be imported again with ImportAll, e.g. to restore a backup or to move
+// the docs to another storage backend. The storage must implement
+// MutableStorage.
+func (ds *Driver) ExportAll() (json.RawMessage, error) {
+	ms, ok := ds.db.(MutableStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support exporting (must implement MutableStorage)")
+	}
+
+	storedTypes, err := ms.AllTypes()
+	if err != nil {
+		return nil, fmt.Errorf("getting all stored types: %w", err)
+	}
+	sort.Slice(storedTypes, func(i, j int) bool {
+		a, b := storedTypes[i], storedTypes[j]
+		if a.PackagePath != b.PackagePath {
+			return a.PackagePath < b.PackagePath
+		}
+		if a.TypeName != b.TypeName {
+			return a.TypeName < b.TypeName
+		}
+		return a.Version < b.Version
+	})
+
+	doc, err := json.Marshal(exportedDocs{Types: storedTypes})
+	if err != nil {
+		return nil, fmt.Errorf("encoding stored types: %w", err)
+	}
+	return doc, nil
+}
+
+// ImportAll stores all of the types in doc, which is a document
+// produced by ExportAll, and associates them with their Caddy module
+// names. Types that are already stored are overwritten. Since only the
+// package paths of the types are known, the *packages.Package passed to
+// the storage's SetCaddyModuleName (or SetCaddyModuleNameAt, with the
+// type's version) has only its PkgPath set.
+func (ds *Driver) ImportAll(doc json.RawMessage) error {
+	var docs exportedDocs
+	if err := json.Unmarshal(doc, &docs); err != nil {
+		return fmt.Errorf("decoding stored types: %w", err)
+	}
+
+	for _, st := range docs.Types {
+		if st.Representation == nil {
+			return fmt.Errorf("type %s.%s@%s has no representation", st.PackagePath, st.TypeName, st.Version)
+		}
+		err := ds.db.StoreType(st.PackagePath, st.TypeName, st.Version, st.Representation)
+		if err != nil {
+			return fmt.Errorf("storing type %s.%s@%s: %w", st.PackagePath, st.TypeName, st.Version, err)
+		}
+		if st.ModuleName == "" {
+			continue
+		}
+		pkg := &packages.Package{PkgPath: st.PackagePath}
+		err = ds.setCaddyModuleName(pkg, st.TypeName, st.Version, st.ModuleName)
+		if err != nil {
+			return fmt.Errorf("saving Caddy module name of type %s.%s@%s: %w", st.PackagePath, st.TypeName, st.Version, err)
+		}
+	}
+
+	// cached types may have been overwritten
+	ds.mu.Lock()
+	ds.discoveredTypes = make(map[string]*Value)
+	ds.mu.Unlock()
+
+	return nil
+}
+
+// exportedDocs is the JSON document of ExportAll and ImportAll.
+type exportedDocs struct {
+	Types []StoredType `json:"types"`
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExportImportAll(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/apps", "App", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := d.ExportAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewMemoryStorage()
+	if err := New(imported).ImportAll(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// everything is imported as it was, including module names
+	expect, actual := db.allTypes(), imported.allTypes()
+	for _, storedTypes := range [][]StoredType{expect, actual} {
+		sort.Slice(storedTypes, func(i, j int) bool {
+			return storedTypes[i].PackagePath+"."+storedTypes[i].TypeName < storedTypes[j].PackagePath+"."+storedTypes[j].TypeName
+		})
+	}
+	if len(expect) != 3 {
+		t.Errorf("expected 3 stored types, got %d", len(expect))
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("expected imported types %+v, got %+v", expect, actual)
+	}
+	for i := range expect {
+		if actual[i].Representation.Fingerprint() != expect[i].Representation.Fingerprint() {
+			t.Errorf("expected imported %s.%s to have the same fingerprint", expect[i].PackagePath, expect[i].TypeName)
+		}
+	}
+	moduleTypes, err := imported.GetTypesByCaddyModuleID("fixture.gizmos.gizmo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moduleTypes) != 1 || moduleTypes[0].TypeName != "example.com/fixture/gizmos.Gizmo" {
+		t.Errorf("expected the imported Gizmo module, got %+v", moduleTypes)
+	}
+
+	// and exporting it again yields the same document
+	reexported, err := New(imported).ExportAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reexported, doc) {
+		t.Errorf("expected the same document when exporting again, got:\n%s\nand:\n%s", doc, reexported)
+	}
+}
+
+func TestExportOmitsFuncs(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/callbacks", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the named func type has no representation to store
+	callback, _ := db.GetTypeByName("example.com/fixture/callbacks", "Callback", "")
+	if callback != nil {
+		t.Errorf("expected Callback not to be stored, got %+v", callback)
+	}
+
+	doc, err := d.ExportAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported exportedDocs
+	if err := json.Unmarshal(doc, &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Types) != 1 {
+		t.Fatalf("expected 1 exported type, got %d: %s", len(exported.Types), doc)
+	}
+
+	// only the field that can be encoded as JSON is exported
+	var keys []string
+	for _, field := range exported.Types[0].Representation.StructFields {
+		keys = append(keys, field.Key)
+	}
+	if expect := []string{"name"}; !reflect.DeepEqual(keys, expect) {
+		t.Errorf("expected exported fields %v, got %v", expect, keys)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strconv"
+)
+
+// Fingerprint returns a hash of the value tree v, which is the same for
+// any two trees with the same contents, so it can be used to tell whether
+// a type's representation changed, e.g. to avoid storing it again. All of
+// the information in the tree contributes to it, including docs; but
+// SameAs references are not followed.
+func (v *Value) Fingerprint() string {
+	h := sha256.New()
+	writeFingerprint(h, v)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeFingerprint(h hash.Hash, v *Value) {
+	if v == nil {
+		writeFingerprintString(h, "-")
+		return
+	}
+	writeFingerprintString(h, "+")
+	writeFingerprintString(h, string(v.Type))
+	writeFingerprintString(h, v.TypeName)
+	writeFingerprintString(h, v.Doc)
+	writeFingerprintString(h, v.SameAs)
+	writeFingerprintString(h, string(v.GoType))
+	writeFingerprintString(h, v.Since)
+	writeFingerprintString(h, strconv.FormatBool(v.Partial))
+	writeFingerprintOptionalString(h, v.ModuleNamespace)
+	writeFingerprintOptionalString(h, v.ModuleInlineKey)
+
+	writeFingerprintString(h, strconv.Itoa(len(v.EnumValues)))
+	for _, enumValue := range v.EnumValues {
+		writeFingerprintString(h, enumValue)
+	}
+
+	writeFingerprintString(h, strconv.Itoa(len(v.Candidates)))
+	for _, candidate := range v.Candidates {
+		writeFingerprintString(h, candidate)
+	}
+	writeFingerprintString(h, v.ModuleExample)
+
+	// struct fields are in declaration order, which is significant
+	writeFingerprintString(h, strconv.Itoa(len(v.StructFields)))
+	for _, sf := range v.StructFields {
+		writeFingerprintString(h, sf.Key)
+		writeFingerprintString(h, sf.Doc)
+		writeFingerprintString(h, sf.TypeName)
+		writeFingerprintString(h, sf.Since)
+		writeFingerprintString(h, strconv.FormatBool(sf.Unexported))
+		tagKeys := make([]string, 0, len(sf.CaddyTags))
+		for key := range sf.CaddyTags {
+			tagKeys = append(tagKeys, key)
+		}
+		sort.Strings(tagKeys)
+		writeFingerprintString(h, strconv.Itoa(len(tagKeys)))
+		for _, key := range tagKeys {
+			writeFingerprintString(h, key)
+			writeFingerprintString(h, sf.CaddyTags[key])
+		}
+		writeFingerprint(h, sf.Value)
+	}
+
+	writeFingerprint(h, v.MapKeys)
+	writeFingerprint(h, v.Elems)
+}
+
+// writeFingerprintString writes s to h, prefixed by its
+// length, so that adjacent strings can't run together.
+func writeFingerprintString(h hash.Hash, s string) {
+	h.Write([]byte(strconv.Itoa(len(s))))
+	h.Write([]byte{':'})
+	h.Write([]byte(s))
+}
+
+// writeFingerprintOptionalString writes s to h, distinguishing
+// a nil s from a pointer to empty string.
+func writeFingerprintOptionalString(h hash.Hash, s *string) {
+	if s == nil {
+		writeFingerprintString(h, "-")
+		return
+	}
+	writeFingerprintString(h, "+")
+	writeFingerprintString(h, *s)
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	ns, otherNS, empty := "http.handlers", "tls.handshake_match", ""
+
+	// a function, so each test gets a fresh tree to change
+	base := func() *Value {
+		return &Value{
+			Type:     Struct,
+			TypeName: "example.com/foo.Bar",
+			Doc:      "Bar is a bar.",
+			StructFields: []*StructField{
+				{Key: "a", Value: &Value{Type: String}, Doc: "A.", CaddyTags: map[string]string{"namespace": ns, "inline_key": "handler"}},
+				{Key: "b", Value: &Value{Type: Array, Elems: &Value{SameAs: "example.com/foo.Baz@v1.0.0"}}},
+				{Key: "c", Value: &Value{Type: Module, ModuleNamespace: &ns}},
+			},
+		}
+	}
+	baseFingerprint := base().Fingerprint()
+
+	if actual := base().Fingerprint(); actual != baseFingerprint {
+		t.Errorf("expected equal trees to have the same fingerprint, got %s and %s", baseFingerprint, actual)
+	}
+	if actual := base().Clone().Fingerprint(); actual != baseFingerprint {
+		t.Errorf("expected a clone to have the same fingerprint, got %s and %s", baseFingerprint, actual)
+	}
+
+	for i, tc := range []struct {
+		change func(*Value)
+		expect bool // whether the fingerprint is the same
+	}{
+		{change: func(v *Value) { v.Doc = "Bar is a different bar." }},
+		{change: func(v *Value) { v.Doc = v.Doc[:len(v.Doc)-1]; v.SameAs = "." }},
+		{change: func(v *Value) { v.TypeName = "example.com/foo.Baz" }},
+		{change: func(v *Value) { v.Since = "v1.0.0" }},
+		{change: func(v *Value) { v.Partial = true }},
+		{change: func(v *Value) { v.EnumValues = []string{"x"} }},
+		{change: func(v *Value) { v.Candidates = []string{"x"} }},
+		{change: func(v *Value) { v.ModuleExample = "{...}" }},
+		{change: func(v *Value) { v.StructFields[0].Key = "z" }},
+		{change: func(v *Value) { v.StructFields[0].Unexported = true }},
+		{change: func(v *Value) { v.StructFields[0].CaddyTags["namespace"] = otherNS }},
+		{change: func(v *Value) { v.StructFields[1].Value.Elems.SameAs = "example.com/foo.Baz@v1.0.1" }},
+		{change: func(v *Value) { v.StructFields[2].Value.ModuleNamespace = &otherNS }},
+		{change: func(v *Value) { v.StructFields[2].Value.ModuleNamespace = nil }},
+		{change: func(v *Value) { v.StructFields[2].Value.ModuleInlineKey = &empty }},
+		{change: func(v *Value) { v.StructFields[0], v.StructFields[1] = v.StructFields[1], v.StructFields[0] }},
+		{change: func(v *Value) { v.StructFields = v.StructFields[:2] }},
+
+		// only the contents matter, not which pointers point to them,
+		// nor the order map entries are in
+		{change: func(v *Value) { ns2 := ns; v.StructFields[2].Value.ModuleNamespace = &ns2 }, expect: true},
+		{change: func(v *Value) {
+			v.StructFields[0].CaddyTags = map[string]string{"inline_key": "handler", "namespace": ns}
+		}, expect: true},
+	} {
+		val := base()
+		tc.change(val)
+		if actual := val.Fingerprint() == baseFingerprint; actual != tc.expect {
+			t.Errorf("Test %d: expected same fingerprint to be %t, got %t", i, tc.expect, actual)
+		}
+	}
+
+	// a nil value and an empty one are different
+	if (*Value)(nil).Fingerprint() == new(Value).Fingerprint() {
+		t.Error("expected nil and empty values to have different fingerprints")
+	}
+}
+
+func TestFingerprintFromSource(t *testing.T) {
+	build := func() *Value {
+		d, db := newTestDriver(t)
+		if _, err := d.AddType("example.com/fixture/gizmos", "Gizmo", ""); err != nil {
+			t.Fatal(err)
+		}
+		rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Gizmo", "")
+		if rep == nil {
+			t.Fatal("Gizmo was not stored")
+		}
+		return rep
+	}
+
+	// building a type from the same source gives the same fingerprint
+	first, second := build(), build()
+	if first.Fingerprint() != second.Fingerprint() {
+		t.Errorf("expected the same fingerprint for the same source, got %s and %s", first.Fingerprint(), second.Fingerprint())
+	}
+
+	// but not once a single doc changes
+	second.StructFields[0].Doc += " Changed."
+	if first.Fingerprint() == second.Fingerprint() {
+		t.Error("expected a changed field doc to change the fingerprint")
+	}
+}
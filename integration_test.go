@@ -0,0 +1,116 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"testing"
+)
+
+// The tests in this file download real modules, so they
+// only run if this environment variable is set.
+const integrationEnv = "MODULEDOC_INTEGRATION"
+
+// pinnedCaddyVersion is the version of Caddy that
+// the integration tests document.
+const pinnedCaddyVersion = "v2.7.6"
+
+// pseudoVersion matches a pseudo-version, capturing its commit hash.
+var pseudoVersion = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-(?:0\.)?(?:[0-9a-z.]+\.)?[0-9]{14}-([0-9a-f]{12})$`)
+
+// newIntegrationDriver returns a driver that loads packages
+// in its own workspace and stores types in memory, or skips
+// the test if integration tests are not enabled.
+func newIntegrationDriver(t *testing.T) (*Driver, *MemoryStorage) {
+	t.Helper()
+	if testing.Short() || os.Getenv(integrationEnv) == "" {
+		t.Skipf("set %s to run integration tests", integrationEnv)
+	}
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	return d, db
+}
+
+func TestBootstrapCoreIntegration(t *testing.T) {
+	d, db := newIntegrationDriver(t)
+	summary, err := d.BootstrapCore(pinnedCaddyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Types < 100 || summary.ModuleSlots < 20 || summary.Modules < 50 {
+		t.Errorf("expected at least 100 types, 20 module slots, and 50 modules, got %+v", summary)
+	}
+	if actual := d.ResolvedCoreVersion(); actual != pinnedCaddyVersion {
+		t.Errorf("expected core version %s, got %q", pinnedCaddyVersion, actual)
+	}
+
+	// the root is stored, along with the apps reached through it
+	config, err := db.GetTypeByName(CaddyCorePackage, "Config", pinnedCaddyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config == nil {
+		t.Fatal("expected the Config type to be stored")
+	}
+	for _, id := range []string{"http", "tls", "http.handlers.reverse_proxy"} {
+		types, err := d.getTypesByCaddyModuleID(id, pinnedCaddyVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(types) != 1 {
+			t.Errorf("expected 1 type for module %s, got %d", id, len(types))
+		}
+	}
+}
+
+func TestLoadModulesAtCommitIntegration(t *testing.T) {
+	const pkg = "github.com/caddyserver/caddy/v2/modules/caddyhttp/templates"
+
+	// a branch resolves to the pseudo-version of its latest commit
+	d, _ := newIntegrationDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage(pkg, "master"); err != nil {
+		t.Fatal(err)
+	}
+	branchVersion := d.ResolvedVersion()
+	match := pseudoVersion.FindStringSubmatch(branchVersion)
+	if match == nil {
+		t.Fatalf("expected the branch to resolve to a pseudo-version, got %q", branchVersion)
+	}
+
+	// and that commit resolves to the same pseudo-version,
+	// by which the module's types are stored
+	d, db := newIntegrationDriver(t)
+	mods, err := d.LoadModulesFromImportingPackage(pkg, match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := d.ResolvedVersion(); actual != branchVersion {
+		t.Errorf("expected commit %s to resolve to %s, got %q", match[1], branchVersion, actual)
+	}
+	if len(mods) != 1 || mods[0].Name != "http.handlers.templates" {
+		t.Fatalf("expected the templates module, got %v", mods)
+	}
+	rep, err := db.GetTypeByName(pkg, "Templates", branchVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep == nil {
+		t.Errorf("expected the module's type to be stored at %s", branchVersion)
+	}
+}
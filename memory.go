@@ -0,0 +1,212 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// MemoryStorage is a Storage that keeps everything in memory. It
+// implements all of the optional storage interfaces too, i.e.
+// VersionedStorage, PackageListingStorage, and MutableStorage. It is
+// safe for concurrent use, and is useful for tests, for short-lived
+// processes, and as a reference for other implementations. Use
+// NewMemoryStorage to make one.
+type MemoryStorage struct {
+	mu          sync.Mutex
+	types       map[string]StoredType
+	moduleNames map[string]string // keyed like types
+
+	// the packages in which the modules are registered,
+	// keyed like types
+	modulePkgs map[string]*packages.Package
+}
+
+// NewMemoryStorage returns a new, empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		types:       make(map[string]StoredType),
+		moduleNames: make(map[string]string),
+		modulePkgs:  make(map[string]*packages.Package),
+	}
+}
+
+func (*MemoryStorage) key(packagePath, typeName, version string) string {
+	return packagePath + "." + typeName + "@" + version
+}
+
+// GetTypeByName implements Storage.
+func (ms *MemoryStorage) GetTypeByName(packagePath, name, version string) (*Value, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.types[ms.key(packagePath, name, version)].Representation, nil
+}
+
+// GetTypesByCaddyModuleID implements Storage. The types of all
+// versions are returned.
+func (ms *MemoryStorage) GetTypesByCaddyModuleID(caddyModuleID string) ([]*Value, error) {
+	return ms.GetTypesByCaddyModuleIDAt(caddyModuleID, "")
+}
+
+// GetTypesByCaddyModuleIDAt implements VersionedStorage.
+func (ms *MemoryStorage) GetTypesByCaddyModuleIDAt(caddyModuleID, version string) ([]*Value, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var vals []*Value
+	for key, moduleName := range ms.moduleNames {
+		if st := ms.types[key]; moduleName == caddyModuleID && (version == "" || st.Version == version) && st.Representation != nil {
+			vals = append(vals, st.Representation)
+		}
+	}
+	return vals, nil
+}
+
+// GetModuleIDsByNamespace implements Storage. The module IDs are not
+// in any particular order.
+func (ms *MemoryStorage) GetModuleIDsByNamespace(namespace string) ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	seen := make(map[string]bool)
+	var moduleIDs []string
+	for _, moduleName := range ms.moduleNames {
+		if ns, _ := ParseModuleID(moduleName); ns == namespace && !seen[moduleName] {
+			seen[moduleName] = true
+			moduleIDs = append(moduleIDs, moduleName)
+		}
+	}
+	return moduleIDs, nil
+}
+
+// StoreType implements Storage.
+func (ms *MemoryStorage) StoreType(packagePath, typeName, version string, rep *Value) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.types[ms.key(packagePath, typeName, version)] = StoredType{
+		PackagePath:    packagePath,
+		TypeName:       typeName,
+		Version:        version,
+		Representation: rep,
+	}
+	return nil
+}
+
+// ListTypesForPackage implements PackageListingStorage.
+func (ms *MemoryStorage) ListTypesForPackage(packagePath string) ([]StoredType, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var storedTypes []StoredType
+	for key, st := range ms.types {
+		if st.PackagePath == packagePath {
+			st.ModuleName = ms.moduleNames[key]
+			storedTypes = append(storedTypes, st)
+		}
+	}
+	return storedTypes, nil
+}
+
+// SetCaddyModuleName implements Storage. The name is associated
+// with the type at the version of pkg's module, if it has one; use
+// SetCaddyModuleNameAt to give the version explicitly.
+func (ms *MemoryStorage) SetCaddyModuleName(pkg *packages.Package, typeName, modName string) error {
+	var version string
+	if pkg.Module != nil {
+		version = pkg.Module.Version
+	}
+	return ms.SetCaddyModuleNameAt(pkg, typeName, version, modName)
+}
+
+// SetCaddyModuleNameAt implements VersionedStorage.
+func (ms *MemoryStorage) SetCaddyModuleNameAt(pkg *packages.Package, typeName, version, modName string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	key := ms.key(pkg.PkgPath, typeName, version)
+	if existing, ok := ms.moduleNames[key]; ok && existing != modName {
+		return fmt.Errorf("%w: %s is already %s", ErrModuleNameConflict, key, existing)
+	}
+	ms.moduleNames[key] = modName
+	ms.modulePkgs[key] = pkg
+	return nil
+}
+
+// allTypes returns all the stored types, with their module names.
+func (ms *MemoryStorage) allTypes() []StoredType {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	storedTypes := make([]StoredType, 0, len(ms.types))
+	for key, st := range ms.types {
+		st.ModuleName = ms.moduleNames[key]
+		storedTypes = append(storedTypes, st)
+	}
+	return storedTypes
+}
+
+// AllTypes implements MutableStorage.
+func (ms *MemoryStorage) AllTypes() ([]StoredType, error) {
+	return ms.allTypes(), nil
+}
+
+// MoveType implements MutableStorage.
+func (ms *MemoryStorage) MoveType(packagePath, typeName, version, newPackagePath string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	key := ms.key(packagePath, typeName, version)
+	st, ok := ms.types[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTypeNotFound, key)
+	}
+	newKey := ms.key(newPackagePath, typeName, version)
+	st.PackagePath = newPackagePath
+	ms.types[newKey] = st
+	delete(ms.types, key)
+	if moduleName, ok := ms.moduleNames[key]; ok {
+		ms.moduleNames[newKey] = moduleName
+		ms.modulePkgs[newKey] = ms.modulePkgs[key]
+		delete(ms.moduleNames, key)
+		delete(ms.modulePkgs, key)
+	}
+	return nil
+}
+
+// MemoryCheckpoint is a Checkpoint that keeps the progress of an ingest
+// in memory, so an ingest can be resumed within the same process, e.g.
+// after it was canceled or failed. It is safe for concurrent use. Use
+// NewMemoryCheckpoint to make one.
+type MemoryCheckpoint struct {
+	mu   sync.Mutex
+	done map[PackageVersion]bool
+}
+
+// NewMemoryCheckpoint returns a new MemoryCheckpoint with nothing done.
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{done: make(map[PackageVersion]bool)}
+}
+
+// Done implements Checkpoint.
+func (mc *MemoryCheckpoint) Done(pattern, version string) (bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.done[PackageVersion{Pattern: pattern, Version: version}], nil
+}
+
+// MarkDone implements Checkpoint.
+func (mc *MemoryCheckpoint) MarkDone(pattern, version string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.done[PackageVersion{Pattern: pattern, Version: version}] = true
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestGetModuleIDsByNamespace(t *testing.T) {
+	db := NewMemoryStorage()
+	pkg := &packages.Package{PkgPath: "example.com/foo"}
+	for i, moduleID := range []string{
+		"tls",
+		"http",
+		"http.handlers.file_server",
+		"http.handlers.reverse_proxy",
+		"http.handlers.reverse_proxy.selection_policies.random",
+		"http.matchers.path",
+	} {
+		typeName := "Type" + string(rune('A'+i))
+		if err := db.StoreType(pkg.PkgPath, typeName, "", &Value{Type: Struct}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetCaddyModuleNameAt(pkg, typeName, "", moduleID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the same module at another version is only listed once
+	if err := db.StoreType(pkg.PkgPath, "TypeC", "v1.0.0", &Value{Type: Struct}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetCaddyModuleNameAt(pkg, "TypeC", "v1.0.0", "http.handlers.file_server"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		namespace string
+		expect    []string
+	}{
+		{namespace: "", expect: []string{"http", "tls"}},
+		{namespace: "http.handlers", expect: []string{"http.handlers.file_server", "http.handlers.reverse_proxy"}},
+		{namespace: "http.handlers.reverse_proxy.selection_policies", expect: []string{"http.handlers.reverse_proxy.selection_policies.random"}},
+		{namespace: "http.matchers", expect: []string{"http.matchers.path"}},
+		{namespace: "http", expect: nil},
+		{namespace: "tls.handshake_match", expect: nil},
+	} {
+		actual, err := db.GetModuleIDsByNamespace(tc.namespace)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%q): expected %q, got %q", i, tc.namespace, tc.expect, actual)
+		}
+	}
+}
+
+func TestModuleNameVersions(t *testing.T) {
+	db := NewMemoryStorage()
+	const pkgPath = "example.com/foo"
+	for _, version := range []string{"v1.0.0", "v2.0.0"} {
+		rep := &Value{Type: Struct, TypeName: pkgPath + ".Handler", Doc: "Handler " + version + "."}
+		if err := db.StoreType(pkgPath, "Handler", version, rep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one version by its package's module, the other explicitly
+	pkg := &packages.Package{PkgPath: pkgPath, Module: &packages.Module{Path: pkgPath, Version: "v1.0.0"}}
+	if err := db.SetCaddyModuleName(pkg, "Handler", "foo.handler"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetCaddyModuleNameAt(pkg, "Handler", "v2.0.0", "foo.handler"); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := func(vals []*Value) []string {
+		var docs []string
+		for _, val := range vals {
+			docs = append(docs, val.Doc)
+		}
+		sort.Strings(docs)
+		return docs
+	}
+	for i, tc := range []struct {
+		version string
+		expect  []string
+	}{
+		{version: "v1.0.0", expect: []string{"Handler v1.0.0."}},
+		{version: "v2.0.0", expect: []string{"Handler v2.0.0."}},
+		{version: "v3.0.0", expect: nil},
+		{version: "", expect: []string{"Handler v1.0.0.", "Handler v2.0.0."}},
+	} {
+		vals, err := db.GetTypesByCaddyModuleIDAt("foo.handler", tc.version)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if actual := docs(vals); !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected %q, got %q", i, tc.version, tc.expect, actual)
+		}
+	}
+
+	// without a version, all of them are returned
+	vals, err := db.GetTypesByCaddyModuleID("foo.handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect, actual := []string{"Handler v1.0.0.", "Handler v2.0.0."}, docs(vals); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+
+	// each version keeps its own association
+	if err := db.SetCaddyModuleNameAt(pkg, "Handler", "v2.0.0", "foo.other"); !errors.Is(err, ErrModuleNameConflict) {
+		t.Errorf("expected a module name conflict, got %v", err)
+	}
+	if moduleIDs, _ := db.GetModuleIDsByNamespace("foo"); !reflect.DeepEqual(moduleIDs, []string{"foo.handler"}) {
+		t.Errorf("expected only foo.handler in namespace foo, got %q", moduleIDs)
+	}
+}
+
+func TestModuleNameConflict(t *testing.T) {
+	db := NewMemoryStorage()
+	pkg := &packages.Package{PkgPath: "example.com/foo"}
+	if err := db.SetCaddyModuleName(pkg, "Handler", "http.handlers.foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// associating the same name again is fine
+	if err := db.SetCaddyModuleName(pkg, "Handler", "http.handlers.foo"); err != nil {
+		t.Errorf("expected no error for the same name, got %v", err)
+	}
+
+	// but another name is not
+	err := db.SetCaddyModuleName(pkg, "Handler", "http.handlers.bar")
+	if !errors.Is(err, ErrModuleNameConflict) {
+		t.Errorf("expected ErrModuleNameConflict, got %v", err)
+	}
+	ids, err := db.GetModuleIDsByNamespace("http.handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"http.handlers.foo"}; !reflect.DeepEqual(ids, expect) {
+		t.Errorf("expected module IDs %v, got %v", expect, ids)
+	}
+}
+
+func TestModuleNameConflictDiagnostic(t *testing.T) {
+	d, db := newTestDriver(t)
+	pkg := &packages.Package{PkgPath: "example.com/fixture/gizmos"}
+	if err := db.SetCaddyModuleName(pkg, "Gizmo", "fixture.gizmos.other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the conflicting module is skipped, not the whole load
+	mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 0 {
+		t.Errorf("expected no modules, got %+v", mods)
+	}
+	diags := d.TakeDiagnostics()
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, ErrModuleNameConflict.Error()) {
+		t.Fatalf("expected a diagnostic about the conflict, got %+v", diags)
+	}
+	if filepath.Base(diags[0].File) != "gizmos.go" || diags[0].Severity != SeverityWarning {
+		t.Errorf("expected a warning in gizmos.go, got %s in %s", diags[0].Severity, diags[0].File)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+// ProgressEvent is a milestone of loading docs (see Driver.OnProgress).
+// Only the fields that are relevant to its kind are set.
+type ProgressEvent struct {
+	Kind ProgressKind
+
+	// The directory of the workspace that was opened.
+	Dir string
+
+	// The package path (or pattern) involved: the one that was
+	// gotten or loaded, or the package of the type or module.
+	PackagePath string
+
+	// The local name of the type that was stored, or of
+	// the type of the module that was discovered.
+	TypeName string
+
+	// The version of the module that was gotten, or of
+	// the package, type, or module.
+	Version string
+
+	// The name of the Caddy module that was discovered.
+	ModuleName string
+}
+
+// ProgressKind is a kind of ProgressEvent.
+type ProgressKind string
+
+// Kinds of progress events.
+const (
+	// A workspace was opened, in which packages are
+	// loaded (see Driver.ModuleDir).
+	ProgressWorkspaceOpened ProgressKind = "workspace_opened"
+
+	// The module of a package was downloaded into the
+	// workspace with 'go get'.
+	ProgressModuleGotten ProgressKind = "module_gotten"
+
+	// A package was loaded and type-checked.
+	ProgressPackageLoaded ProgressKind = "package_loaded"
+
+	// A type representation was stored.
+	ProgressTypeStored ProgressKind = "type_stored"
+
+	// A Caddy module was discovered and associated
+	// with its type.
+	ProgressModuleDiscovered ProgressKind = "module_discovered"
+)
+
+// progress reports event to the OnProgress function, if any.
+func (d *Driver) progress(event ProgressEvent) {
+	if d.OnProgress != nil {
+		d.OnProgress(event)
+	}
+}
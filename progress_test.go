@@ -0,0 +1,65 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgressAddType(t *testing.T) {
+	d, _ := newTestDriver(t)
+	var events []ProgressEvent
+	d.OnProgress = func(event ProgressEvent) {
+		events = append(events, event)
+	}
+
+	if _, err := d.AddType("example.com/fixture/gizmos", "Gizmo", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the workspace is an existing module, so nothing is gotten;
+	// and types are stored after the types they refer to
+	expect := []ProgressEvent{
+		{Kind: ProgressWorkspaceOpened, Dir: d.ModuleDir},
+		{Kind: ProgressPackageLoaded, PackagePath: "example.com/fixture/gizmos"},
+		{Kind: ProgressTypeStored, PackagePath: "example.com/fixture/gizmos", TypeName: "Part"},
+		{Kind: ProgressTypeStored, PackagePath: "example.com/fixture/gizmos", TypeName: "Gizmo"},
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Errorf("expected events:\n%+v\ngot:\n%+v", expect, events)
+	}
+}
+
+func TestProgressModuleDiscovered(t *testing.T) {
+	d, _ := newTestDriver(t)
+	var discovered []ProgressEvent
+	d.OnProgress = func(event ProgressEvent) {
+		if event.Kind == ProgressModuleDiscovered {
+			discovered = append(discovered, event)
+		}
+	}
+
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []ProgressEvent{
+		{Kind: ProgressModuleDiscovered, PackagePath: "example.com/fixture/gizmos", TypeName: "Gizmo", ModuleName: "fixture.gizmos.gizmo"},
+	}
+	if !reflect.DeepEqual(discovered, expect) {
+		t.Errorf("expected events:\n%+v\ngot:\n%+v", expect, discovered)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONSkeleton returns a sample JSON structure for v, which can serve
+// as a starting point for writing its config: structs are objects with
+// all of their (exported) fields present, in order; arrays are empty
+// arrays; maps and module maps are empty objects; and values of basic
+// types are their zero values. A module is an object that, if the
+// module's name is specified inline, has only its inline key, with a
+// placeholder for the name like "<http.handlers module name>". Values
+// of type Any, and anything else that has no particular structure,
+// are null.
+//
+// References to other types (SameAs) are not followed, so v should be
+// dereferenced deeply first, if needed (as with LoadTypeByPath); any
+// remaining references, as to recursive types, are null as well.
+func (v *Value) JSONSkeleton() json.RawMessage {
+	var buf bytes.Buffer
+	v.writeJSONSkeleton(&buf, make(map[*Value]bool))
+	return buf.Bytes()
+}
+
+func (v *Value) writeJSONSkeleton(buf *bytes.Buffer, ancestors map[*Value]bool) {
+	if v == nil || ancestors[v] {
+		buf.WriteString("null")
+		return
+	}
+	ancestors[v] = true
+	defer delete(ancestors, v)
+
+	switch v.Type {
+	case Struct:
+		buf.WriteByte('{')
+		var n int
+		for _, sf := range v.StructFields {
+			if sf.Unexported {
+				continue
+			}
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, sf.Key)
+			buf.WriteByte(':')
+			sf.Value.writeJSONSkeleton(buf, ancestors)
+			n++
+		}
+		buf.WriteByte('}')
+
+	case Module:
+		if v.ModuleInlineKey == nil || *v.ModuleInlineKey == "" {
+			buf.WriteString("{}")
+			return
+		}
+		placeholder := "<module name>"
+		if v.ModuleNamespace != nil && *v.ModuleNamespace != "" {
+			placeholder = "<" + *v.ModuleNamespace + " module name>"
+		}
+		buf.WriteByte('{')
+		writeJSONString(buf, *v.ModuleInlineKey)
+		buf.WriteByte(':')
+		writeJSONString(buf, placeholder)
+		buf.WriteByte('}')
+
+	case Array:
+		buf.WriteString("[]")
+	case Map, ModuleMap:
+		buf.WriteString("{}")
+	case String:
+		buf.WriteString(`""`)
+	case Int, Uint, Float:
+		buf.WriteByte('0')
+	case Bool:
+		buf.WriteString("false")
+	default:
+		buf.WriteString("null")
+	}
+}
+
+// writeJSONString writes s to buf as a JSON string. Unlike
+// with json.Marshal, the placeholders' angle brackets are not
+// escaped, since the skeleton is meant to be read and edited.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)               // can't fail for a string
+	buf.Truncate(buf.Len() - 1) // Encode adds a newline
+}
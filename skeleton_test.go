@@ -0,0 +1,89 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var updateGoldenFiles = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestJSONSkeleton(t *testing.T) {
+	d, _ := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/servers", "Server", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		path   string
+		golden string
+	}{
+		{path: "", golden: "server.json"},
+		{path: "tls", golden: "tls.json"},
+		{path: "handler", golden: "handler.json"},
+	} {
+		exact, _, err := d.LoadTypeByPathFrom("example.com/fixture/servers", "Server", tc.path, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.path, err)
+			continue
+		}
+		skeleton := exact.JSONSkeleton()
+		if !json.Valid(skeleton) {
+			t.Errorf("Test %d (%s): skeleton is not valid JSON: %s", i, tc.path, skeleton)
+			continue
+		}
+		var actual bytes.Buffer
+		if err := json.Indent(&actual, skeleton, "", "\t"); err != nil {
+			t.Fatal(err)
+		}
+		actual.WriteByte('\n')
+
+		goldenFile := filepath.Join("testdata", "skeleton", tc.golden)
+		if *updateGoldenFiles {
+			if err := ioutil.WriteFile(goldenFile, actual.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expect, err := ioutil.ReadFile(goldenFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actual.Bytes(), expect) {
+			t.Errorf("Test %d (%s): expected skeleton:\n%s\ngot:\n%s", i, tc.path, expect, actual.Bytes())
+		}
+	}
+}
+
+func TestJSONSkeletonRecursive(t *testing.T) {
+	// a value that contains itself (as a type can, through
+	// pointers) must end somewhere
+	val := &Value{Type: Struct}
+	val.StructFields = []*StructField{
+		{Key: "name", Value: &Value{Type: String}},
+		{Key: "next", Value: val},
+	}
+	if expect, actual := `{"name":"","next":null}`, string(val.JSONSkeleton()); actual != expect {
+		t.Errorf("expected %s, got %s", expect, actual)
+	}
+	if expect, actual := `null`, string((*Value)(nil).JSONSkeleton()); actual != expect {
+		t.Errorf("expected %s, got %s", expect, actual)
+	}
+}
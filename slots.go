@@ -0,0 +1,71 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+// ModuleSlot is a place in a config structure where a module
+// (or a map of modules) goes.
+type ModuleSlot struct {
+	// The config path to the slot, relative to the value it is
+	// in (see ConfigPathParts); like other config paths, it does
+	// not have segments for array indices or map keys.
+	Path string `json:"path"`
+
+	// The namespace and the inline key of the modules,
+	// as with Value.ModuleNamespace and ModuleInlineKey.
+	Namespace *string `json:"namespace,omitempty"`
+	InlineKey *string `json:"inline_key,omitempty"`
+
+	// Whether the slot is a module map, i.e. a map of
+	// module names to configs, rather than a module.
+	Map bool `json:"map,omitempty"`
+}
+
+// ModuleSlots returns the module slots within v: the module and module
+// map values reachable from v through struct fields and the elements
+// of arrays and maps, in order. References to other types (SameAs) are
+// not followed, so v should be dereferenced deeply first, if needed (as
+// with LoadTypeByPath). Values that occur more than once in the tree
+// (e.g. because of a cycle) are only visited once.
+func (v *Value) ModuleSlots() []ModuleSlot {
+	var slots []ModuleSlot
+	v.moduleSlots("", make(map[*Value]bool), &slots)
+	return slots
+}
+
+func (v *Value) moduleSlots(path string, visited map[*Value]bool, slots *[]ModuleSlot) {
+	if v == nil || visited[v] {
+		return
+	}
+	visited[v] = true
+
+	switch v.Type {
+	case Module, ModuleMap:
+		*slots = append(*slots, ModuleSlot{
+			Path:      path,
+			Namespace: cloneStringPtr(v.ModuleNamespace),
+			InlineKey: cloneStringPtr(v.ModuleInlineKey),
+			Map:       v.Type == ModuleMap,
+		})
+		return
+	}
+
+	for _, sf := range v.StructFields {
+		if sf.Unexported {
+			continue
+		}
+		sf.Value.moduleSlots(joinPath(path, sf.Key), visited, slots)
+	}
+	v.Elems.moduleSlots(path, visited, slots)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestModuleSlots(t *testing.T) {
+	handlers, matchers, encoders := "http.handlers", "http.matchers", "http.encoders"
+	handler := "handler"
+
+	route := &Value{
+		Type: Struct,
+		StructFields: []*StructField{
+			{Key: "handle", Value: &Value{Type: Module, ModuleNamespace: &handlers, ModuleInlineKey: &handler}},
+			{Key: "match", Value: &Value{Type: Array, Elems: &Value{Type: ModuleMap, ModuleNamespace: &matchers}}},
+			{Key: "hidden", Unexported: true, Value: &Value{Type: Module, ModuleNamespace: &handlers}},
+		},
+	}
+	// a route can contain routes (through a subroute)
+	route.StructFields = append(route.StructFields, &StructField{
+		Key:   "routes",
+		Value: &Value{Type: Array, Elems: route},
+	})
+	server := &Value{
+		Type: Struct,
+		StructFields: []*StructField{
+			{Key: "listen", Value: &Value{Type: Array, Elems: &Value{Type: String}}},
+			{Key: "routes", Value: &Value{Type: Array, Elems: route}},
+			{Key: "encodings", Value: &Value{Type: Map, Elems: &Value{
+				Type: Struct,
+				StructFields: []*StructField{
+					{Key: "encoder", Value: &Value{Type: Module, ModuleNamespace: &encoders}},
+				},
+			}}},
+			{Key: "other", Value: &Value{SameAs: "example.com/foo.Other"}},
+		},
+	}
+
+	expect := []ModuleSlot{
+		{Path: "routes/handle", Namespace: &handlers, InlineKey: &handler},
+		{Path: "routes/match", Namespace: &matchers, Map: true},
+		{Path: "encodings/encoder", Namespace: &encoders},
+	}
+	if actual := server.ModuleSlots(); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("expected slots %s, got %s", slotsString(expect), slotsString(actual))
+	}
+
+	// a module value is itself a slot, at the root
+	if actual := route.StructFields[0].Value.ModuleSlots(); len(actual) != 1 || actual[0].Path != "" || *actual[0].Namespace != handlers {
+		t.Errorf("expected a slot at the root, got %s", slotsString(actual))
+	}
+	if actual := (&Value{Type: String}).ModuleSlots(); len(actual) != 0 {
+		t.Errorf("expected no slots, got %s", slotsString(actual))
+	}
+}
+
+// slotsString returns slots as JSON, for test failures.
+func slotsString(slots []ModuleSlot) string {
+	b, _ := json.Marshal(slots)
+	return string(b)
+}
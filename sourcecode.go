@@ -17,10 +17,12 @@ package moduledoc
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/token"
 	"go/types"
-	"log"
 	"strings"
 
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -30,34 +32,82 @@ import (
 // module registrations are useless to us because they do not contain the module name:
 // for that, we need to inspect the AST of the type's CaddyModule method; but we check
 // for module registrations anyway because a caddy.Module that is not registered cannot
-// be used (or at the very least, is inconsistent) so we return an error in that case.
+// be used (or at the very least, is inconsistent) so we return an error in that case
+// (unless the driver is configured to tolerate it).
 //
-// This function returns a map of type identifiers from the AST to their associated
-// Caddy module IDs.
-func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]string, error) {
+// This function returns a map of type identifiers from the AST to information about
+// their associated Caddy modules, such as their IDs, along with diagnostics about
+// problems that were not errors, such as modules that had to be skipped.
+func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]caddyModuleDecl, []Diagnostic, error) {
+	var diags []Diagnostic
 	caddyModRegs := make(map[string]*ast.Ident)
 	caddyModImpls := make(map[string]*ast.Ident)
 	caddyModIDs := make(map[string]string)
+	caddyModComputedIDs := make(map[string][]string)
+	caddyModRegDocs := make(map[string]string)
+	caddyModMethodDocs := make(map[string]map[string]string)
 
 	for _, file := range pkg.Syntax {
 		var inspectErr error
 		var currentCaddyModuleFunc *ast.Ident
+		var insideRegistrationFunc bool
+
+		// if the CaddyModule() method has a named result, it might
+		// assign the ModuleInfo to it and use a bare return
+		var namedResult types.Object
+		var namedResultValue *ast.CompositeLit
+
+		// comments are associated with statements (not expressions)
+		// in a comment map, so remember the comments of statements
+		// that are function calls, in case they register a module
+		var cmap ast.CommentMap
+		callComments := make(map[*ast.CallExpr][]*ast.CommentGroup)
+		if ds.IncludeRegistrationDocs {
+			cmap = ast.NewCommentMap(pkg.Fset, file, file.Comments)
+		}
+
 		ast.Inspect(file, func(node ast.Node) bool {
 			switch val := node.(type) {
+			case *ast.ExprStmt:
+				if call, ok := val.X.(*ast.CallExpr); ok && cmap != nil {
+					callComments[call] = cmap[val]
+				}
+
 			case *ast.CallExpr:
+				// the body of a registration wrapper registers whatever
+				// module it is given, so there is nothing to learn there
+				if insideRegistrationFunc {
+					return true
+				}
+
 				// function call; look for module registration which is
-				// a call to caddy.RegisterModule()
-				moduleReg, err := ds.findModuleRegistration(pkg, val)
+				// a call to caddy.RegisterModule() (or an equivalent)
+				moduleRegs, regDiags, err := ds.findModuleRegistration(pkg, val)
+				diags = append(diags, regDiags...)
 				if err != nil {
 					inspectErr = err
 					return false
 				}
-				if moduleReg == nil {
-					return true
+				for _, moduleReg := range moduleRegs {
+					caddyModRegs[moduleReg.Name] = moduleReg
+					if regDoc := commentsText(callComments[val]); regDoc != "" {
+						caddyModRegDocs[moduleReg.Name] = regDoc
+					}
 				}
-				caddyModRegs[moduleReg.Name] = moduleReg
 
 			case *ast.FuncDecl:
+				insideRegistrationFunc = ds.isRegistrationFunc(pkg.TypesInfo.Defs[val.Name])
+
+				// remember the docs of methods that describe module behavior
+				if doc := val.Doc.Text(); doc != "" && ds.isDocumentedMethod(val.Name.Name) {
+					if receiver := receiverTypeIdent(val); receiver != nil {
+						if caddyModMethodDocs[receiver.Name] == nil {
+							caddyModMethodDocs[receiver.Name] = make(map[string]string)
+						}
+						caddyModMethodDocs[receiver.Name][val.Name.Name] = normalizeDoc(doc)
+					}
+				}
+
 				// function (or method) declaration; look for CaddyModule()
 				// method, which implements the caddy.Module interface
 				moduleImpl, err := ds.findModuleImpl(val)
@@ -70,6 +120,28 @@ func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]s
 				}
 				caddyModImpls[moduleImpl.Name] = moduleImpl
 				currentCaddyModuleFunc = moduleImpl
+				namedResult, namedResultValue = nil, nil
+				if results := val.Type.Results; results != nil && len(results.List) == 1 && len(results.List[0].Names) == 1 {
+					namedResult = pkg.TypesInfo.Defs[results.List[0].Names[0]]
+				}
+
+			case *ast.AssignStmt:
+				// remember the value assigned to the named result, if any
+				if currentCaddyModuleFunc == nil || namedResult == nil || len(val.Lhs) != len(val.Rhs) {
+					break
+				}
+				for i, lhs := range val.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && pkg.TypesInfo.Uses[ident] == namedResult {
+						namedResultValue, _ = val.Rhs[i].(*ast.CompositeLit)
+					}
+				}
+
+			case *ast.FuncLit:
+				// return statements in function literals (like the
+				// ModuleInfo's New function) are not the method's
+				if currentCaddyModuleFunc != nil {
+					return false
+				}
 
 			case *ast.ReturnStmt:
 				// return statement; look for caddy.ModuleInfo struct so we
@@ -80,16 +152,31 @@ func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]s
 					break
 				}
 
-				// expect exactly 1 return value
-				if len(val.Results) != 1 {
+				// expect exactly 1 return value, or a bare return
+				// of the named result
+				var result ast.Expr
+				switch {
+				case len(val.Results) == 1:
+					result = val.Results[0]
+				case len(val.Results) == 0 && namedResultValue != nil:
+					result = namedResultValue
+				case len(val.Results) == 0:
+					diags = append(diags, newDiagnostic(pkg.Fset, SeverityWarning, val.Pos(),
+						"CaddyModule() method of %s has a bare return, but its named result is not assigned a composite literal; skipping",
+						currentCaddyModuleFunc.Name))
+					delete(caddyModRegs, currentCaddyModuleFunc.Name)
+					delete(caddyModImpls, currentCaddyModuleFunc.Name)
+					currentCaddyModuleFunc = nil
+					return true
+				default:
 					inspectErr = fmt.Errorf("expected exactly 1 return value from %#v, got %d", val, len(val.Results))
 					return false
 				}
 
 				// it should be a composite literal (struct)
-				compLit, ok := val.Results[0].(*ast.CompositeLit)
+				compLit, ok := result.(*ast.CompositeLit)
 				if !ok {
-					inspectErr = fmt.Errorf("expected composite literal return value from %#v; got %#v", val, val.Results[0])
+					inspectErr = fmt.Errorf("expected composite literal return value from %#v; got %#v", val, result)
 					return false
 				}
 
@@ -97,28 +184,38 @@ func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]s
 
 				// peer inside its elements to get the name
 				var caddyModName string
+				var computedIDs []string
 				for _, element := range compLit.Elts {
 					kv, ok := element.(*ast.KeyValueExpr)
 					if !ok {
 						continue
 					}
 					if kv.Key.(*ast.Ident).Name == "ID" {
-						// TODO: configadapters.go in the main caddy module has an unexported helper type called
-						// adapterModule which implements CaddyModule interface, and its ID is computed, not static:
-						// `caddy.ModuleID("caddy.adapters." + am.name)` - this is obviously problematic here...
-						// but that's also a special case that real modules should not be having
-						kvValueBasicLiteral, ok := kv.Value.(*ast.BasicLit)
+						// some IDs are computed, not static, like that of core Caddy's
+						// adapterModule type: `caddy.ModuleID("caddy.adapters." + am.name)`;
+						// real modules should not be doing that, but if the IDs such a
+						// type takes are configured, it is documented with each of them
+						id, ok := moduleIDValue(pkg, kv.Value)
 						if !ok {
-							log.Printf("[WARNING] CaddyModule() method in %s returns ModuleInfo with unsupported ID value (must be a static literal value); skipping: %#v", file.Name, kv.Value)
+							var diag *Diagnostic
+							computedIDs, diag = ds.computedModuleIDs(pkg, currentCaddyModuleFunc.Name, kv.Value)
+							if len(computedIDs) > 0 {
+								caddyModName = computedIDs[0]
+								break
+							}
+							if diag == nil {
+								d := newDiagnostic(pkg.Fset, SeverityWarning, kv.Value.Pos(),
+									"CaddyModule() method of %s returns ModuleInfo with unsupported ID value (must be a static value); skipping: %s",
+									currentCaddyModuleFunc.Name, types.ExprString(kv.Value))
+								diag = &d
+							}
+							diags = append(diags, *diag)
 							delete(caddyModRegs, currentCaddyModuleFunc.Name)
 							delete(caddyModImpls, currentCaddyModuleFunc.Name)
 							currentCaddyModuleFunc = nil
 							return true
 						}
-
-						// TODO: What if the module name is pulled out to a constant? do we need to evaluate it?
-						rawString := kvValueBasicLiteral.Value
-						caddyModName = strings.Trim(rawString, `"`)
+						caddyModName = id
 						break
 					}
 				}
@@ -128,108 +225,569 @@ func (ds *Driver) findCaddyModuleIdents(pkg *packages.Package) (map[*ast.Ident]s
 					return false
 				}
 
+				// the New function should construct the same type whose
+				// CaddyModule method this is; if not, the docs would be
+				// for a different type than the one that is configured
+				for _, element := range compLit.Elts {
+					kv, ok := element.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					if key, ok := kv.Key.(*ast.Ident); !ok || key.Name != "New" {
+						continue
+					}
+					newType := constructedType(pkg, kv.Value)
+					if newType != nil && (newType.Obj().Name() != currentCaddyModuleFunc.Name ||
+						newType.Obj().Pkg() == nil || newType.Obj().Pkg().Path() != pkg.PkgPath) {
+						diags = append(diags, newDiagnostic(pkg.Fset, SeverityWarning, kv.Value.Pos(),
+							"CaddyModule() method of %s returns ModuleInfo for %s, but its New function constructs a %s",
+							currentCaddyModuleFunc.Name, caddyModName, newType))
+					}
+				}
+
 				// associate the caddy module name with the type name
 				caddyModIDs[currentCaddyModuleFunc.Name] = caddyModName
+				if computedIDs != nil {
+					caddyModComputedIDs[currentCaddyModuleFunc.Name] = computedIDs
+				}
 				currentCaddyModuleFunc = nil
 			}
 
 			return true
 		})
 		if inspectErr != nil {
-			return nil, inspectErr
+			return nil, diags, inspectErr
 		}
 	}
 
 	// see if any caddy module types are implemented but
-	// not registered, and vice-versa
+	// not registered, and vice-versa; if tolerated, such
+	// types are skipped (deleting from the map being
+	// ranged over is fine)
+	inconsistent := func(ident *ast.Ident, err error) error {
+		if !ds.TolerateModuleInconsistencies {
+			return err
+		}
+		diags = append(diags, newDiagnostic(pkg.Fset, SeverityError, ident.Pos(), "%s: %v; skipping", ident.Name, err))
+		delete(caddyModRegs, ident.Name)
+		delete(caddyModImpls, ident.Name)
+		return nil
+	}
+	moduleIface := caddyModuleInterface(pkg)
 	for key, val := range caddyModRegs {
-		if _, ok := caddyModImpls[key]; !ok {
-			return nil, fmt.Errorf("caddy module gets registered but does not implement caddy.Module interface: %#v", val)
+		var err error
+		if notModuleErr := registeredTypeIsModule(pkg, val, moduleIface); notModuleErr != nil {
+			err = notModuleErr
+		} else if _, ok := caddyModImpls[key]; !ok {
+			err = fmt.Errorf("caddy module gets registered but does not implement caddy.Module interface: %#v", val)
+		} else if _, ok := caddyModIDs[key]; !ok {
+			err = fmt.Errorf("caddy module gets registered, but we could not find its module name: %#v", val)
 		}
-		if _, ok := caddyModIDs[key]; !ok {
-			return nil, fmt.Errorf("caddy module gets registered, but we could not find its module name: %#v", val)
+		if err != nil {
+			if err := inconsistent(val, err); err != nil {
+				return nil, diags, err
+			}
 		}
 	}
 	for key, val := range caddyModImpls {
+		var err error
 		if _, ok := caddyModRegs[key]; !ok {
-			return nil, fmt.Errorf("type has CaddyModule method, but does not get registered via caddy.%s(): %#v", registerModule, val)
+			err = fmt.Errorf("type has CaddyModule method, but does not get registered via caddy.%s(): %#v", registerModule, val)
+		} else if _, ok := caddyModIDs[key]; !ok {
+			err = fmt.Errorf("type has CaddyModule method, but we could not find its module name: %#v", val)
 		}
-		if _, ok := caddyModIDs[key]; !ok {
-			return nil, fmt.Errorf("type has CaddyModule method, but we could not find its module name: %#v", val)
+		if err != nil {
+			if err := inconsistent(val, err); err != nil {
+				return nil, diags, err
+			}
 		}
 	}
 
 	// the contents of all maps should now be consistent, so finally
 	// pair each type identifier with its caddy module name
-	mods := make(map[*ast.Ident]string)
+	mods := make(map[*ast.Ident]caddyModuleDecl)
 	for typeName, ident := range caddyModRegs {
-		mods[ident] = caddyModIDs[typeName]
+		mods[ident] = caddyModuleDecl{
+			id:              caddyModIDs[typeName],
+			computedIDs:     caddyModComputedIDs[typeName],
+			registrationDoc: caddyModRegDocs[typeName],
+			methodDocs:      caddyModMethodDocs[typeName],
+		}
 	}
 
-	return mods, nil
+	return mods, diags, nil
 }
 
-// findModuleRegistration returns an AST identifier for a type
-// that is registered using fnCall. If fnCall is not a call to
-// caddy.RegisterModule, nil is returned.
-func (ds *Driver) findModuleRegistration(pkg *packages.Package, fnCall *ast.CallExpr) (*ast.Ident, error) {
+// caddyModuleInterface returns the caddy.Module interface type, as seen
+// by pkg (i.e. from core Caddy at the version pkg imports), or nil if
+// it is not known, e.g. because pkg does not import core Caddy directly.
+func caddyModuleInterface(pkg *packages.Package) *types.Interface {
+	caddyPkg := pkg.Types
+	if pkg.PkgPath != caddyCorePackagePath {
+		imported, ok := pkg.Imports[caddyCorePackagePath]
+		if !ok {
+			return nil
+		}
+		caddyPkg = imported.Types
+	}
+	if caddyPkg == nil {
+		return nil
+	}
+	obj, ok := caddyPkg.Scope().Lookup("Module").(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	iface, _ := obj.Type().Underlying().(*types.Interface)
+	return iface
+}
+
+// registeredTypeIsModule returns an error if the type identified by
+// ident, which is registered as a module, does not implement iface
+// (the caddy.Module interface), either by value or by pointer; for
+// example, because its CaddyModule method has the wrong signature.
+// If the type or iface is not known, nil is returned.
+func registeredTypeIsModule(pkg *packages.Package, ident *ast.Ident, iface *types.Interface) error {
+	if iface == nil {
+		return nil
+	}
+	obj := pkg.TypesInfo.Uses[ident]
+	if obj == nil {
+		return nil
+	}
+	typ := unalias(obj.Type())
+	if inst, ok := pkg.TypesInfo.Instances[ident]; ok {
+		typ = inst.Type
+	} else if named, ok := typ.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil // uninstantiated generic type; nothing to check
+	}
+	if types.Implements(typ, iface) || types.Implements(types.NewPointer(typ), iface) {
+		return nil
+	}
+	method, wrongType := types.MissingMethod(types.NewPointer(typ), iface, true)
+	if method == nil {
+		return fmt.Errorf("registered type %s does not implement caddy.Module", typ)
+	}
+	if wrongType {
+		return fmt.Errorf("registered type %s does not implement caddy.Module: method %s has the wrong signature (want %s)",
+			typ, method.Name(), method.Type())
+	}
+	return fmt.Errorf("registered type %s does not implement caddy.Module: missing method %s", typ, method.Name())
+}
+
+// caddyModuleDecl is information about a Caddy module
+// that was found in source code.
+type caddyModuleDecl struct {
+	// the Caddy module ID
+	id string
+
+	// the known IDs of the module, if its ID is computed
+	// (see ComputedModuleID); id is the first of them
+	computedIDs []string
+
+	// the comments on the statement that registers
+	// the module, if enabled
+	registrationDoc string
+
+	// the godocs of the module type's methods,
+	// keyed by method name, if enabled
+	methodDocs map[string]string
+}
+
+// moduleIDValue returns the module ID that expr, the value of the ID field
+// of a caddy.ModuleInfo, evaluates to, if it can be determined statically:
+// i.e. if expr is constant (a string literal, a named constant, or the
+// conversion of one of those to caddy.ModuleID), or if it is a package
+// variable of type caddy.ModuleID whose initializer is constant.
+func moduleIDValue(pkg *packages.Package, expr ast.Expr) (string, bool) {
+	if pkg.TypesInfo == nil {
+		return "", false
+	}
+	if id, ok := constantString(pkg, expr); ok {
+		return id, true
+	}
+
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	v, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || v.Parent() != pkg.Types.Scope() || !isModuleIDType(v.Type()) {
+		return "", false
+	}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					if pkg.TypesInfo.Defs[name] == v && i < len(valueSpec.Values) {
+						return constantString(pkg, valueSpec.Values[i])
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// constantString returns the value of expr if it is a string constant.
+func constantString(pkg *packages.Package, expr ast.Expr) (string, bool) {
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// isModuleIDType returns true if typ is caddy.ModuleID.
+func isModuleIDType(typ types.Type) bool {
+	named, ok := unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == caddyCorePackagePath && named.Obj().Name() == "ModuleID"
+}
+
+// constructedType returns the type of the module constructed by newFunc,
+// which is the value of the New field of a caddy.ModuleInfo: either a
+// function literal, or a reference to a function declared in pkg. If
+// the type cannot be determined, nil is returned.
+func constructedType(pkg *packages.Package, newFunc ast.Expr) *types.Named {
+	var body *ast.BlockStmt
+	switch fn := newFunc.(type) {
+	case *ast.FuncLit:
+		body = fn.Body
+	case *ast.Ident:
+		obj := pkg.TypesInfo.Uses[fn]
+		if obj == nil {
+			return nil
+		}
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				if fnDecl, ok := decl.(*ast.FuncDecl); ok && pkg.TypesInfo.Defs[fnDecl.Name] == obj {
+					body = fnDecl.Body
+				}
+			}
+		}
+	}
+	if body == nil {
+		return nil
+	}
+
+	var constructed *types.Named
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch val := node.(type) {
+		case *ast.FuncLit:
+			return false // returns in here are for another function
+		case *ast.ReturnStmt:
+			if len(val.Results) != 1 || constructed != nil {
+				break
+			}
+			typ := unalias(pkg.TypesInfo.TypeOf(val.Results[0]))
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = unalias(ptr.Elem())
+			}
+			if named, ok := typ.(*types.Named); ok {
+				if _, isInterface := named.Underlying().(*types.Interface); !isInterface {
+					constructed = named
+				}
+			}
+		}
+		return true
+	})
+	return constructed
+}
+
+// commentsText returns the text of the comment groups
+// joined into one, or empty string if there are none.
+func commentsText(groups []*ast.CommentGroup) string {
+	var texts []string
+	for _, cg := range groups {
+		if text := cg.Text(); text != "" {
+			texts = append(texts, strings.TrimSpace(text))
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// findModuleRegistration returns the AST identifiers for types
+// that are registered using fnCall. If fnCall is not a call to
+// caddy.RegisterModule (or one of the driver's additional
+// registration functions), nil is returned. Usually there is
+// only one type, unless fnCall registers each element of a
+// slice in a loop.
+func (ds *Driver) findModuleRegistration(pkg *packages.Package, fnCall *ast.CallExpr) ([]*ast.Ident, []Diagnostic, error) {
+	fnName := registerModule
+
 	// this could be any function call; make sure it's
 	// actually a call to register a module
 	switch fn := fnCall.Fun.(type) {
 	case *ast.Ident:
+		if ds.isRegistrationFunc(pkg.TypesInfo.Uses[fn]) {
+			// a registration wrapper in the same package
+			fnName = fn.Name
+			break
+		}
+
 		// in the core caddy package, i.e. `RegisterModule(...)`
 		if fn.Name != registerModule {
-			return nil, nil
+			return nil, nil, nil
 		}
 	case *ast.SelectorExpr:
+		if ds.isRegistrationFunc(pkg.TypesInfo.Uses[fn.Sel]) {
+			// a registration wrapper in another package
+			fnName = fn.Sel.Name
+			break
+		}
+
 		// outside of core caddy package, i.e. `caddy.RegisterModule(...)`
 		if fn.Sel.Name != registerModule {
-			return nil, nil
+			return nil, nil, nil
 		}
 
 		// make sure the selector's field expression
 		// resolves to the actual caddy package
 		x, ok := fn.X.(*ast.Ident)
 		if !ok {
-			return nil, nil
+			return nil, nil, nil
 		}
 		if pkgName, ok := pkg.TypesInfo.Uses[x].(*types.PkgName); ok {
 			importedPkg := pkgName.Imported()
 			if importedPkg.Path() != caddyCorePackagePath {
-				return nil, fmt.Errorf("%s call does not resolve to %s; resolves to: %s",
+				return nil, nil, fmt.Errorf("%s call does not resolve to %s; resolves to: %s",
 					registerModule, caddyCorePackagePath, importedPkg.Path())
 			}
 		}
 	default:
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	if len(fnCall.Args) != 1 {
-		return nil, fmt.Errorf("wrong number of arguments to %s: %d (expected 1)",
-			registerModule, len(fnCall.Args))
+		return nil, nil, fmt.Errorf("wrong number of arguments to %s: %d (expected 1)",
+			fnName, len(fnCall.Args))
 	}
 
-	var caddyModuleIdent *ast.Ident
+	// happens with `for _, m := range mods { caddy.RegisterModule(m) }`
+	if argIdent, ok := fnCall.Args[0].(*ast.Ident); ok {
+		return ds.findRangedModuleRegistrations(pkg, fnName, argIdent)
+	}
 
-	switch val := fnCall.Args[0].(type) {
+	caddyModuleIdent, err := moduleTypeIdent(fnName, fnCall.Args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return []*ast.Ident{caddyModuleIdent}, nil, nil
+}
+
+// moduleTypeIdent returns the identifier of the type of the module
+// value expressed by arg, which is an argument to fnName.
+func moduleTypeIdent(fnName string, arg ast.Expr) (*ast.Ident, error) {
+	switch val := arg.(type) {
 	case *ast.CompositeLit:
 		// happens with `caddy.RegisterModule(Gizmo{})`
-		caddyModuleIdent = val.Type.(*ast.Ident)
+		// (or `Gizmo[T]{}`, for a generic type)
+		if typeIdent := typeExprIdent(val.Type); typeIdent != nil {
+			return typeIdent, nil
+		}
+
+	case *ast.UnaryExpr:
+		// happens with `caddy.RegisterModule(&Gizmo{})`
+		if compLit, ok := val.X.(*ast.CompositeLit); ok && val.Op == token.AND {
+			return moduleTypeIdent(fnName, compLit)
+		}
 
 	case *ast.CallExpr:
 		// happens with `caddy.RegisterModule(new(Gizmo))`
-		if funIdent, ok := val.Fun.(*ast.Ident); ok && funIdent.Name == "new" {
-			caddyModuleIdent = val.Args[0].(*ast.Ident)
-		} else {
-			return nil, fmt.Errorf("unknown function call in %s(): %#v - only support new()",
-				registerModule, val.Fun)
+		if funIdent, ok := val.Fun.(*ast.Ident); ok && funIdent.Name == "new" && len(val.Args) == 1 {
+			if typeIdent := typeExprIdent(val.Args[0]); typeIdent != nil {
+				return typeIdent, nil
+			}
 		}
-	default:
-		return nil, fmt.Errorf("unexpected argument to %s(): %#v - expect either composite literal or new()",
-			registerModule, val)
+		return nil, fmt.Errorf("unknown function call in %s(): %#v - only support new()",
+			fnName, val.Fun)
+	}
+	return nil, fmt.Errorf("unexpected argument to %s(): %#v - expect either composite literal or new()",
+		fnName, arg)
+}
+
+// typeExprIdent returns the identifier of the type named by expr, which
+// may be an instance of a generic type, e.g. `Gizmo[string]`; it returns
+// nil if expr is not a (local) type name.
+func typeExprIdent(expr ast.Expr) *ast.Ident {
+	switch val := expr.(type) {
+	case *ast.Ident:
+		return val
+	case *ast.IndexExpr:
+		return typeExprIdent(val.X)
+	case *ast.IndexListExpr:
+		return typeExprIdent(val.X)
+	}
+	return nil
+}
+
+// findRangedModuleRegistrations returns the identifiers of the module
+// types registered by a call to fnName with argIdent, which should be
+// the value variable of a loop ranging over a slice of modules, i.e.
+// `for _, m := range mods { caddy.RegisterModule(m) }`. This is only
+// best-effort: the slice must be a composite literal, either in the
+// range clause or as the value of a package-level variable, and its
+// elements must be composite literals (or new() calls) themselves. If
+// the types cannot be determined this way, a warning diagnostic is
+// returned instead of identifiers.
+func (ds *Driver) findRangedModuleRegistrations(pkg *packages.Package, fnName string, argIdent *ast.Ident) ([]*ast.Ident, []Diagnostic, error) {
+	argObj := pkg.TypesInfo.Uses[argIdent]
+	if argObj == nil {
+		return nil, nil, nil
+	}
+
+	// find the slice the loop variable ranges over
+	var sliceExpr ast.Expr
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(node ast.Node) bool {
+			rangeStmt, ok := node.(*ast.RangeStmt)
+			if !ok || sliceExpr != nil {
+				return sliceExpr == nil
+			}
+			if valueIdent, ok := rangeStmt.Value.(*ast.Ident); ok && pkg.TypesInfo.Defs[valueIdent] == argObj {
+				sliceExpr = rangeStmt.X
+			}
+			return true
+		})
+	}
+
+	// if it's a variable, find the value the variable is declared with
+	if sliceIdent, ok := sliceExpr.(*ast.Ident); ok {
+		sliceExpr = nil
+		sliceObj := pkg.TypesInfo.Uses[sliceIdent]
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(node ast.Node) bool {
+				valueSpec, ok := node.(*ast.ValueSpec)
+				if !ok {
+					return sliceExpr == nil
+				}
+				for i, name := range valueSpec.Names {
+					if pkg.TypesInfo.Defs[name] == sliceObj && i < len(valueSpec.Values) {
+						sliceExpr = valueSpec.Values[i]
+					}
+				}
+				return false
+			})
+		}
+	}
+
+	compLit, ok := sliceExpr.(*ast.CompositeLit)
+	if !ok {
+		return nil, []Diagnostic{newDiagnostic(pkg.Fset, SeverityWarning, argIdent.Pos(),
+			"unable to determine type(s) of module(s) registered by %s(%s); skipping", fnName, argIdent.Name)}, nil
 	}
 
-	return caddyModuleIdent, nil
+	var idents []*ast.Ident
+	var diags []Diagnostic
+	for _, elt := range compLit.Elts {
+		ident, err := moduleTypeIdent(fnName, elt)
+		if err != nil {
+			diags = append(diags, newDiagnostic(pkg.Fset, SeverityWarning, elt.Pos(),
+				"unable to determine type of module registered by %s(%s): %v; skipping", fnName, argIdent.Name, err))
+			continue
+		}
+		idents = append(idents, ident)
+	}
+	return idents, diags, nil
+}
+
+// isRegistrationFunc returns true if obj is one of the
+// driver's additional module registration functions.
+func (ds *Driver) isRegistrationFunc(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+	for _, regFn := range ds.RegistrationFuncs {
+		if regFn.PackagePath == fn.Pkg().Path() && regFn.Name == fn.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistrationFunc identifies a function which registers a Caddy
+// module in the same way as caddy.RegisterModule, i.e. it takes
+// the module value as its only argument. Plugins sometimes wrap
+// caddy.RegisterModule with their own helper function.
+type RegistrationFunc struct {
+	// The import path of the package defining the function.
+	PackagePath string
+
+	// The name of the function.
+	Name string
+}
+
+// ComputedModuleID identifies a module type whose CaddyModule method
+// computes its ID by appending a name to a static prefix, and the names
+// it is known to be registered with. Each of the resulting IDs is
+// documented as a module of its own.
+type ComputedModuleID struct {
+	// The import path of the package defining the type.
+	PackagePath string
+
+	// The name of the type.
+	TypeName string
+
+	// The static part of the ID, e.g. "caddy.adapters.".
+	Prefix string
+
+	// The names appended to Prefix, e.g. "caddyfile".
+	Names []string
+}
+
+// computedModuleIDs returns the module IDs known for typeName in
+// pkg, if it is configured as a ComputedModuleID and its CaddyModule
+// method returns the ID idExpr; otherwise, it returns nil. If the
+// static prefix of idExpr can be determined, it must match the
+// configured one, or a diagnostic is returned instead.
+func (ds *Driver) computedModuleIDs(pkg *packages.Package, typeName string, idExpr ast.Expr) ([]string, *Diagnostic) {
+	for _, cmi := range ds.ComputedModuleIDs {
+		if cmi.PackagePath != pkg.PkgPath || cmi.TypeName != typeName {
+			continue
+		}
+		if prefix, ok := moduleIDPrefix(pkg, idExpr); ok && prefix != cmi.Prefix {
+			diag := newDiagnostic(pkg.Fset, SeverityWarning, idExpr.Pos(),
+				"CaddyModule() method of %s computes IDs with prefix %q, but %q is configured; skipping",
+				typeName, prefix, cmi.Prefix)
+			return nil, &diag
+		}
+		ids := make([]string, len(cmi.Names))
+		for i, name := range cmi.Names {
+			ids[i] = cmi.Prefix + name
+		}
+		return ids, nil
+	}
+	return nil, nil
+}
+
+// moduleIDPrefix returns the static prefix of expr, a computed module
+// ID, if it is the concatenation of a constant string and some other
+// value, optionally converted to caddy.ModuleID: for example, the
+// prefix of caddy.ModuleID("caddy.adapters." + am.name) is
+// "caddy.adapters.".
+func moduleIDPrefix(pkg *packages.Package, expr ast.Expr) (string, bool) {
+	if pkg.TypesInfo == nil {
+		return "", false
+	}
+	expr = astutil.Unparen(expr)
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		if tv, ok := pkg.TypesInfo.Types[call.Fun]; ok && tv.IsType() && isModuleIDType(tv.Type) {
+			expr = astutil.Unparen(call.Args[0])
+		}
+	}
+	binExpr, ok := expr.(*ast.BinaryExpr)
+	if !ok || binExpr.Op != token.ADD {
+		return "", false
+	}
+	return constantString(pkg, binExpr.X)
 }
 
 // findModuleImpl returns a type identifier if fnDecl implements
@@ -247,26 +805,52 @@ func (ds *Driver) findModuleImpl(fnDecl *ast.FuncDecl) (*ast.Ident, error) {
 
 	// TODO: check return type, make sure it returns a caddy.ModuleInfo
 
-	var receiver *ast.Ident
-	switch val := fnDecl.Recv.List[0].Type.(type) {
-	case *ast.Ident:
-		receiver = val
-	case *ast.StarExpr:
-		receiver = val.X.(*ast.Ident)
-	default:
+	receiver := receiverTypeIdent(fnDecl)
+	if receiver == nil {
 		return nil, fmt.Errorf("expected identifier or pointer for receiver type, but got %#v", fnDecl.Recv.List[0].Type)
 	}
 
 	return receiver, nil
 }
 
+// receiverTypeIdent returns the identifier of the receiver type
+// of fnDecl, or nil if fnDecl is not a method (of a local type).
+func receiverTypeIdent(fnDecl *ast.FuncDecl) *ast.Ident {
+	if fnDecl.Recv == nil || len(fnDecl.Recv.List) != 1 {
+		return nil
+	}
+	// the receiver of a method of a generic type has
+	// type parameters, e.g. `func (g Gizmo[T]) ...`
+	recvType := fnDecl.Recv.List[0].Type
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		recvType = star.X
+	}
+	return typeExprIdent(recvType)
+}
+
+// isDocumentedMethod returns true if the godocs of methods
+// named name are collected for modules (see Driver.MethodDocs).
+func (ds *Driver) isDocumentedMethod(name string) bool {
+	for _, methodName := range ds.MethodDocs {
+		if methodName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Value describes a config value. *Technically* it actually describes
 // a type, but since our purpose is documentation, all uses of the
 // type end up becoming values, so from the user's perspective, they
 // are values, even though though they are only plausible/template
 // values derived from types in source code.
 type Value struct {
-	// Indicates the fundamental type of the value.
+	// Indicates the fundamental type of the value. It is
+	// only empty for a reference to a named type (see
+	// SameAs); values whose type is unknown, or whose
+	// type has a custom JSON encoding, are Any. (Any
+	// other value without a type is not well-formed;
+	// see Validate.)
 	Type Type `json:"type,omitempty"`
 
 	// The local name of the type from the source code.
@@ -300,6 +884,117 @@ type Value struct {
 	// with its struct, this is the name of the key with
 	// which the module name is specified.
 	ModuleInlineKey *string `json:"module_inline_key,omitempty"`
+
+	// If the Go type of this value is different from how it
+	// appears in JSON, this is the Go type. For example, map
+	// keys are always strings in JSON, even if the map's key
+	// type in Go is an int.
+	GoType Type `json:"go_type,omitempty"`
+
+	// If this value is of a string type for which constants
+	// are declared (i.e. it is probably an enum), these are
+	// the values of those constants, which are likely the
+	// allowed values.
+	EnumValues []string `json:"enum_values,omitempty"`
+
+	// The version since which this type exists, if its
+	// godoc says so with a since marker (see
+	// Driver.SinceMarker), which is removed from Doc.
+	Since string `json:"since,omitempty"`
+
+	// Whether this representation is incomplete, because
+	// it was built only from source code, without type
+	// information (because the package has type errors);
+	// the types of its struct fields are not known.
+	Partial bool `json:"partial,omitempty"`
+
+	// If this value is fulfilled by Caddy modules (or is a map
+	// of them), these are the IDs of the stored modules in its
+	// namespace, i.e. the modules that could go here. This is
+	// only filled in when the value is dereferenced deeply,
+	// and only if Driver.IncludeModuleCandidates is enabled.
+	Candidates []string `json:"candidates,omitempty"`
+
+	// If this value is fulfilled by Caddy modules (or is a map
+	// of them), an example of its JSON shape, showing where the
+	// module name goes (see Value.moduleExample). This is only
+	// filled in when the value is dereferenced deeply.
+	ModuleExample string `json:"module_example,omitempty"`
+}
+
+// Clone returns a deep copy of v, so that the copy
+// can be changed without affecting v, or vice-versa.
+func (v *Value) Clone() *Value {
+	if v == nil {
+		return nil
+	}
+	clone := *v
+	if v.StructFields != nil {
+		clone.StructFields = make([]*StructField, len(v.StructFields))
+		for i, sf := range v.StructFields {
+			sfClone := *sf
+			sfClone.Value = sf.Value.Clone()
+			if sf.CaddyTags != nil {
+				sfClone.CaddyTags = make(map[string]string, len(sf.CaddyTags))
+				for key, val := range sf.CaddyTags {
+					sfClone.CaddyTags[key] = val
+				}
+			}
+			clone.StructFields[i] = &sfClone
+		}
+	}
+	clone.MapKeys = v.MapKeys.Clone()
+	clone.Elems = v.Elems.Clone()
+	clone.ModuleNamespace = cloneStringPtr(v.ModuleNamespace)
+	clone.ModuleInlineKey = cloneStringPtr(v.ModuleInlineKey)
+	if v.EnumValues != nil {
+		clone.EnumValues = append([]string(nil), v.EnumValues...)
+	}
+	if v.Candidates != nil {
+		clone.Candidates = append([]string(nil), v.Candidates...)
+	}
+	return &clone
+}
+
+// cloneStringPtr returns a pointer to a copy of *s,
+// or nil if s is nil.
+func cloneStringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	sCopy := *s
+	return &sCopy
+}
+
+// moduleExample returns an example of the JSON shape of v, if v is
+// fulfilled by modules, showing where the module name goes relative
+// to the module's own fields; otherwise it returns empty string. For
+// a module with an inline key, the name is a value under that key,
+// alongside the module's fields:
+//
+//	{"handler": "<module name>", ...}
+//
+// and for a module map, the names are the keys of the map:
+//
+//	{"<module name>": {...}}
+//
+// Modules without an inline key are configured with only their own
+// fields; their name is given by the context, so there is no example.
+func (v *Value) moduleExample() string {
+	var inline string
+	if v.ModuleInlineKey != nil {
+		inline = fmt.Sprintf("%q: \"<module name>\", ", *v.ModuleInlineKey)
+	}
+	switch v.Type {
+	case Module:
+		if inline == "" {
+			return ""
+		}
+		return "{" + inline + "...}"
+	case ModuleMap:
+		return `{"<module name>": {` + inline + `...}}`
+	}
+	return ""
 }
 
 // StructField contains information about a struct field.
@@ -307,6 +1002,27 @@ type StructField struct {
 	Key   string `json:"key"`
 	Value *Value `json:"value"`
 	Doc   string `json:"doc,omitempty"`
+
+	// The field's type as declared in Go, with package paths
+	// (e.g. "[]github.com/caddyserver/caddy/v2/modules/caddyhttp.MatcherSet"
+	// or "string"), which is known even if Value has no type name.
+	TypeName string `json:"type_name,omitempty"`
+
+	// The version since which this field exists, if its
+	// godoc says so with a since marker (see
+	// Driver.SinceMarker), which is removed from Doc.
+	Since string `json:"since,omitempty"`
+
+	// Whether the field is unexported, and thus does not appear
+	// in JSON; its Key is then its Go name. Unexported fields
+	// are only documented if Driver.IncludeUnexportedFields is
+	// enabled.
+	Unexported bool `json:"unexported,omitempty"`
+
+	// All the key-value pairs of the field's "caddy:" struct
+	// tag, including namespace and inline_key (which are also
+	// applied to Value) as well as any other hints it carries.
+	CaddyTags map[string]string `json:"caddy_tags,omitempty"`
 }
 
 // Type represents a funamdental type. Recognized
@@ -338,6 +1054,10 @@ const (
 	Array  Type = "array"
 	Map    Type = "map"
 
+	// Any JSON value, e.g. a field of type interface{} or any,
+	// or a value whose type could not be determined
+	Any Type = "any"
+
 	// Caddy-specific types
 	Module    Type = "module"
 	ModuleMap Type = "module_map"
@@ -0,0 +1,279 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValueJSONRoundTrip(t *testing.T) {
+	ns, inlineKey, empty := "http.handlers", "handler", ""
+
+	for i, tc := range []struct {
+		val    *Value
+		expect string
+	}{
+		{val: &Value{Type: Bool}, expect: `{"type":"bool"}`},
+		{val: &Value{Type: Int}, expect: `{"type":"int"}`},
+		{val: &Value{Type: Uint}, expect: `{"type":"uint"}`},
+		{val: &Value{Type: Float}, expect: `{"type":"float"}`},
+		{val: &Value{Type: Complex}, expect: `{"type":"complex"}`},
+		{val: &Value{Type: String}, expect: `{"type":"string"}`},
+		{val: &Value{Type: Any}, expect: `{"type":"any"}`},
+		{val: &Value{Type: Module}, expect: `{"type":"module"}`},
+		{val: &Value{Type: ModuleMap}, expect: `{"type":"module_map"}`},
+		{
+			val:    &Value{Type: Struct, StructFields: []*StructField{{Key: "a", Value: &Value{Type: String}}}},
+			expect: `{"type":"struct","struct_fields":[{"key":"a","value":{"type":"string"}}]}`,
+		},
+		{
+			val:    &Value{Type: Array, Elems: &Value{Type: Int}},
+			expect: `{"type":"array","elems":{"type":"int"}}`,
+		},
+		{
+			val:    &Value{Type: Map, MapKeys: &Value{Type: String, GoType: Int}, Elems: &Value{Type: Bool}},
+			expect: `{"type":"map","map_keys":{"type":"string","go_type":"int"},"elems":{"type":"bool"}}`,
+		},
+
+		// a reference to a named type has no type of its own
+		{val: &Value{SameAs: "example.com/foo.Bar@v1.0.0"}, expect: `{"same_as":"example.com/foo.Bar@v1.0.0"}`},
+		{val: &Value{}, expect: `{}`},
+
+		// module information survives, including an empty
+		// namespace (the root namespace), which is not omitted
+		{
+			val:    &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey, ModuleExample: `{"handler": "<module name>", ...}`},
+			expect: `{"type":"module","module_namespace":"http.handlers","module_inline_key":"handler","module_example":"{\"handler\": \"\u003cmodule name\u003e\", ...}"}`,
+		},
+		{
+			val:    &Value{Type: ModuleMap, ModuleNamespace: &empty, Candidates: []string{"tls", "http"}},
+			expect: `{"type":"module_map","module_namespace":"","candidates":["tls","http"]}`,
+		},
+		{
+			val:    &Value{SameAs: "example.com/foo.Bar", ModuleNamespace: &ns},
+			expect: `{"same_as":"example.com/foo.Bar","module_namespace":"http.handlers"}`,
+		},
+	} {
+		data, err := json.Marshal(tc.val)
+		if err != nil {
+			t.Errorf("Test %d: marshaling: %v", i, err)
+			continue
+		}
+		if string(data) != tc.expect {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expect, data)
+		}
+
+		var actual *Value
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Errorf("Test %d: unmarshaling: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.val) {
+			t.Errorf("Test %d: expected %+v after round trip, got %+v", i, tc.val, actual)
+		}
+		if actual.Fingerprint() != tc.val.Fingerprint() {
+			t.Errorf("Test %d: fingerprint changed in round trip", i)
+		}
+	}
+}
+
+func TestFindCaddyModules(t *testing.T) {
+	wrapperFunc := RegistrationFunc{PackagePath: "example.com/fixture/registry/wrapper", Name: "Register"}
+
+	for i, tc := range []struct {
+		pkg       string
+		configure func(*Driver)
+		expect    []string
+
+		// substrings of the expected diagnostics' messages, in order
+		expectDiags []string
+
+		// the expected registration docs, by module ID
+		expectRegDocs map[string]string
+	}{
+		{
+			pkg: "wrapper",
+			configure: func(d *Driver) {
+				d.RegistrationFuncs = append(d.RegistrationFuncs, wrapperFunc)
+			},
+			expect: []string{"fixture.wrapper.wrapped"},
+		},
+		{
+			// without the wrapper, its body can't be understood,
+			// and the module does not get registered
+			pkg: "wrapper",
+			configure: func(d *Driver) {
+				d.TolerateModuleInconsistencies = true
+			},
+			expectDiags: []string{
+				"unable to determine type(s) of module(s) registered by RegisterModule(mod)",
+				"Wrapped: type has CaddyModule method, but does not get registered",
+			},
+		},
+		{
+			pkg:    "ranged",
+			expect: []string{"fixture.ranged.first", "fixture.ranged.second", "fixture.ranged.third"},
+		},
+		{
+			pkg: "documented",
+			configure: func(d *Driver) {
+				d.IncludeRegistrationDocs = true
+			},
+			expect: []string{"fixture.documented.noted", "fixture.documented.unnoted"},
+			expectRegDocs: map[string]string{
+				"fixture.documented.noted":   "Noted is best used together with other modules.",
+				"fixture.documented.unnoted": "",
+			},
+		},
+		{
+			// registration docs are only included if enabled
+			pkg:    "documented",
+			expect: []string{"fixture.documented.noted", "fixture.documented.unnoted"},
+			expectRegDocs: map[string]string{
+				"fixture.documented.noted":   "",
+				"fixture.documented.unnoted": "",
+			},
+		},
+		{
+			// the elements of a slice that isn't a literal are unknown
+			pkg: "unranged",
+			configure: func(d *Driver) {
+				d.TolerateModuleInconsistencies = true
+			},
+			expectDiags: []string{
+				"unable to determine type(s) of module(s) registered by RegisterModule(mod)",
+				"Hidden: type has CaddyModule method, but does not get registered",
+			},
+		},
+		{
+			// a bare return of a named result that is assigned a
+			// literal is understood; otherwise the module is skipped
+			pkg:    "named",
+			expect: []string{"fixture.named.assigned"},
+			expectDiags: []string{
+				"CaddyModule() method of Unassigned has a bare return, but its named result is not assigned a composite literal; skipping",
+			},
+		},
+		{
+			// a mismatched type is still registered, but noted
+			pkg:    "constructors",
+			expect: []string{"fixture.constructors.declared", "fixture.constructors.mismatched"},
+			expectDiags: []string{
+				"CaddyModule() method of Mismatched returns ModuleInfo for fixture.constructors.mismatched, but its New function constructs a example.com/fixture/registry/constructors.Declared",
+			},
+		},
+		{
+			// constant and constantly initialized IDs are resolved
+			pkg:    "ids",
+			expect: []string{"fixture.ids.constant", "fixture.ids.variable"},
+			expectDiags: []string{
+				"CaddyModule() method of Computed returns ModuleInfo with unsupported ID value (must be a static value); skipping: computedID",
+			},
+		},
+		{
+			// a module type may be an instance of a generic type
+			pkg:    "generic",
+			expect: []string{"fixture.generic.wrapper"},
+		},
+		{
+			// computed IDs are enumerated from the configured names
+			pkg: "computed",
+			configure: func(d *Driver) {
+				d.ComputedModuleIDs = []ComputedModuleID{{
+					PackagePath: "example.com/fixture/registry/computed",
+					TypeName:    "Adapter",
+					Prefix:      "fixture.adapters.",
+					Names:       []string{"json", "caddyfile"},
+				}}
+			},
+			expect: []string{"fixture.adapters.caddyfile", "fixture.adapters.json"},
+		},
+		{
+			// but only if the prefix matches the computation
+			pkg: "computed",
+			configure: func(d *Driver) {
+				d.ComputedModuleIDs = []ComputedModuleID{{
+					PackagePath: "example.com/fixture/registry/computed",
+					TypeName:    "Adapter",
+					Prefix:      "caddy.adapters.",
+					Names:       []string{"json"},
+				}}
+			},
+			expectDiags: []string{
+				`CaddyModule() method of Adapter computes IDs with prefix "fixture.adapters.", but "caddy.adapters." is configured; skipping`,
+			},
+		},
+		{
+			// a registered type must implement caddy.Module,
+			// not just have a method named CaddyModule
+			pkg: "impostor",
+			configure: func(d *Driver) {
+				d.TolerateModuleInconsistencies = true
+			},
+			expect: []string{"fixture.impostor.genuine"},
+			expectDiags: []string{
+				"Impostor: registered type example.com/fixture/registry/impostor.Impostor does not implement caddy.Module: method CaddyModule has the wrong signature",
+			},
+		},
+	} {
+		d, _ := newTestDriver(t)
+		if tc.configure != nil {
+			tc.configure(d)
+		}
+		mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/"+tc.pkg, "")
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.pkg, err)
+			continue
+		}
+		var actual []string
+		for _, mod := range mods {
+			actual = append(actual, mod.Name)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected modules %v, got %v", i, tc.pkg, tc.expect, actual)
+		}
+		for _, mod := range mods {
+			if expect, ok := tc.expectRegDocs[mod.Name]; ok && mod.RegistrationDoc != expect {
+				t.Errorf("Test %d (%s): expected registration doc of %s to be %q, got %q", i, tc.pkg, mod.Name, expect, mod.RegistrationDoc)
+			}
+		}
+
+		diags := d.TakeDiagnostics()
+		if len(diags) != len(tc.expectDiags) {
+			t.Errorf("Test %d (%s): expected %d diagnostics, got %d: %+v", i, tc.pkg, len(tc.expectDiags), len(diags), diags)
+			continue
+		}
+		for j, diag := range diags {
+			if !strings.Contains(diag.Message, tc.expectDiags[j]) {
+				t.Errorf("Test %d (%s): expected diagnostic %d to contain %q, got %q", i, tc.pkg, j, tc.expectDiags[j], diag.Message)
+			}
+		}
+	}
+}
+
+func TestRegisteredNonModule(t *testing.T) {
+	// unless inconsistencies are tolerated, registering
+	// a type that isn't a Caddy module is an error
+	d, _ := newTestDriver(t)
+	_, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/impostor", "")
+	if err == nil || !strings.Contains(err.Error(), "method CaddyModule has the wrong signature") {
+		t.Errorf("expected an error about the CaddyModule method's signature, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@ package moduledoc
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -32,74 +33,412 @@ type Storage interface {
 	// (Caddy module IDs are not necessarily globally unique.)
 	GetTypesByCaddyModuleID(caddyModuleID string) ([]*Value, error)
 
+	// GetModuleIDsByNamespace returns the IDs of all Caddy modules in
+	// the given namespace. The namespace of a module ID is everything
+	// before its last dot, as returned by ParseModuleID; thus module IDs
+	// without a dot are in the root namespace, which is empty string.
+	// Only modules directly in the namespace are returned, not those
+	// in namespaces nested within it.
+	GetModuleIDsByNamespace(namespace string) ([]string, error)
+
 	// StoreType stores a type with the given package path and type name.
+	// If the type is already stored, implementations can compare
+	// Fingerprints to skip writing a representation that is unchanged.
 	StoreType(packagePath, typeName, version string, rep *Value) error
 
 	// SetCaddyModuleName sets the module name for the type with the
-	// given package and type name.
+	// given package and type name. It must be idempotent: setting the
+	// same name again is not an error. But if the type is already
+	// associated with a different module name, the association must
+	// not be changed, and an error wrapping ErrModuleNameConflict
+	// must be returned.
 	SetCaddyModuleName(pkg *packages.Package, typeName, modName string) error
 }
 
+// VersionedStorage is a Storage which associates Caddy module names with
+// a particular version of a type, so that when a plugin is documented at
+// more than one version, the versions of its module are kept apart. It
+// is optional: if the driver's storage does not implement it, module
+// names are associated with types regardless of their version.
+type VersionedStorage interface {
+	Storage
+
+	// SetCaddyModuleNameAt is like SetCaddyModuleName, but for the type
+	// of the given version (which is the version it is stored with; it
+	// may be empty). The same module name may be associated with
+	// different versions of a type, and those associations must be
+	// kept apart.
+	SetCaddyModuleNameAt(pkg *packages.Package, typeName, version, modName string) error
+
+	// GetTypesByCaddyModuleIDAt is like GetTypesByCaddyModuleID, but if
+	// version is not empty, only types of that version are returned.
+	GetTypesByCaddyModuleIDAt(caddyModuleID, version string) ([]*Value, error)
+}
+
+// PackageListingStorage is a Storage which can also list the types
+// stored for a package. It is optional; see Driver.ListTypesForPackage.
+type PackageListingStorage interface {
+	Storage
+
+	// ListTypesForPackage returns all the types stored with the
+	// given package path, of all versions. Each StoredType must
+	// have its ModuleName set if the type is associated with a
+	// Caddy module (see SetCaddyModuleName), since that is the
+	// only way to tell which stored types are modules.
+	ListTypesForPackage(packagePath string) ([]StoredType, error)
+}
+
+// MutableStorage is a Storage which can also enumerate and move stored
+// types. It is optional, and only needed for maintenance operations
+// such as RemapPackagePath.
+type MutableStorage interface {
+	Storage
+
+	// AllTypes returns all stored types, of all versions. As with
+	// PackageListingStorage.ListTypesForPackage, each StoredType must
+	// have its ModuleName set if the type is associated with a Caddy
+	// module.
+	AllTypes() ([]StoredType, error)
+
+	// MoveType moves the type stored with the given package path,
+	// type name, and version so that it is stored with the new
+	// package path instead, along with its Caddy module name (if
+	// any). The stored representation itself is not changed.
+	MoveType(packagePath, typeName, version, newPackagePath string) error
+}
+
+// StoredType is a type representation along with
+// the keys with which it is stored.
+type StoredType struct {
+	PackagePath    string `json:"package_path"`
+	TypeName       string `json:"type_name"`
+	Version        string `json:"version,omitempty"`
+	Representation *Value `json:"representation"`
+
+	// The name of the Caddy module the type is
+	// associated with, if any.
+	ModuleName string `json:"module_name,omitempty"`
+}
+
+// storeType stores rep in the driver's storage, like
+// Storage.StoreType, and reports that it was stored. A
+// representation which is not well-formed is rejected
+// or warned about, depending on the driver's config.
+func (d *Driver) storeType(packagePath, typeName, version string, rep *Value) error {
+	if err := rep.Validate(); err != nil {
+		if d.RejectInvalidRepresentations {
+			return fmt.Errorf("storing type %s.%s@%s: %w", packagePath, typeName, version, err)
+		}
+		d.logger().Printf("[WARNING] storing type %s.%s@%s: %v", packagePath, typeName, version, err)
+	}
+	if err := d.db.StoreType(packagePath, typeName, version, rep); err != nil {
+		return err
+	}
+	d.progress(ProgressEvent{
+		Kind:        ProgressTypeStored,
+		PackagePath: packagePath,
+		TypeName:    typeName,
+		Version:     version,
+	})
+	return nil
+}
+
+// setCaddyModuleName associates the type with the given name, package,
+// and version with the Caddy module name in the driver's storage. The
+// version is only kept if the storage is a VersionedStorage.
+func (d *Driver) setCaddyModuleName(pkg *packages.Package, typeName, version, modName string) error {
+	if vs, ok := d.db.(VersionedStorage); ok {
+		return vs.SetCaddyModuleNameAt(pkg, typeName, version, modName)
+	}
+	return d.db.SetCaddyModuleName(pkg, typeName, modName)
+}
+
+// getTypesByCaddyModuleID returns the stored types of the Caddy module
+// with the given ID at version, or at any version if version is empty.
+// If the storage is not a VersionedStorage, which cannot tell versions
+// apart, the types of all versions are returned.
+func (d *Driver) getTypesByCaddyModuleID(moduleID, version string) ([]*Value, error) {
+	if vs, ok := d.db.(VersionedStorage); ok {
+		return vs.GetTypesByCaddyModuleIDAt(moduleID, version)
+	}
+	return d.db.GetTypesByCaddyModuleID(moduleID)
+}
+
+// ListTypesForPackage returns all of the types stored for the package at
+// packagePath, of all versions, sorted by type name and then version. The
+// storage must be a PackageListingStorage or, failing that, a
+// MutableStorage, whose types are filtered by package path.
+func (ds *Driver) ListTypesForPackage(packagePath string) ([]StoredType, error) {
+	var storedTypes []StoredType
+	switch db := ds.db.(type) {
+	case PackageListingStorage:
+		var err error
+		storedTypes, err = db.ListTypesForPackage(packagePath)
+		if err != nil {
+			return nil, fmt.Errorf("listing types for package %s: %w", packagePath, err)
+		}
+	case MutableStorage:
+		allTypes, err := db.AllTypes()
+		if err != nil {
+			return nil, fmt.Errorf("listing types for package %s: %w", packagePath, err)
+		}
+		for _, st := range allTypes {
+			if st.PackagePath == packagePath {
+				storedTypes = append(storedTypes, st)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("storage does not support listing types (must implement PackageListingStorage or MutableStorage)")
+	}
+	sort.Slice(storedTypes, func(i, j int) bool {
+		if storedTypes[i].TypeName != storedTypes[j].TypeName {
+			return storedTypes[i].TypeName < storedTypes[j].TypeName
+		}
+		return storedTypes[i].Version < storedTypes[j].Version
+	})
+	return storedTypes, nil
+}
+
+// canListTypes reports whether the driver's storage can list the
+// types stored for a package, with ListTypesForPackage.
+func (ds *Driver) canListTypes() bool {
+	switch ds.db.(type) {
+	case PackageListingStorage, MutableStorage:
+		return true
+	}
+	return false
+}
+
+// RemapPackagePath re-keys all stored types in the package oldPath, or
+// any package within it, so that they are stored under newPath instead,
+// and rewrites all references to those types (in SameAs and TypeName) in
+// all stored types. This is useful when a module's import path changes,
+// for example when its repository moves, so that existing docs keep
+// working. The storage must implement MutableStorage.
+func (ds *Driver) RemapPackagePath(oldPath, newPath string) error {
+	ms, ok := ds.db.(MutableStorage)
+	if !ok {
+		return fmt.Errorf("storage does not support remapping package paths (must implement MutableStorage)")
+	}
+
+	remap := func(pkgPath string) (string, bool) {
+		if pkgPath == oldPath {
+			return newPath, true
+		}
+		if strings.HasPrefix(pkgPath, oldPath+"/") {
+			return newPath + strings.TrimPrefix(pkgPath, oldPath), true
+		}
+		return pkgPath, false
+	}
+	remapTypeName := func(name string) string {
+		fqtn, version := splitVersion(name)
+		pkgPath, typeName := splitTypeName(fqtn)
+		if newPkgPath, ok := remap(pkgPath); ok {
+			if version != "" {
+				return newPkgPath + "." + typeName + "@" + version
+			}
+			return newPkgPath + "." + typeName
+		}
+		return name
+	}
+
+	storedTypes, err := ms.AllTypes()
+	if err != nil {
+		return fmt.Errorf("getting all stored types: %w", err)
+	}
+
+	for _, st := range storedTypes {
+		// rewrite references to remapped types in all types
+		var changed bool
+		walkValue(st.Representation, func(val *Value) {
+			if newName := remapTypeName(val.SameAs); newName != val.SameAs {
+				val.SameAs = newName
+				changed = true
+			}
+			if newName := remapTypeName(val.TypeName); newName != val.TypeName {
+				val.TypeName = newName
+				changed = true
+			}
+		})
+		if changed {
+			err := ms.StoreType(st.PackagePath, st.TypeName, st.Version, st.Representation)
+			if err != nil {
+				return fmt.Errorf("storing type %s.%s@%s: %w", st.PackagePath, st.TypeName, st.Version, err)
+			}
+		}
+
+		// then move the types that are in remapped packages
+		if newPkgPath, ok := remap(st.PackagePath); ok {
+			err := ms.MoveType(st.PackagePath, st.TypeName, st.Version, newPkgPath)
+			if err != nil {
+				return fmt.Errorf("moving type %s.%s@%s to %s: %w", st.PackagePath, st.TypeName, st.Version, newPkgPath, err)
+			}
+		}
+	}
+
+	// the cache is keyed by type name, so it is now stale
+	ds.mu.Lock()
+	ds.discoveredTypes = make(map[string]*Value)
+	ds.mu.Unlock()
+
+	return nil
+}
+
+// walkValue calls fn for val and, recursively, all of its
+// struct fields and map keys/elements. It does not follow
+// SameAs references.
+func walkValue(val *Value, fn func(*Value)) {
+	if val == nil {
+		return
+	}
+	fn(val)
+	for _, sf := range val.StructFields {
+		walkValue(sf.Value, fn)
+	}
+	walkValue(val.MapKeys, fn)
+	walkValue(val.Elems, fn)
+}
+
 // dereference follows val.SameAs and returns the
 // value that is pointed to by val.SameAs. The
 // ModuleNamespace and ModuleInlineKey information is
-// preserved in the returned value. If val.SameAs is
+// preserved in the returned value, which is a copy, so
+// the stored type is not changed. If val.SameAs is
 // empty string, val is returned and this is a no-op.
+// If the stored type is itself only a reference to
+// another type, that reference is followed too, and
+// so on; a chain of references that leads back to
+// itself is an error, since there is no type at the
+// end of it.
 func (ds *Driver) dereference(val *Value) (*Value, error) {
-	// if there is no equivalent type, nothing to dereference
+	val, _, err := ds.dereferenceChain(val)
+	return val, err
+}
+
+// dereferenceChain is like dereference, but it also returns the
+// references that were followed, in order, starting with val.SameAs.
+func (ds *Driver) dereferenceChain(val *Value) (*Value, []string, error) {
 	if val.SameAs == "" {
-		return val, nil
+		return val, nil, nil
 	}
+	chain := []string{val.SameAs}
+	for val.SameAs != "" {
+		// load the referenced type
+		fqtn, version := splitVersion(val.SameAs)
+		typ, err := ds.getTypeByFullName(fqtn, version)
+		if err != nil {
+			return nil, nil, err
+		}
+		if typ == nil {
+			return nil, nil, fmt.Errorf("dereference failed: %w: %s@%s", ErrTypeNotFound, fqtn, version)
+		}
+		typ = typ.Clone()
 
-	// load the referenced type
-	parts := strings.SplitN(val.SameAs, "@", 2)
-	fqtn, version := parts[0], ""
-	if len(parts) == 2 {
-		version = parts[1]
-	}
-	typ, err := ds.getTypeByFullName(fqtn, version)
-	if err != nil {
-		return nil, err
-	}
-	if typ == nil {
-		return nil, fmt.Errorf("dereference failed, type not found: %s@%s", fqtn, version)
-	}
+		// transfer over the module namespace and inline key, since that
+		// information is specific to the context in which the type appears,
+		// thus the normalized stored type will not have that information;
+		// but first we have to dive down through maps and arrays until we
+		// are not at a map or array anymore, so that the information is
+		// in the relevant spot in the structure (since typ is a copy, this
+		// does not affect the stored type or other contexts it is used in;
+		// and if the context does not specify them, keep what the type has,
+		// e.g. for a type configured as a module map with a namespace)
+		moduleElem := moduleValue(typ)
+		if val.ModuleNamespace != nil {
+			moduleElem.ModuleNamespace = val.ModuleNamespace
+		}
+		if val.ModuleInlineKey != nil {
+			moduleElem.ModuleInlineKey = val.ModuleInlineKey
+		}
 
-	// transfer over the module namespace and inline key, since that
-	// information is specific to the context in which the type appears,
-	// thus the normalized stored type will not have that information;
-	// but first we have to dive down through maps and arrays until we
-	// are not at a map or array anymore, so that the information is
-	// in the relevant spot in the structure
-	moduleElem := typ
-	for moduleElem.Elems != nil {
-		moduleElem = moduleElem.Elems
-	}
-	moduleElem.ModuleNamespace = val.ModuleNamespace
-	moduleElem.ModuleInlineKey = val.ModuleInlineKey
+		// it is also useful to combine the type's godoc with the parent's.
+		typ.Doc = joinDocs(val.Doc, typ.Doc)
 
-	// it is also useful to combine the type's godoc with the parent's.
-	if val.Doc != "" {
-		typ.Doc = val.Doc + "\n\n" + typ.Doc
+		if typ.SameAs != "" {
+			for _, sameAs := range chain {
+				if sameAs == typ.SameAs {
+					return nil, nil, fmt.Errorf("dereference failed: %w: cyclic reference %s",
+						ErrInvalidRepresentation, strings.Join(append(chain, typ.SameAs), " -> "))
+				}
+			}
+			chain = append(chain, typ.SameAs)
+		}
+		val = typ
 	}
 
-	return typ, nil
+	return val, chain, nil
+}
+
+// moduleValue returns the value within val that would be the module,
+// if val holds modules: it dives down through the elements of arrays
+// and maps (which may be nested, e.g. a slice of maps of modules) until
+// it reaches a value that is not a container. That is where module
+// information, like namespace and inline key, belongs.
+func moduleValue(val *Value) *Value {
+	for val.Elems != nil {
+		val = val.Elems
+	}
+	return val
 }
 
 // deepDereference calls ds.dereference, but recursively,
 // for val and all struct fields or map/array elems of val.
 // As a result, the returned value information is completely
-// dereferenced and filled out.
+// dereferenced and filled out. The returned value is a copy;
+// val is not changed. A reference to a type that
+// is already being dereferenced further up (i.e. a recursive
+// type) is not followed, and is left as a SameAs reference.
 func (ds *Driver) deepDereference(val *Value) (*Value, error) {
-	var err error
-	val, err = ds.dereference(val)
+	return ds.deepDereferenceAt(val.Clone(), make(map[string]bool), 0)
+}
+
+func (ds *Driver) deepDereferenceAt(val *Value, ancestors map[string]bool, depth int) (*Value, error) {
+	maxDepth := ds.MaxDereferenceDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDereferenceDepth
+	}
+	if depth > maxDepth {
+		return nil, fmt.Errorf("exceeded maximum dereference depth of %d", maxDepth)
+	}
+
+	ref := val
+	val, chain, err := ds.dereferenceChain(val)
 	if err != nil {
 		return nil, err
 	}
 
+	// the types that val refers to, directly or through other
+	// references, are its ancestors, so if it refers to one of
+	// its own ancestors, it is not expanded again
+	for _, sameAs := range chain {
+		if ancestors[sameAs] {
+			return &Value{
+				SameAs:          ref.SameAs,
+				Doc:             joinDocs(ref.Doc, "(Recursive reference to "+sameAs+", which is not expanded again.)"),
+				ModuleNamespace: ref.ModuleNamespace,
+				ModuleInlineKey: ref.ModuleInlineKey,
+			}, nil
+		}
+	}
+	for _, sameAs := range chain {
+		ancestors[sameAs] = true
+		defer delete(ancestors, sameAs)
+	}
+
+	// list the modules that could fill this value, if enabled
+	if ds.IncludeModuleCandidates && (val.Type == Module || val.Type == ModuleMap) && val.ModuleNamespace != nil {
+		candidates, err := ds.db.GetModuleIDsByNamespace(*val.ModuleNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("getting candidate modules in namespace '%s': %w", *val.ModuleNamespace, err)
+		}
+		sort.Strings(candidates)
+		val.Candidates = candidates
+	}
+	val.ModuleExample = val.moduleExample()
+
 	// dereference all struct fields
 	for _, sf := range val.StructFields {
-		sf.Value, err = ds.deepDereference(sf.Value)
+		sf.Value, err = ds.deepDereferenceAt(sf.Value, ancestors, depth+1)
 		if err != nil {
 			return nil, err
 		}
@@ -108,18 +447,17 @@ func (ds *Driver) deepDereference(val *Value) (*Value, error) {
 		// The struct field's godoc can give context and describe how this
 		// particular instance of the type is used, but the type's godoc is
 		// still important because it describes the type's universal usage.
-		// If it's a map or array, get the underlying type that has docs.
+		// If it's an unnamed map or array (possibly nested, like a slice of
+		// maps), dive down to the underlying type that has docs.
 		underlyingTypeVal := sf.Value
-		if sf.Value.Elems != nil {
-			underlyingTypeVal = sf.Value.Elems
+		for underlyingTypeVal.TypeName == "" && underlyingTypeVal.Elems != nil {
+			underlyingTypeVal = underlyingTypeVal.Elems
 		}
 
 		// prepend the struct field doc (it is usually more specific, and
 		// a better introduction to what the value is, than the type's doc)
 		// to the type doc
-		if sf.Doc != "" {
-			underlyingTypeVal.Doc = strings.TrimSpace(sf.Doc + "\n\n" + underlyingTypeVal.Doc)
-		}
+		underlyingTypeVal.Doc = joinDocs(sf.Doc, underlyingTypeVal.Doc)
 
 		if underlyingTypeVal.Doc != "" {
 			sf.Doc = underlyingTypeVal.Doc
@@ -128,7 +466,7 @@ func (ds *Driver) deepDereference(val *Value) (*Value, error) {
 
 	// dereference all map keys
 	if val.MapKeys != nil {
-		val.MapKeys, err = ds.deepDereference(val.MapKeys)
+		val.MapKeys, err = ds.deepDereferenceAt(val.MapKeys, ancestors, depth+1)
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +474,7 @@ func (ds *Driver) deepDereference(val *Value) (*Value, error) {
 
 	// dereference all map or array elements
 	if val.Elems != nil {
-		val.Elems, err = ds.deepDereference(val.Elems)
+		val.Elems, err = ds.deepDereferenceAt(val.Elems, ancestors, depth+1)
 		if err != nil {
 			return nil, err
 		}
@@ -148,6 +486,6 @@ func (ds *Driver) deepDereference(val *Value) (*Value, error) {
 // getTypeByFullName gets the type representation for the given type
 // by its fully-qualified type name and version.
 func (ds *Driver) getTypeByFullName(fqtn, version string) (*Value, error) {
-	pkgName, typeName := SplitLastDot(fqtn)
+	pkgName, typeName := splitTypeName(fqtn)
 	return ds.db.GetTypeByName(pkgName, typeName, version)
 }
@@ -0,0 +1,344 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDeepDereferenceModuleExample(t *testing.T) {
+	d, db := newTestDriver(t)
+
+	// a module map type, referenced by a field that gives it an inline key
+	ns := "fixture.handlers"
+	handlersMap := &Value{
+		Type:            ModuleMap,
+		TypeName:        "example.com/fixture.HandlersMap",
+		MapKeys:         &Value{Type: String},
+		ModuleNamespace: &ns,
+	}
+	if err := db.StoreType("example.com/fixture", "HandlersMap", "", handlersMap); err != nil {
+		t.Fatal(err)
+	}
+
+	inlineKey := "handler"
+	val := &Value{
+		Type:     Struct,
+		TypeName: "example.com/fixture.Server",
+		StructFields: []*StructField{
+			{Key: "inline", Value: &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey}},
+			{Key: "not_inline", Value: &Value{Type: Module, ModuleNamespace: &ns}},
+			{Key: "map", Value: &Value{Type: ModuleMap, MapKeys: &Value{Type: String}, ModuleNamespace: &ns}},
+			{Key: "inline_map", Value: &Value{SameAs: "example.com/fixture.HandlersMap", ModuleInlineKey: &inlineKey}},
+			{Key: "inline_array", Value: &Value{Type: Array, Elems: &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey}}},
+			{Key: "name", Value: &Value{Type: String}},
+		},
+	}
+
+	deref, err := d.deepDereference(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		key    string
+		expect string
+	}{
+		{key: "inline", expect: `{"handler": "<module name>", ...}`},
+		{key: "not_inline", expect: ""},
+		{key: "map", expect: `{"<module name>": {...}}`},
+		{key: "inline_map", expect: `{"<module name>": {"handler": "<module name>", ...}}`},
+		{key: "inline_array", expect: ""},
+		{key: "name", expect: ""},
+	} {
+		actual := deref.StructFields[i].Value.ModuleExample
+		if deref.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got %s", i, tc.key, deref.StructFields[i].Key)
+		}
+		if actual != tc.expect {
+			t.Errorf("Test %d (%s): expected example %s, got %s", i, tc.key, tc.expect, actual)
+		}
+	}
+
+	// the elements of the array are modules with an inline key
+	if expect, actual := `{"handler": "<module name>", ...}`, deref.StructFields[4].Value.Elems.ModuleExample; actual != expect {
+		t.Errorf("expected array element example %s, got %s", expect, actual)
+	}
+
+	// the example is only filled in on the dereferenced copy
+	if actual := val.StructFields[0].Value.ModuleExample; actual != "" {
+		t.Errorf("expected original value to have no example, got %s", actual)
+	}
+}
+
+func TestRemapPackagePath(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/gizmos", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/apps", "App", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.RemapPackagePath("example.com/fixture/gizmos", "example.com/moved/gizmos"); err != nil {
+		t.Fatal(err)
+	}
+
+	if old, _ := db.GetTypeByName("example.com/fixture/gizmos", "Gizmo", ""); old != nil {
+		t.Errorf("expected Gizmo to be moved, but it is still stored at its old path")
+	}
+	gizmo, _ := db.GetTypeByName("example.com/moved/gizmos", "Gizmo", "")
+	if gizmo == nil {
+		t.Fatal("expected Gizmo to be stored at its new path")
+	}
+	if expect := "example.com/moved/gizmos.Gizmo"; gizmo.TypeName != expect {
+		t.Errorf("expected type name %s, got %s", expect, gizmo.TypeName)
+	}
+	if expect, actual := "example.com/moved/gizmos.Part", gizmo.StructFields[1].Value.Elems.SameAs; actual != expect {
+		t.Errorf("expected reference to %s, got %s", expect, actual)
+	}
+
+	// references from other packages are rewritten too
+	app, _ := db.GetTypeByName("example.com/fixture/apps", "App", "")
+	if app == nil {
+		t.Fatal("expected App to stay stored")
+	}
+	if expect, actual := "example.com/moved/gizmos.Gizmo", app.StructFields[0].Value.SameAs; actual != expect {
+		t.Errorf("expected reference to %s, got %s", expect, actual)
+	}
+
+	// and the module name moves with the type
+	moduleTypes, err := db.GetTypesByCaddyModuleID("fixture.gizmos.gizmo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moduleTypes) != 1 || moduleTypes[0] != gizmo {
+		t.Errorf("expected the moved Gizmo as the module's type, got %+v", moduleTypes)
+	}
+}
+
+// mutableOnlyStorage is a MutableStorage which
+// is not a PackageListingStorage.
+type mutableOnlyStorage struct {
+	MutableStorage
+}
+
+func TestListTypesForPackage(t *testing.T) {
+	for i, db := range []Storage{
+		NewMemoryStorage(),
+		mutableOnlyStorage{NewMemoryStorage()},
+	} {
+		d := newTestDriverWithStorage(t, db)
+		for _, version := range []string{"v2.0.0", "v1.0.0"} {
+			for _, typeName := range []string{"Handler", "Config", "Duration"} {
+				rep := &Value{Type: Struct, TypeName: "example.com/foo." + typeName}
+				if err := db.StoreType("example.com/foo", typeName, version, rep); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pkg := &packages.Package{PkgPath: "example.com/foo", Module: &packages.Module{Version: version}}
+			if err := d.setCaddyModuleName(pkg, "Handler", version, "foo.handler"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// neither other packages nor packages within it are listed
+		if err := db.StoreType("example.com/foo/bar", "Config", "v1.0.0", &Value{Type: Struct}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.StoreType("example.com/foobar", "Config", "v1.0.0", &Value{Type: Struct}); err != nil {
+			t.Fatal(err)
+		}
+
+		storedTypes, err := d.ListTypesForPackage("example.com/foo")
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		var actual []string
+		for _, st := range storedTypes {
+			if st.PackagePath != "example.com/foo" || st.Representation == nil {
+				t.Errorf("Test %d: unexpected stored type %+v", i, st)
+			}
+			actual = append(actual, st.TypeName+"@"+st.Version+" "+st.ModuleName)
+		}
+		expect := []string{
+			"Config@v1.0.0 ",
+			"Config@v2.0.0 ",
+			"Duration@v1.0.0 ",
+			"Duration@v2.0.0 ",
+			"Handler@v1.0.0 foo.handler",
+			"Handler@v2.0.0 foo.handler",
+		}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("Test %d: expected %q, got %q", i, expect, actual)
+		}
+	}
+
+	// storage which can't list its types
+	d := newTestDriverWithStorage(t, unversionedStorage{NewMemoryStorage()})
+	if _, err := d.ListTypesForPackage("example.com/foo"); err == nil {
+		t.Error("expected an error listing types of a storage which can't list them, got none")
+	}
+}
+
+func TestDeepDereferenceReferenceChains(t *testing.T) {
+	d, db := newTestDriver(t)
+	for typeName, rep := range map[string]*Value{
+		// a chain of references that ends at an actual type
+		"Alias":  {SameAs: "example.com/foo.Direct", Doc: "Alias is another name."},
+		"Direct": {SameAs: "example.com/foo.Struct"},
+		"Struct": {Type: Struct, TypeName: "example.com/foo.Struct", Doc: "Struct is a struct.", StructFields: []*StructField{
+			{Key: "next", Value: &Value{SameAs: "example.com/foo.Struct"}},
+		}},
+
+		// a struct that refers to itself through another reference
+		"Node": {Type: Struct, TypeName: "example.com/foo.Node", StructFields: []*StructField{
+			{Key: "parent", Value: &Value{SameAs: "example.com/foo.NodeRef"}},
+		}},
+		"NodeRef": {SameAs: "example.com/foo.Node"},
+
+		// and chains that lead back to themselves
+		"Self":  {SameAs: "example.com/foo.Self"},
+		"Ping":  {SameAs: "example.com/foo.Pong"},
+		"Pong":  {SameAs: "example.com/foo.Ping"},
+		"Entry": {SameAs: "example.com/foo.Ping"},
+	} {
+		if err := db.StoreType("example.com/foo", typeName, "", rep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, tc := range []struct {
+		sameAs    string
+		expectErr bool
+	}{
+		{sameAs: "example.com/foo.Alias"},
+		{sameAs: "example.com/foo.Direct"},
+		{sameAs: "example.com/foo.Self", expectErr: true},
+		{sameAs: "example.com/foo.Ping", expectErr: true},
+		{sameAs: "example.com/foo.Entry", expectErr: true},
+	} {
+		val, err := d.deepDereference(&Value{SameAs: tc.sameAs})
+		if tc.expectErr {
+			if !errors.Is(err, ErrInvalidRepresentation) {
+				t.Errorf("Test %d (%s): expected an invalid representation error, got %v", i, tc.sameAs, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.sameAs, err)
+			continue
+		}
+		if val.TypeName != "example.com/foo.Struct" {
+			t.Errorf("Test %d (%s): expected to end at Struct, got %+v", i, tc.sameAs, val)
+		}
+
+		// a struct that refers to itself is not a cycle of references;
+		// it is truncated, with a note, where it recurs
+		if next := val.StructFields[0].Value; next.SameAs != "example.com/foo.Struct" || !strings.Contains(next.Doc, "Recursive reference") {
+			t.Errorf("Test %d (%s): expected a truncated recursive reference, got %+v", i, tc.sameAs, next)
+		}
+	}
+
+	node, err := d.deepDereference(&Value{SameAs: "example.com/foo.Node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent := node.StructFields[0].Value; parent.SameAs != "example.com/foo.NodeRef" || len(parent.StructFields) != 0 {
+		t.Errorf("expected the indirectly recursive reference to be truncated, got %+v", parent)
+	}
+
+	// the docs of the references along the chain are kept
+	val, err := d.deepDereference(&Value{SameAs: "example.com/foo.Alias"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Alias is another name.\n\nStruct is a struct."; val.Doc != expect {
+		t.Errorf("expected doc %q, got %q", expect, val.Doc)
+	}
+}
+
+func TestDeepDereferenceMaxDepth(t *testing.T) {
+	d, db := newTestDriver(t)
+	d.MaxDereferenceDepth = 2
+
+	// each level is a different type, so none of them recurs
+	for i := 0; i < 5; i++ {
+		rep := &Value{Type: Struct, TypeName: fmt.Sprintf("example.com/foo.Level%d", i), StructFields: []*StructField{
+			{Key: "next", Value: &Value{SameAs: fmt.Sprintf("example.com/foo.Level%d", i+1)}},
+		}}
+		if err := db.StoreType("example.com/foo", fmt.Sprintf("Level%d", i), "", rep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.StoreType("example.com/foo", "Level5", "", &Value{Type: String, TypeName: "example.com/foo.Level5"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.deepDereference(&Value{SameAs: "example.com/foo.Level3"}); err != nil {
+		t.Errorf("expected dereferencing within the maximum depth to succeed, got %v", err)
+	}
+	if _, err := d.deepDereference(&Value{SameAs: "example.com/foo.Level0"}); err == nil {
+		t.Error("expected an error when exceeding the maximum depth, got none")
+	}
+}
+
+func TestDeepDereferenceModuleCandidates(t *testing.T) {
+	for i, tc := range []struct {
+		include bool
+		expect  map[string][]string
+	}{
+		{
+			include: true,
+			expect: map[string][]string{
+				"handler":  {"fixture.documented.noted", "fixture.documented.unnoted"},
+				"handlers": {"fixture.documented.noted", "fixture.documented.unnoted"},
+				"unfilled": nil,
+			},
+		},
+		{
+			// candidates are only listed if enabled
+			include: false,
+			expect:  map[string][]string{"handler": nil, "handlers": nil, "unfilled": nil},
+		},
+	} {
+		d, db := newTestDriver(t)
+		d.IncludeModuleCandidates = tc.include
+		if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/registry/documented", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.AddType("example.com/fixture/apps", "Host", ""); err != nil {
+			t.Fatal(err)
+		}
+		host, _ := db.GetTypeByName("example.com/fixture/apps", "Host", "")
+		if host == nil {
+			t.Fatal("Host was not stored")
+		}
+		deref, err := d.deepDereference(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sf := range deref.StructFields {
+			if actual := sf.Value.Candidates; !reflect.DeepEqual(actual, tc.expect[sf.Key]) {
+				t.Errorf("Test %d (%s): expected candidates %v, got %v", i, sf.Key, tc.expect[sf.Key], actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import "fmt"
+
+// Validate checks that v is a well-formed representation, and returns
+// an error wrapping ErrInvalidRepresentation for the first problem it
+// finds, if any: a value that is neither a type nor a reference to one
+// (i.e. Type and SameAs are both empty, as with a value that was never
+// resolved), a value of an unknown type, a struct field that is nil or
+// has no value, or an array or map without elements. The error's path
+// is like a config path, where map keys and array or map elements are
+// denoted by "(keys)" and "(elems)", as with DiffValues. References to
+// other types (SameAs) are not followed. (To validate config against
+// a representation, use Driver.ValidateAtPath instead.)
+func (v *Value) Validate() error {
+	return v.validate("", make(map[*Value]bool))
+}
+
+func (v *Value) validate(path string, visited map[*Value]bool) error {
+	if v == nil {
+		return invalidRepresentation(path, "value is nil")
+	}
+	if visited[v] {
+		return nil
+	}
+	visited[v] = true
+
+	if v.SameAs != "" {
+		return nil
+	}
+	switch v.Type {
+	case "":
+		return invalidRepresentation(path, "value has neither a type nor a reference to one (SameAs)")
+	case Bool, Int, Uint, Float, Complex, String, Any, Module, ModuleMap:
+	case Struct:
+		for i, sf := range v.StructFields {
+			if sf == nil {
+				return invalidRepresentation(path, fmt.Sprintf("struct field %d is nil", i))
+			}
+			if err := sf.Value.validate(joinPath(path, sf.Key), visited); err != nil {
+				return err
+			}
+		}
+	case Array, Map:
+		if v.Elems == nil {
+			return invalidRepresentation(path, fmt.Sprintf("%s has no elements", v.Type))
+		}
+	default:
+		return invalidRepresentation(path, fmt.Sprintf("unknown type %q", v.Type))
+	}
+
+	if v.MapKeys != nil {
+		if err := v.MapKeys.validate(joinPath(path, "(keys)"), visited); err != nil {
+			return err
+		}
+	}
+	if v.Elems != nil {
+		if err := v.Elems.validate(joinPath(path, "(elems)"), visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidRepresentation returns an error wrapping
+// ErrInvalidRepresentation for the problem at path.
+func invalidRepresentation(path, problem string) error {
+	if path == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidRepresentation, problem)
+	}
+	return fmt.Errorf("%w: at %s: %s", ErrInvalidRepresentation, path, problem)
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	ns := "http.handlers"
+	recursive := &Value{Type: Struct}
+	recursive.StructFields = []*StructField{{Key: "next", Value: recursive}}
+
+	for i, tc := range []struct {
+		val           *Value
+		expectErr     bool
+		expectInError string // the path and problem
+	}{
+		{val: &Value{Type: Bool}},
+		{val: &Value{Type: Int}},
+		{val: &Value{Type: Uint}},
+		{val: &Value{Type: Float}},
+		{val: &Value{Type: Complex}},
+		{val: &Value{Type: String}},
+		{val: &Value{Type: Any}},
+		{val: &Value{Type: Any, TypeName: "example.com/foo.Custom", Doc: "(This type has a custom JSON encoding, so its structure is not documented.)"}},
+		{val: &Value{Type: Module, ModuleNamespace: &ns}},
+		{val: &Value{Type: ModuleMap, MapKeys: &Value{Type: String}}},
+		{val: &Value{Type: Struct}},
+		{val: &Value{Type: Struct, StructFields: []*StructField{{Key: "a", Value: &Value{Type: String}}}}},
+		{val: &Value{Type: Array, Elems: &Value{Type: Int}}},
+		{val: &Value{Type: Map, MapKeys: &Value{Type: String}, Elems: &Value{Type: Int}}},
+		{val: &Value{SameAs: "example.com/foo.Bar"}},
+		{val: recursive},
+
+		{val: nil, expectErr: true, expectInError: "nil"},
+		{val: &Value{}, expectErr: true, expectInError: "neither a type nor a reference"},
+		{val: &Value{Doc: "A value that was never resolved."}, expectErr: true, expectInError: "neither a type nor a reference"},
+		{val: &Value{Type: "tuple"}, expectErr: true, expectInError: `unknown type "tuple"`},
+		{val: &Value{Type: Array}, expectErr: true, expectInError: "array has no elements"},
+		{val: &Value{Type: Map, MapKeys: &Value{Type: String}}, expectErr: true, expectInError: "map has no elements"},
+		{
+			val:           &Value{Type: Struct, StructFields: []*StructField{{Key: "a", Value: &Value{Type: String}}, nil}},
+			expectErr:     true,
+			expectInError: "struct field 1 is nil",
+		},
+		{
+			val:           &Value{Type: Struct, StructFields: []*StructField{{Key: "a/b"}}},
+			expectErr:     true,
+			expectInError: `at a\/b: value is nil`,
+		},
+		{
+			val:           &Value{Type: Map, MapKeys: &Value{}, Elems: &Value{Type: Int}},
+			expectErr:     true,
+			expectInError: "at (keys):",
+		},
+		{
+			val: &Value{Type: Array, Elems: &Value{Type: Struct, StructFields: []*StructField{
+				{Key: "handler", Value: &Value{Type: Map, Elems: &Value{Type: "object"}}},
+			}}},
+			expectErr:     true,
+			expectInError: `at (elems)/handler/(elems): unknown type "object"`,
+		},
+	} {
+		err := tc.val.Validate()
+		if !tc.expectErr {
+			if err != nil {
+				t.Errorf("Test %d: expected no error, got: %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("Test %d: expected error, got none", i)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidRepresentation) {
+			t.Errorf("Test %d: expected error to wrap ErrInvalidRepresentation, got: %v", i, err)
+		}
+		if !strings.Contains(err.Error(), tc.expectInError) {
+			t.Errorf("Test %d: expected error to contain '%s', got: %v", i, tc.expectInError, err)
+		}
+	}
+}
+
+func TestRejectInvalidRepresentations(t *testing.T) {
+	// the types the builder produces are valid, including
+	// those with custom JSON encodings
+	d, db := newTestDriver(t)
+	d.RejectInvalidRepresentations = true
+	if _, err := d.AddType("example.com/fixture/encodings", "Config", ""); err != nil {
+		t.Fatalf("expected valid representations, got: %v", err)
+	}
+	custom, _ := db.GetTypeByName("example.com/fixture/encodings", "Custom", "")
+	if custom == nil || custom.Type != Any {
+		t.Errorf("expected Custom to be stored as %s, got %+v", Any, custom)
+	}
+
+	// but an override without a type is not
+	d, db = newTestDriver(t)
+	d.RejectInvalidRepresentations = true
+	d.TypeOverrides = map[string]*Value{
+		"example.com/fixture/encodings.Custom": {Doc: "An override that forgot its type."},
+	}
+	_, err := d.AddType("example.com/fixture/encodings", "Config", "")
+	if !errors.Is(err, ErrInvalidRepresentation) {
+		t.Errorf("expected error wrapping ErrInvalidRepresentation, got: %v", err)
+	}
+	if config, _ := db.GetTypeByName("example.com/fixture/encodings", "Config", ""); config != nil {
+		t.Errorf("expected the invalid Config not to be stored")
+	}
+
+	// unless invalid representations are only warned about
+	d, db = newTestDriver(t)
+	d.TypeOverrides = map[string]*Value{
+		"example.com/fixture/encodings.Custom": {Doc: "An override that forgot its type."},
+	}
+	if _, err := d.AddType("example.com/fixture/encodings", "Config", ""); err != nil {
+		t.Errorf("expected invalid representation to be stored anyway, got: %v", err)
+	}
+	if config, _ := db.GetTypeByName("example.com/fixture/encodings", "Config", ""); config == nil {
+		t.Errorf("expected the invalid Config to be stored")
+	}
+}
@@ -15,15 +15,21 @@
 package moduledoc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/token"
 	"go/types"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
 )
 
 type goListOutput struct {
@@ -61,21 +67,12 @@ type goListOutput struct {
 // getStructFieldGodocs gets the godoc for the struct fields in typ,
 // and keys them by the field name. typ must be a named struct type.
 func (rb representationBuilder) getStructFieldGodocs(typ types.Type) (map[string]string, error) {
-	packagePath, typeName := typePackageAndName(typ)
-
-	typeVersion, err := rb.getDepVersion(typ.(*types.Named))
-	if err != nil {
-		return nil, err
-	}
+	_, typeName := typePackageAndName(typ)
 
-	pkgs, err := rb.ws.getPackages(packagePath, typeVersion)
+	pkg, err := rb.loadTypePackage(typ.(*types.Named))
 	if err != nil {
 		return nil, err
 	}
-	if len(pkgs) != 1 {
-		return nil, fmt.Errorf("expected 1 package, but got %d from pattern '%s'", len(pkgs), packagePath)
-	}
-	pkg := pkgs[0]
 
 	fieldGodocs := make(map[string]string)
 	var found bool
@@ -99,33 +96,135 @@ func (rb representationBuilder) getStructFieldGodocs(typ types.Type) (map[string
 				continue
 			}
 			for _, fieldIdent := range field.Names {
-				fieldGodocs[fieldIdent.Name] = field.Doc.Text()
+				fieldGodocs[fieldIdent.Name] = normalizeDoc(field.Doc.Text())
 			}
 		}
 	}
 	if !found {
-		return nil, fmt.Errorf("did not find struct type %s in %s", typeName, pkg.ID)
+		return nil, fmt.Errorf("%w: did not find struct type %s in %s", ErrTypeNotFound, typeName, pkg.ID)
 	}
 	return fieldGodocs, nil
 }
 
-// getGodocForType returns the godoc for the given type.
-func (rb representationBuilder) getGodocForType(typ types.Type) (string, error) {
-	packagePath, typeName := typePackageAndName(typ)
+// partialRepresentation builds and stores a representation of the struct
+// type named typeName in pkg, at version, from only its syntax, for when
+// the type can't be resolved because the package has type errors. The
+// struct fields are keyed and documented, but their types are unknown
+// (Any, except for their Go type expressions), and the representation is
+// flagged as partial. It returns a reference to the stored type, along
+// with the position of the type's declaration.
+func (rb representationBuilder) partialRepresentation(pkg *packages.Package, typeName, version string) (*Value, token.Pos, error) {
+	for _, f := range pkg.Syntax {
+		obj := f.Scope.Lookup(typeName)
+		if obj == nil {
+			continue
+		}
+		typeSpec, ok := obj.Decl.(*ast.TypeSpec)
+		if !ok {
+			break
+		}
 
-	typeVersion, err := rb.getDepVersion(typ.(*types.Named))
-	if err != nil {
-		return "", err
+		rep := &Value{
+			Type:     Any,
+			TypeName: pkg.PkgPath + "." + typeName,
+			Doc:      normalizeDoc(typeSpec.Doc.Text()),
+			Partial:  true,
+		}
+		if rep.Doc == "" {
+			objPath, _ := astutil.PathEnclosingInterval(f, typeSpec.Pos(), typeSpec.Pos())
+			for _, op := range objPath {
+				if genDecl, ok := op.(*ast.GenDecl); ok {
+					rep.Doc = normalizeDoc(genDecl.Doc.Text())
+				}
+			}
+		}
+		rep.Doc, rep.Since = rb.ws.driver.splitSince(rep.Doc)
+
+		if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+			rep.Type = Struct
+			for _, field := range structType.Fields.List {
+				var tag string
+				if field.Tag != nil {
+					tag, _ = strconv.Unquote(field.Tag.Value)
+				}
+				jsonName, ok := jsonNameFromTag(tag)
+				if !ok || jsonName == "" {
+					continue // can't tell what embedded fields would contribute
+				}
+				for _, name := range field.Names {
+					if !name.IsExported() {
+						continue
+					}
+					rep.StructFields = append(rep.StructFields, &StructField{
+						Key:      jsonName,
+						Value:    &Value{Type: Any},
+						Doc:      normalizeDoc(field.Doc.Text()),
+						TypeName: types.ExprString(field.Type),
+					})
+				}
+			}
+			rb.ws.driver.splitFieldsSince(rep.StructFields)
+		}
+
+		sameAs := pkg.PkgPath + "." + typeName
+		if version != "" {
+			sameAs += "@" + version
+			if rb.ws.driver.VersionedTypeNames {
+				rep.TypeName += "@" + version
+			}
+		}
+		rb.ws.driver.discoveredTypes[sameAs] = rep
+		if err := rb.ws.driver.storeType(pkg.PkgPath, typeName, version, rep); err != nil {
+			return nil, token.NoPos, err
+		}
+		delete(rb.absentTypes, sameAs)
+		return &Value{SameAs: sameAs}, typeSpec.Pos(), nil
 	}
+	return nil, token.NoPos, fmt.Errorf("%w: did not find type declaration of %s in %s", ErrTypeNotFound, typeName, pkg.ID)
+}
 
-	pkgs, err := rb.ws.getPackages(packagePath, typeVersion)
+// unnamedStructFieldGodoc returns the godoc for field, which is a field
+// of an unnamed struct type, by finding the field by its position in the
+// syntax of its package. Since the package declares the type in which the
+// unnamed struct appears, it has already been loaded.
+func (rb representationBuilder) unnamedStructFieldGodoc(field *types.Var) string {
+	if field.Pkg() == nil {
+		return ""
+	}
+	rb.ws.mu.RLock()
+	pkg, ok := rb.ws.parsedPackages[field.Pkg().Path()]
+	rb.ws.mu.RUnlock()
+	if !ok {
+		return ""
+	}
+	for _, f := range pkg.Syntax {
+		if field.Pos() < f.Pos() || field.Pos() >= f.End() {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(f, field.Pos(), field.Pos())
+		for _, node := range path {
+			if astField, ok := node.(*ast.Field); ok {
+				return normalizeDoc(astField.Doc.Text())
+			}
+		}
+		break
+	}
+	return ""
+}
+
+// getGodocForType returns the godoc for the given type. In order of
+// preference, the godoc is: the doc comment of the type's spec; the doc
+// comment of its declaration (which, for a type in a `type (...)` block
+// whose spec is undocumented, is the block's doc comment); the trailing
+// line comment of the spec; or a comment that precedes a lone type
+// declaration but isn't attached to it.
+func (rb representationBuilder) getGodocForType(typ types.Type) (string, error) {
+	packagePath, typeName := typePackageAndName(typ)
+
+	pkg, err := rb.loadTypePackage(typ.(*types.Named))
 	if err != nil {
 		return "", err
 	}
-	if len(pkgs) != 1 {
-		return "", fmt.Errorf("expected 1 package, but got %d from pattern '%s'", len(pkgs), packagePath)
-	}
-	pkg := pkgs[0]
 
 	var foundObj bool
 	for _, f := range pkg.Syntax {
@@ -135,47 +234,166 @@ func (rb representationBuilder) getGodocForType(typ types.Type) (string, error)
 		}
 		foundObj = true
 		objPath, _ := astutil.PathEnclosingInterval(f, obj.Pos(), obj.Pos())
+		var typeSpec *ast.TypeSpec
+		var genDecl *ast.GenDecl
 		for _, op := range objPath {
 			// Types defined within a parenthesized `type (...)` block
 			// and which have their own individual godoc will have
 			// their godoc in a TypeSpec, otherwise the godoc will be
 			// in a GenDecl. TypeSpec will usually be more specific,
 			// since type blocks often have more than one type in them.
-			if typespec, ok := op.(*ast.TypeSpec); ok &&
-				typespec != nil &&
-				typespec.Doc != nil {
-				return typespec.Doc.Text(), nil
+			// (Note that a comment group consisting only of directives,
+			// like //nolint:foo, has empty text.)
+			if typespec, ok := op.(*ast.TypeSpec); ok && typespec != nil {
+				typeSpec = typespec
+				if doc := typespec.Doc.Text(); doc != "" {
+					return doc, nil
+				}
 			}
-			if gendecl, ok := op.(*ast.GenDecl); ok &&
-				gendecl != nil &&
-				gendecl.Doc != nil {
-				return gendecl.Doc.Text(), nil
+			if gendecl, ok := op.(*ast.GenDecl); ok && gendecl != nil {
+				genDecl = gendecl
+				if doc := gendecl.Doc.Text(); doc != "" {
+					return doc, nil
+				}
 			}
 		}
+
+		// no proper godoc, but the type might still be documented in
+		// an unconventional way: with a line comment after the spec...
+		if typeSpec != nil {
+			if doc := typeSpec.Comment.Text(); doc != "" {
+				return doc, nil
+			}
+		}
+
+		// ...or with a comment right before the declaration that isn't
+		// attached to it, e.g. because it is separated by a blank line
+		// or a directive comment like //nolint (but only for a lone type
+		// declaration, not a type block, since the comment could apply
+		// to any of the types in the block)
+		if genDecl != nil && !genDecl.Lparen.IsValid() {
+			return precedingComment(pkg.Fset, f, genDecl), nil
+		}
 		break
 	}
 
 	if !foundObj {
-		return "", fmt.Errorf("did not find type '%s' in '%s' from package path '%s'", typeName, pkg.ID, packagePath)
+		return "", fmt.Errorf("%w: did not find type '%s' in '%s' from package path '%s'", ErrTypeNotFound, typeName, pkg.ID, packagePath)
 	}
 	return "", nil
 }
 
+// precedingComment returns the text of the comment group that precedes
+// decl (or its doc comment, if it has one), separated from it by no more
+// than one blank line, as long as no other declaration is in between.
+func precedingComment(fset *token.FileSet, f *ast.File, decl *ast.GenDecl) string {
+	start := decl.Pos()
+	if decl.Doc != nil {
+		start = decl.Doc.Pos()
+	}
+
+	// the comment must be after the end of the previous declaration
+	prevEnd := f.Name.End()
+	for _, d := range f.Decls {
+		if d.End() <= start && d.End() > prevEnd {
+			prevEnd = d.End()
+		}
+	}
+
+	var preceding *ast.CommentGroup
+	for _, cg := range f.Comments {
+		if cg.End() > start {
+			break
+		}
+		if cg.Pos() > prevEnd {
+			preceding = cg
+		}
+	}
+	if preceding == nil {
+		return ""
+	}
+	if fset.Position(start).Line-fset.Position(preceding.End()).Line > 2 {
+		return ""
+	}
+	return preceding.Text()
+}
+
+// loadTypePackage returns the parsed package (with syntax) in which typ
+// is declared, at exactly the version that is in use by the workspace,
+// i.e. the same version that is used to key the type's representation.
+// Packages that were parsed as dependencies of an earlier load are reused
+// from the workspace cache; otherwise, the package is fetched and loaded
+// on its own, which is needed for types from transitive dependencies that
+// were not part of a previous load's import graph.
+func (rb representationBuilder) loadTypePackage(typ *types.Named) (*packages.Package, error) {
+	packagePath, _ := typePackageAndName(typ)
+
+	typeVersion, err := rb.getDepVersion(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	// (internal packages are usually in the cache, since they are only
+	// reachable through the packages of their own module that import
+	// them; but if not, loading them on their own may well fail)
+	pkgs, err := rb.ws.getPackages(packagePath, typeVersion)
+	if err != nil {
+		if isInternalPackage(packagePath) {
+			return nil, fmt.Errorf("loading internal package %s@%s for godoc (it was not loaded as a dependency of a package in its own module, so it could not be loaded on its own): %w",
+				packagePath, typeVersion, err)
+		}
+		return nil, fmt.Errorf("loading package %s@%s for godoc: %w", packagePath, typeVersion, err)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected 1 package, but got %d from pattern '%s'", len(pkgs), packagePath)
+	}
+	pkg := pkgs[0]
+
+	// make sure we didn't end up with a different version than the one
+	// we resolved from the workspace (this would happen if loading the
+	// package changed the module graph), otherwise the docs would not
+	// correspond to the type we are documenting
+	if pkg.Module != nil && typeVersion != "" && pkg.Module.Version != typeVersion {
+		return nil, fmt.Errorf("loaded package %s at version %s, but type is from version %s",
+			packagePath, pkg.Module.Version, typeVersion)
+	}
+
+	return pkg, nil
+}
+
 type representationBuilder struct {
-	ws           workspace
-	versionCache map[string]string
+	ws workspace
+
+	// the types (keyed like discoveredTypes) which were found
+	// to be absent from storage during this build, so that
+	// storage need not be asked for them again, until stored
+	absentTypes map[string]bool
 }
 
 // buildRepresentation returns a structured representation of
 // the given type, which we can use to put into our database
-// and thus use to render documentation for the type.
+// and thus use to render documentation for the type. If the
+// type has no JSON representation (e.g. funcs and channels),
+// nil is returned with no error.
 func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type) (*Value, error) {
 	var rep *Value
 
+	// an alias is represented like the type it stands for
+	caddyModuleType = unalias(caddyModuleType)
+
 	switch typ := caddyModuleType.(type) {
 	case *types.Interface:
-		return new(Value), nil
+		// an empty interface (interface{} or any) holds arbitrary
+		// JSON; other interfaces can't be decoded from JSON at all
+		if typ.Empty() {
+			return &Value{Type: Any}, nil
+		}
+		return nil, nil
 	case *types.Pointer:
+		// pointers are encoded like their elements; for a named element
+		// this is a fresh SameAs reference, so module information from
+		// a field's caddy tag can be set on it without touching the stored
+		// type, and dereference carries it over to the referenced type
 		return rb.buildRepresentation(typ.Elem())
 
 	case *types.Basic:
@@ -192,6 +410,11 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 			return &Value{Type: Complex}, nil
 		case types.String:
 			return &Value{Type: String}, nil
+		case types.UnsafePointer:
+			// like funcs and channels, unsafe pointers can't be
+			// encoded as JSON; they only show up in unexported
+			// fields (e.g. of sync/atomic.Pointer)
+			return nil, nil
 		default:
 			return nil, fmt.Errorf("unrecognized basic kind: %#v", caddyModuleType)
 		}
@@ -204,6 +427,16 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 			return nil, err
 		}
 
+		// a json.RawMessage type represents a module! (so does a type
+		// defined from it; aliases of it are the same type already)
+		isModule, err := rb.isRawMessage(typ)
+		if err != nil {
+			return nil, err
+		}
+		if isModule {
+			return &Value{Type: Module}, nil
+		}
+
 		// if type has already been seen, return that
 		fqtn := caddyModuleType.String() // all that matters is that this is unique
 		sameAs := fqtn
@@ -214,124 +447,115 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 			return &Value{SameAs: sameAs}, nil
 		}
 
-		// if type has not already been seen but already exists in db, return that
-		packagePath, typeName := typePackageAndName(caddyModuleType)
-		discoveredType, err := rb.ws.driver.db.GetTypeByName(packagePath, typeName, typeVersion)
-		if err != nil {
-			return nil, err
-		}
-		if discoveredType != nil {
-			rb.ws.driver.discoveredTypes[sameAs] = discoveredType
-			return &Value{SameAs: discoveredType.TypeName}, nil
+		// types registered as module maps are represented as such,
+		// regardless of their Go structure (they probably have a
+		// custom JSON encoding)
+		if mmt, ok := rb.ws.driver.ModuleMapTypes[fullyQualifiedTypeName(caddyModuleType)]; ok {
+			return rb.moduleMapRepresentation(typ, mmt)
 		}
 
-		// a json.RawMessage type represents a module!
-		if packagePath == "encoding/json" && typeName == "RawMessage" {
-			return &Value{Type: Module}, nil
+		// likewise for types whose representation is overridden
+		if override := rb.ws.driver.typeOverride(fullyQualifiedTypeName(caddyModuleType)); override != nil {
+			rep := override.Clone()
+			if rep.TypeName == "" {
+				rep.TypeName = fullyQualifiedTypeName(caddyModuleType)
+			}
+			return rep, nil
 		}
 
-		// otherwise, if this type is new, store it in the DB
-		switch utyp := typ.Underlying().(type) {
-		case *types.Struct:
-			rep = &Value{Type: Struct}
+		// types from excluded packages are not worth documenting
+		if pkg := typ.Obj().Pkg(); pkg != nil && rb.ws.driver.isExcludedPackage(pkg.Path()) {
+			return &Value{
+				Type:     Any,
+				TypeName: fullyQualifiedTypeName(caddyModuleType) + typeArgsSuffix(caddyModuleType),
+				Doc:      "(Type from excluded package " + pkg.Path() + ", which is not documented.)",
+			}, nil
+		}
 
-			// load the godoc for the struct fields
-			structFieldDocs, err := rb.getStructFieldGodocs(caddyModuleType)
+		// if type has not already been seen but already exists in db, return that
+		packagePath, typeName := typePackageAndName(caddyModuleType)
+		typeName += typeArgsSuffix(caddyModuleType)
+		if !rb.absentTypes[sameAs] {
+			discoveredType, err := rb.ws.driver.db.GetTypeByName(packagePath, typeName, typeVersion)
 			if err != nil {
 				return nil, err
 			}
-
-			for i := 0; i < utyp.NumFields(); i++ {
-				field := utyp.Field(i)
-
-				if !field.Exported() {
-					continue
-				}
-
-				// JSON field name from tag is required, but if the field
-				// is embedded, it's OK if there isn't a JSON struct tag,
-				// because when embedding a field it is often desirable
-				// that such a field is a JSON-fallthrough
-				jsonName, ok := jsonNameFromTag(utyp.Tag(i))
-				if !ok || (jsonName == "" && !field.Embedded()) {
-					continue
-				}
-
-				fieldRep, err := rb.buildRepresentation(field.Type())
-				if err != nil {
-					return nil, err
-				}
-
-				// get module information from the caddy struct tags
-				ctf, err := caddyTagFields(utyp.Tag(i))
-				if err != nil {
-					return nil, err
-				}
-				modVal := fieldRep
-				if fieldRep.Elems != nil {
-					modVal = fieldRep.Elems
-				}
-				if moduleNamespace, ok := ctf["namespace"]; ok {
-					modVal.ModuleNamespace = &moduleNamespace
-				}
-				if ModuleInlineKey, ok := ctf["inline_key"]; ok {
-					modVal.ModuleInlineKey = &ModuleInlineKey
-				}
-
-				// embedded values act as if their fields were part of this type
-				if field.Embedded() {
-					embedded, err := rb.ws.driver.dereference(fieldRep)
-					if err != nil {
-						return nil, err
-					}
-					if embedded.Type == Struct {
-						rep.StructFields = append(rep.StructFields, embedded.StructFields...)
-					}
-				} else {
-					rep.StructFields = append(rep.StructFields, &StructField{
-						Key:   jsonName,
-						Value: fieldRep,
-						Doc:   structFieldDocs[field.Name()],
-					})
-				}
+			if discoveredType != nil {
+				rb.ws.driver.discoveredTypes[sameAs] = discoveredType
+				return &Value{SameAs: sameAs}, nil
 			}
+			rb.absentTypes[sameAs] = true
+		}
 
-		default:
+		// otherwise, if this type is new, store it in the DB
+		if marshalerRep := marshalerRepresentation(typ); marshalerRep != nil {
+			rep = marshalerRep
+		} else if utyp, ok := typ.Underlying().(*types.Struct); ok {
+			rep, err = rb.buildNamedStructRepresentation(typ, utyp)
+			if err != nil {
+				return nil, err
+			}
+		} else {
 			rep, err = rb.buildRepresentation(typ.Underlying())
 			if err != nil {
 				return nil, err
 			}
+			if rep == nil {
+				return nil, nil // not serializable
+			}
+			if rep.Type == String {
+				rep.EnumValues = enumValues(typ)
+			}
 		}
 
-		fullTypeName := fullyQualifiedTypeName(caddyModuleType)
+		fullTypeName := fullyQualifiedTypeName(caddyModuleType) + typeArgsSuffix(caddyModuleType)
 		typeGodoc, err := rb.getGodocForType(caddyModuleType)
 		if err != nil {
 			return nil, err
 		}
-		rep.Doc = typeGodoc
+		// keep any notes from building the representation
+		rep.Doc, rep.Since = rb.ws.driver.splitSince(joinDocs(typeGodoc, rep.Doc))
 		rep.TypeName = fullTypeName
+		if rb.ws.driver.VersionedTypeNames && typeVersion != "" {
+			rep.TypeName += "@" + typeVersion
+		}
 
 		// remember this type so we don't have to re-assemble it all later
 		rb.ws.driver.discoveredTypes[sameAs] = rep
-		err = rb.ws.driver.db.StoreType(packagePath, typeName, typeVersion, rep)
+		err = rb.ws.driver.storeType(packagePath, typeName, typeVersion, rep)
 		if err != nil {
 			return nil, err
 		}
+		delete(rb.absentTypes, sameAs)
 
 		return &Value{SameAs: sameAs}, nil
 
 	case *types.Struct:
-		// very similar to above case, but this is an inlined, unnamed struct (can't get godoc without a name)
+		// very similar to above case, but this is an inlined, unnamed struct, e.g.
+		// a field's type or the element type of a slice or map; since it has no
+		// name, its field docs are found by position in the enclosing declaration
 		rep := &Value{Type: Struct}
 
 		for i := 0; i < typ.NumFields(); i++ {
+			// like named structs; see buildNamedStructRepresentation
+			_, isStruct := typ.Field(i).Type().Underlying().(*types.Struct)
+			if !typ.Field(i).Exported() && !(typ.Field(i).Embedded() && isStruct) {
+				continue
+			}
 			jsonName, ok := jsonNameFromTag(typ.Tag(i))
-			if !ok {
+			inline := jsonName == "" && (typ.Field(i).Embedded() || inlineFromTag(typ.Tag(i)))
+			if !ok || (jsonName == "" && !inline) {
 				continue
 			}
 			fieldRep, err := rb.buildRepresentation(typ.Field(i).Type())
 			if err != nil {
-				return nil, err
+				fieldRep, err = rb.fieldError(typ.String(), typ.Field(i).Name(), err)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if fieldRep == nil {
+				continue // not serializable
 			}
 
 			// get module information from the caddy struct tags
@@ -339,10 +563,7 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 			if err != nil {
 				return nil, err
 			}
-			modVal := fieldRep
-			if fieldRep.Elems != nil {
-				modVal = fieldRep.Elems
-			}
+			modVal := moduleValue(fieldRep)
 			if moduleNamespace, ok := ctf["namespace"]; ok {
 				modVal.ModuleNamespace = &moduleNamespace
 			}
@@ -350,29 +571,52 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 				modVal.ModuleInlineKey = &ModuleInlineKey
 			}
 
-			// correctly represent embedded fields inline with this parent type
-			if typ.Field(i).Embedded() {
-				if fieldRep.Type == Struct {
-					rep.StructFields = append(rep.StructFields, fieldRep.StructFields...)
+			// correctly represent embedded (or inlined) fields inline with this parent type
+			if inline {
+				embedded, err := rb.ws.driver.dereference(fieldRep)
+				if err != nil {
+					return nil, err
+				}
+				if embedded.Type == Struct {
+					rep.StructFields = append(rep.StructFields, embedded.StructFields...)
+				} else if typ.Field(i).Embedded() && typ.Field(i).Exported() {
+					// like named structs, embedded types that aren't structs
+					// (e.g. an interface that holds arbitrary JSON) are
+					// encoded like regular fields, keyed by their type name
+					rep.StructFields = append(rep.StructFields, &StructField{
+						Key:       typ.Field(i).Name(),
+						Value:     fieldRep,
+						Doc:       rb.unnamedStructFieldGodoc(typ.Field(i)),
+						TypeName:  typ.Field(i).Type().String(),
+						CaddyTags: caddyTags(ctf),
+					})
 				}
 			} else {
 				rep.StructFields = append(rep.StructFields, &StructField{
-					Key:   jsonName,
-					Value: fieldRep,
+					Key:       jsonName,
+					Value:     fieldRep,
+					Doc:       rb.unnamedStructFieldGodoc(typ.Field(i)),
+					TypeName:  typ.Field(i).Type().String(),
+					CaddyTags: caddyTags(ctf),
 				})
 			}
 		}
+		rb.ws.driver.splitFieldsSince(rep.StructFields)
 		return rep, nil
 
-	case *types.Slice:
-		elemRep, err := rb.buildRepresentation(typ.Elem())
+	case *types.Slice, *types.Array:
+		// arrays are encoded like slices
+		elemRep, err := rb.buildRepresentation(typ.(interface{ Elem() types.Type }).Elem())
 		if err != nil {
 			return nil, err
 		}
+		if elemRep == nil {
+			return nil, nil // not serializable
+		}
 		return &Value{Type: Array, Elems: elemRep}, nil
 
 	case *types.Map:
-		keyRep, err := rb.buildRepresentation(typ.Key())
+		keyRep, err := rb.buildMapKeyRepresentation(typ.Key())
 		if err != nil {
 			return nil, err
 		}
@@ -380,12 +624,27 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 		if err != nil {
 			return nil, err
 		}
+		if keyRep == nil || elemRep == nil {
+			return nil, nil // not serializable
+		}
+		// the keys of any map are encoded as JSON strings (see
+		// buildMapKeyRepresentation), whatever their Go type, so
+		// a map of modules is a module map, keyed by module name
 		if keyRep.Type == String && elemRep.Type == Module {
 			return &Value{Type: ModuleMap}, nil
 		}
 		return &Value{Type: Map, MapKeys: keyRep, Elems: elemRep}, nil
 
-	case *types.Interface:
+	case *types.TypeParam:
+		// only in a generic type that is not instantiated, e.g.
+		// one whose CaddyModule method is on the generic type
+		// itself; the type argument could be anything
+		return &Value{Type: Any}, nil
+
+	case *types.Signature, *types.Chan:
+		// functions and channels can't be encoded as JSON, so there is
+		// no value to document; returning nil tells the caller to omit
+		// it, rather than leave a placeholder in the representation
 		return nil, nil
 
 	default:
@@ -393,6 +652,370 @@ func (rb representationBuilder) buildRepresentation(caddyModuleType types.Type)
 	}
 }
 
+// buildNamedStructRepresentation returns the representation of the named
+// type typ, whose underlying type is the struct utyp. The representation
+// does not yet include the type's name or godoc.
+func (rb representationBuilder) buildNamedStructRepresentation(typ *types.Named, utyp *types.Struct) (*Value, error) {
+	rep := &Value{Type: Struct}
+
+	// load the godoc for the struct fields
+	structFieldDocs, err := rb.getStructFieldGodocs(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < utyp.NumFields(); i++ {
+		field := utyp.Field(i)
+
+		// unexported fields are not encoded, except that the exported
+		// fields of an embedded struct are promoted into this one even
+		// if the embedded struct type itself is unexported
+		_, isStruct := field.Type().Underlying().(*types.Struct)
+		if !field.Exported() && !(field.Embedded() && isStruct) {
+			// blank fields (like padding, or the noCopy and [0]T
+			// markers in sync/atomic) are never used for anything
+			if !rb.ws.driver.IncludeUnexportedFields || field.Name() == "_" {
+				continue
+			}
+			fieldRep, err := rb.buildRepresentation(field.Type())
+			if err != nil {
+				fieldRep, err = rb.fieldError(fullyQualifiedTypeName(typ), field.Name(), err)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if fieldRep != nil {
+				rep.StructFields = append(rep.StructFields, &StructField{
+					Key:        field.Name(),
+					Value:      fieldRep,
+					Doc:        structFieldDocs[field.Name()],
+					TypeName:   field.Type().String(),
+					Unexported: true,
+				})
+			}
+			continue
+		}
+
+		// JSON field name from tag is required, but if the field
+		// is embedded, it's OK if there isn't a JSON struct tag,
+		// because when embedding a field it is often desirable
+		// that such a field is a JSON-fallthrough; same if the
+		// field is explicitly inlined with a struct tag option
+		jsonName, ok := jsonNameFromTag(utyp.Tag(i))
+		inline := jsonName == "" && (field.Embedded() || inlineFromTag(utyp.Tag(i)))
+		if !ok || (jsonName == "" && !inline) {
+			continue
+		}
+
+		fieldRep, err := rb.buildRepresentation(field.Type())
+		if err != nil {
+			fieldRep, err = rb.fieldError(fullyQualifiedTypeName(typ), field.Name(), err)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if fieldRep == nil {
+			continue // not serializable
+		}
+
+		// get module information from the caddy struct tags
+		ctf, err := caddyTagFields(utyp.Tag(i))
+		if err != nil {
+			return nil, err
+		}
+		modVal := moduleValue(fieldRep)
+		if moduleNamespace, ok := ctf["namespace"]; ok {
+			modVal.ModuleNamespace = &moduleNamespace
+		}
+		if ModuleInlineKey, ok := ctf["inline_key"]; ok {
+			modVal.ModuleInlineKey = &ModuleInlineKey
+		}
+
+		// embedded (or inlined) values act as if their fields were part of this type
+		if inline {
+			embedded, err := rb.ws.driver.dereference(fieldRep)
+			if err != nil {
+				return nil, err
+			}
+			if embedded.Type == Struct {
+				rep.StructFields = append(rep.StructFields, embedded.StructFields...)
+			} else if field.Embedded() && field.Exported() {
+				// embedded types that aren't structs are encoded
+				// like regular fields, keyed by their type name
+				// (embedded interfaces that can't hold JSON have
+				// no representation, so they were skipped above)
+				rep.StructFields = append(rep.StructFields, &StructField{
+					Key:       field.Name(),
+					Value:     fieldRep,
+					Doc:       structFieldDocs[field.Name()],
+					TypeName:  field.Type().String(),
+					CaddyTags: caddyTags(ctf),
+				})
+			}
+		} else {
+			rep.StructFields = append(rep.StructFields, &StructField{
+				Key:       jsonName,
+				Value:     fieldRep,
+				Doc:       structFieldDocs[field.Name()],
+				TypeName:  field.Type().String(),
+				CaddyTags: caddyTags(ctf),
+			})
+		}
+	}
+	rb.ws.driver.splitFieldsSince(rep.StructFields)
+
+	return rep, nil
+}
+
+// splitSince removes the since marker (see Driver.SinceMarker) from doc,
+// if the driver is configured with one and doc has one, and returns the
+// doc without it, along with the version it specifies.
+func (d *Driver) splitSince(doc string) (string, string) {
+	if d.SinceMarker == nil {
+		return doc, ""
+	}
+	loc := d.SinceMarker.FindStringSubmatchIndex(doc)
+	if len(loc) < 4 || loc[2] < 0 {
+		return doc, ""
+	}
+	return normalizeDoc(doc[:loc[0]] + doc[loc[1]:]), doc[loc[2]:loc[3]]
+}
+
+// splitFieldsSince calls splitSince for the docs of the fields
+// that don't have a since version yet.
+func (d *Driver) splitFieldsSince(fields []*StructField) {
+	for _, sf := range fields {
+		if sf.Since == "" {
+			sf.Doc, sf.Since = d.splitSince(sf.Doc)
+		}
+	}
+}
+
+// fieldError handles err, which is from building the representation of
+// the struct field named fieldName in structType. Normally, err is just
+// returned, as a FieldError, but if the driver tolerates field errors,
+// it is recorded instead, and an empty placeholder value is returned for
+// the field, so that the rest of the struct can still be documented.
+func (rb representationBuilder) fieldError(structType, fieldName string, err error) (*Value, error) {
+	d := rb.ws.driver
+	fieldErr := FieldError{StructType: structType, Field: fieldName, Err: err}
+	if !d.TolerateFieldErrors {
+		return nil, fieldErr
+	}
+	d.logger().Printf("[WARNING] Unable to document struct field: %v", fieldErr)
+	d.mu.Lock()
+	d.fieldErrors = append(d.fieldErrors, fieldErr)
+	d.mu.Unlock()
+	return &Value{Type: Any}, nil
+}
+
+// buildMapKeyRepresentation returns the representation of map keys of
+// type keyType. Since JSON object keys are always strings, the key is
+// always represented as a string, but if its Go type is not a string
+// (e.g. an int, which encoding/json converts to a string), GoType is
+// set accordingly. Named key types are not represented with SameAs,
+// which would lose that information when dereferenced; instead their
+// name and docs are included directly.
+func (rb representationBuilder) buildMapKeyRepresentation(keyType types.Type) (*Value, error) {
+	keyRep, err := rb.buildRepresentation(keyType)
+	if err != nil || keyRep == nil {
+		return keyRep, err
+	}
+	if keyRep.SameAs != "" {
+		named, ok := rb.ws.driver.discoveredTypes[keyRep.SameAs]
+		if !ok {
+			named, err = rb.ws.driver.dereference(keyRep)
+			if err != nil {
+				return nil, fmt.Errorf("map key type: %w", err)
+			}
+		}
+		keyRep = &Value{
+			Type:       named.Type,
+			TypeName:   named.TypeName,
+			Doc:        named.Doc,
+			EnumValues: named.EnumValues,
+		}
+	}
+	if keyRep.Type != String {
+		keyRep.GoType = keyRep.Type
+		keyRep.Type = String
+	}
+	return keyRep, nil
+}
+
+// isRawMessage returns true if typ is json.RawMessage or is a type
+// defined from it, like `type ModuleRaw json.RawMessage`. Other types
+// whose underlying type is []byte are not json.RawMessage, and thus
+// do not represent modules.
+func (rb representationBuilder) isRawMessage(typ *types.Named) (bool, error) {
+	packagePath, typeName := typePackageAndName(typ)
+	if packagePath == "encoding/json" && typeName == "RawMessage" {
+		return true, nil
+	}
+	if !types.Identical(typ.Underlying(), types.NewSlice(types.Typ[types.Byte])) {
+		return false, nil
+	}
+
+	// the type checker does not record which type a defined type
+	// was defined from, so we have to look at its declaration
+	pkg, err := rb.loadTypePackage(typ)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range pkg.Syntax {
+		obj := f.Scope.Lookup(typeName)
+		if obj == nil {
+			continue
+		}
+		typeSpec, ok := obj.Decl.(*ast.TypeSpec)
+		if !ok {
+			return false, nil
+		}
+		from, ok := unalias(pkg.TypesInfo.TypeOf(typeSpec.Type)).(*types.Named)
+		if !ok || from.Obj() == typ.Obj() {
+			return false, nil
+		}
+		return rb.isRawMessage(from)
+	}
+	return false, nil
+}
+
+// enumValues returns the values of the string constants of type typ
+// declared in its package, in the order they are declared. Types like
+// that are usually enums, so these are the allowed values.
+func enumValues(typ *types.Named) []string {
+	pkg := typ.Obj().Pkg()
+	if pkg == nil {
+		return nil
+	}
+	var consts []*types.Const
+	for _, name := range pkg.Scope().Names() {
+		if c, ok := pkg.Scope().Lookup(name).(*types.Const); ok &&
+			types.Identical(c.Type(), typ) &&
+			c.Val().Kind() == constant.String {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	var values []string
+	for _, c := range consts {
+		values = append(values, constant.StringVal(c.Val()))
+	}
+	return values
+}
+
+// marshalerRepresentation returns the representation of typ if it has
+// a custom text or JSON encoding, in which case its structure does not
+// describe how it appears in JSON. A type with a custom JSON encoding
+// could appear as just about anything, so it is represented as Any,
+// with a note. Otherwise, a type that encodes as text appears as a
+// string. (As with encoding/json, the JSON encoding takes precedence.)
+// If typ does not have a custom encoding, nil is returned.
+func marshalerRepresentation(typ *types.Named) *Value {
+	if hasMarshalMethod(typ, "MarshalJSON") {
+		return &Value{Type: Any, Doc: "(This type has a custom JSON encoding, so its structure is not documented.)"}
+	}
+	if hasMarshalMethod(typ, "MarshalText") {
+		return &Value{Type: String}
+	}
+	return nil
+}
+
+// hasMarshalMethod returns true if typ, or a pointer to typ, has a method
+// named methodName with the signature of a marshaling method, i.e. it
+// takes no arguments and returns ([]byte, error), like MarshalJSON of
+// json.Marshaler and MarshalText of encoding.TextMarshaler.
+func hasMarshalMethod(typ *types.Named, methodName string) bool {
+	methodSet := types.NewMethodSet(types.NewPointer(typ))
+	sel := methodSet.Lookup(typ.Obj().Pkg(), methodName)
+	if sel == nil {
+		return false
+	}
+	sig, ok := sel.Type().(*types.Signature)
+	if !ok || sig.Params().Len() != 0 || sig.Results().Len() != 2 {
+		return false
+	}
+	byteSlice, ok := sig.Results().At(0).Type().(*types.Slice)
+	if !ok || !types.Identical(byteSlice.Elem(), types.Typ[types.Byte]) {
+		return false
+	}
+	return types.Identical(sig.Results().At(1).Type(), types.Universe.Lookup("error").Type())
+}
+
+// moduleMapRepresentation returns the representation of typ,
+// which is configured to be treated as a module map by mmt.
+func (rb representationBuilder) moduleMapRepresentation(typ *types.Named, mmt ModuleMapType) (*Value, error) {
+	typeGodoc, err := rb.getGodocForType(typ)
+	if err != nil {
+		return nil, err
+	}
+	rep := &Value{
+		Type:     ModuleMap,
+		TypeName: fullyQualifiedTypeName(typ),
+	}
+	rep.Doc, rep.Since = rb.ws.driver.splitSince(normalizeDoc(typeGodoc))
+	if mmt.Namespace != "" {
+		rep.ModuleNamespace = &mmt.Namespace
+	}
+	if mmt.InlineKey != "" {
+		rep.ModuleInlineKey = &mmt.InlineKey
+	}
+	return rep, nil
+}
+
+// DefaultTypeOverrides are the representations of types from the
+// standard library which are named structs (or otherwise have confusing
+// internal structures) but appear as simple values in JSON, because of
+// their custom encodings. They are used in addition to Driver.TypeOverrides.
+var DefaultTypeOverrides = map[string]*Value{
+	"net.IP":               {Type: String, Doc: "An IP address, e.g. \"192.0.2.1\" or \"2001:db8::1\"."},
+	"net/netip.Addr":       {Type: String, Doc: "An IP address, e.g. \"192.0.2.1\" or \"2001:db8::1\"."},
+	"net/netip.AddrPort":   {Type: String, Doc: "An IP address and port, e.g. \"192.0.2.1:443\" or \"[2001:db8::1]:443\"."},
+	"net/netip.Prefix":     {Type: String, Doc: "An IP network prefix in CIDR notation, e.g. \"192.0.2.0/24\"."},
+	"math/big.Int":         {Type: Int, Doc: "An arbitrary-precision integer."},
+	"math/big.Float":       {Type: String, Doc: "An arbitrary-precision floating-point number, as a string."},
+	"regexp.Regexp":        {Type: String, Doc: "A regular expression (RE2 syntax)."},
+	"time.Time":            {Type: String, Doc: "A timestamp in RFC 3339 format, e.g. \"2006-01-02T15:04:05Z\"."},
+	"encoding/json.Number": {Type: Float, Doc: "A JSON number."},
+}
+
+// typeOverride returns the representation that overrides the
+// representation of the type named fqtn, if any (see
+// Driver.TypeOverrides and DefaultTypeOverrides).
+func (d *Driver) typeOverride(fqtn string) *Value {
+	if override, ok := d.TypeOverrides[fqtn]; ok {
+		return override
+	}
+	return DefaultTypeOverrides[fqtn]
+}
+
+// isExcludedPackage returns true if the package at pkgPath
+// is, or is within, one of the driver's ExcludedPackages.
+func (d *Driver) isExcludedPackage(pkgPath string) bool {
+	for _, excluded := range d.ExcludedPackages {
+		excluded = strings.TrimSuffix(excluded, "/")
+		if pkgPath == excluded || strings.HasPrefix(pkgPath, excluded+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ModuleMapType describes a type that should be documented as a module
+// map, i.e. a JSON object keyed by module name, even though its Go type
+// is not a map. This is typically the case for types with a custom JSON
+// encoding, for example a wrapper struct that marshals the module it
+// holds as {"module_name": {...}}.
+type ModuleMapType struct {
+	// The namespace of the modules in the map, if it is not
+	// specified by the caddy struct tag where the type is used.
+	Namespace string
+
+	// The inline key, if the modules' names are also
+	// specified inline with their config.
+	InlineKey string
+}
+
 func (rb *representationBuilder) getDepVersion(typ *types.Named) (string, error) {
 	fieldTypePackageName, _ := typePackageAndName(typ.Obj().Type())
 	if fieldTypePackageName == "" {
@@ -400,17 +1023,33 @@ func (rb *representationBuilder) getDepVersion(typ *types.Named) (string, error)
 		return "", fmt.Errorf("unable to determine type's package")
 	}
 
-	// see if we already have the version cached (should be same as any parent packages)
+	// the standard library has no module version
+	if isStandardPackage(fieldTypePackageName) {
+		return "", nil
+	}
+
+	rb.ws.mu.Lock()
+	defer rb.ws.mu.Unlock()
+
+	// see if we already have the version cached (should be same as any parent
+	// packages); usually it is, since preloadDepVersions caches them in bulk
 	parts := strings.Split(fieldTypePackageName, "/")
 	for i := len(parts); i > 0; i-- {
 		parent := strings.Join(parts[:i], "/")
-		if parentVersion, ok := rb.versionCache[parent]; ok {
+		if parentVersion, ok := rb.ws.versionCache[parent]; ok {
 			return parentVersion, nil
 		}
 	}
 
+	// the package was probably already loaded as part of an import graph,
+	// in which case we know its module without having to run 'go list'
+	if pkg, ok := rb.ws.parsedPackages[fieldTypePackageName]; ok && pkg.Module != nil {
+		rb.ws.versionCache[pkg.Module.Path] = pkg.Module.Version
+		return pkg.Module.Version, nil
+	}
+
 	// get the version of the module in use for this package in our workspace
-	pkgInfo, err := runGoList(rb.ws.dir, fieldTypePackageName)
+	pkgInfo, err := rb.ws.runGoList(fieldTypePackageName)
 	if err != nil {
 		return "", err
 	}
@@ -421,15 +1060,17 @@ func (rb *representationBuilder) getDepVersion(typ *types.Named) (string, error)
 		// module version will be empty because it's a Go standard library type; oh well
 		pathKey = pkgInfo.ImportPath
 	}
-	rb.versionCache[pathKey] = pkgInfo.Module.Version
+	rb.ws.versionCache[pathKey] = pkgInfo.Module.Version
 
 	return pkgInfo.Module.Version, nil
 }
 
-func runGoList(workspaceDir, pkg string) (goListOutput, error) {
+func (ws workspace) runGoList(pkg string) (goListOutput, error) {
 	pkg = strings.TrimSuffix(pkg, "/...")
-	cmd := exec.Command("go", "list", "-json", pkg)
-	cmd.Dir = workspaceDir
+	args := append([]string{"list", "-json"}, ws.buildFlags()...)
+	cmd := exec.CommandContext(ws.ctx, "go", append(args, pkg)...)
+	cmd.Dir = ws.dir
+	cmd.Env = ws.env()
 	results, err := cmd.Output()
 	if err != nil {
 		if ee, ok := err.(*exec.ExitError); ok {
@@ -442,4 +1083,85 @@ func runGoList(workspaceDir, pkg string) (goListOutput, error) {
 	return pkgInfo, err
 }
 
+// preloadDepVersions caches the versions of the modules of all the packages
+// in the import graph of pkgs, so that getDepVersion need not run 'go list'
+// for them one at a time while representations are built. The versions of
+// packages whose module is known from loading them are cached directly; the
+// rest are resolved with a single 'go list' of all of them.
+func (ws workspace) preloadDepVersions(pkgs []*packages.Package) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var unresolved []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if isStandardPackage(pkg.PkgPath) {
+			return
+		}
+		if pkg.Module != nil {
+			ws.versionCache[pkg.Module.Path] = pkg.Module.Version
+			return
+		}
+		unresolved = append(unresolved, pkg.PkgPath)
+	})
+
+	var stillUnresolved []string
+	for _, pkgPath := range unresolved {
+		if !ws.versionCached(pkgPath) {
+			stillUnresolved = append(stillUnresolved, pkgPath)
+		}
+	}
+	if len(stillUnresolved) == 0 {
+		return nil
+	}
+
+	pkgInfos, err := ws.runGoListMulti(stillUnresolved)
+	if err != nil {
+		return err
+	}
+	for _, pkgInfo := range pkgInfos {
+		if pkgInfo.Module.Path != "" {
+			ws.versionCache[pkgInfo.Module.Path] = pkgInfo.Module.Version
+		}
+	}
+	return nil
+}
+
+// versionCached returns true if the version of the module of the package
+// at pkgPath is in the version cache. The caller must hold a lock on ws.mu.
+func (ws workspace) versionCached(pkgPath string) bool {
+	parts := strings.Split(pkgPath, "/")
+	for i := len(parts); i > 0; i-- {
+		if _, ok := ws.versionCache[strings.Join(parts[:i], "/")]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runGoListMulti is like runGoList, but for many packages at once. Packages
+// that can't be listed are not an error; their info just has no module.
+func (ws workspace) runGoListMulti(pkgs []string) ([]goListOutput, error) {
+	args := append([]string{"list", "-e", "-json"}, ws.buildFlags()...)
+	cmd := exec.CommandContext(ws.ctx, "go", append(args, pkgs...)...)
+	cmd.Dir = ws.dir
+	cmd.Env = ws.env()
+	results, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("exec %v: %v; >>>>>>\n%s\n<<<<<<", cmd.Args, err, ee.Stderr)
+		}
+		return nil, fmt.Errorf("exec %v: %v", cmd.Args, err)
+	}
+	var pkgInfos []goListOutput
+	dec := json.NewDecoder(bytes.NewReader(results))
+	for dec.More() {
+		var pkgInfo goListOutput
+		if err := dec.Decode(&pkgInfo); err != nil {
+			return nil, fmt.Errorf("decoding output of %v: %v", cmd.Args, err)
+		}
+		pkgInfos = append(pkgInfos, pkgInfo)
+	}
+	return pkgInfos, nil
+}
+
 const caddyCorePackagePath = "github.com/caddyserver/caddy/v2"
@@ -0,0 +1,1145 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"go/types"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestIncludeUnexportedFields(t *testing.T) {
+	for i, tc := range []struct {
+		include bool
+		expect  []string
+	}{
+		{include: false, expect: []string{"name"}},
+		{include: true, expect: []string{"name", "count", "parent", "history"}},
+	} {
+		d, db := newTestDriver(t)
+		d.IncludeUnexportedFields = tc.include
+
+		// the unsafe.Pointer fields, both in Counter and in the
+		// sync/atomic.Pointer it contains, have no representation
+		if _, err := d.AddType("example.com/fixture/unexported", "Counter", ""); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		rep, _ := db.GetTypeByName("example.com/fixture/unexported", "Counter", "")
+		if rep == nil {
+			t.Fatalf("Test %d: Counter was not stored", i)
+		}
+		var keys []string
+		for _, sf := range rep.StructFields {
+			keys = append(keys, sf.Key)
+			if sf.Unexported == (sf.Key == "name") {
+				t.Errorf("Test %d: field %s: expected Unexported=%t, got %t", i, sf.Key, !sf.Unexported, sf.Unexported)
+			}
+		}
+		if !reflect.DeepEqual(keys, tc.expect) {
+			t.Errorf("Test %d: expected fields %v, got %v", i, tc.expect, keys)
+		}
+	}
+}
+
+func TestPointerToModuleField(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/apps", "App", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/apps", "App", "")
+	if rep == nil {
+		t.Fatal("App was not stored")
+	}
+	deref, err := d.deepDereference(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		key             string
+		moduleNamespace string
+		moduleInlineKey string
+	}{
+		{key: "main", moduleNamespace: "fixture.gizmos", moduleInlineKey: "kind"},
+		{key: "more", moduleNamespace: "fixture.gizmos"},
+		{key: "plain"},
+	} {
+		sf := deref.StructFields[i]
+		if sf.Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got %s", i, tc.key, sf.Key)
+		}
+
+		// the module information is on the gizmo itself, which
+		// for a slice is the element
+		modVal := moduleValue(sf.Value)
+		if modVal.TypeName != "example.com/fixture/gizmos.Gizmo" || modVal.Type != Struct {
+			t.Errorf("Test %d: expected the Gizmo struct, got %s %s", i, modVal.Type, modVal.TypeName)
+		}
+		if actual := stringPtrValue(modVal.ModuleNamespace); actual != tc.moduleNamespace {
+			t.Errorf("Test %d: expected namespace '%s', got '%s'", i, tc.moduleNamespace, actual)
+		}
+		if actual := stringPtrValue(modVal.ModuleInlineKey); actual != tc.moduleInlineKey {
+			t.Errorf("Test %d: expected inline key '%s', got '%s'", i, tc.moduleInlineKey, actual)
+		}
+	}
+
+	// the module information is specific to the fields,
+	// so the stored type does not have it
+	gizmo, _ := db.GetTypeByName("example.com/fixture/gizmos", "Gizmo", "")
+	if gizmo == nil {
+		t.Fatal("Gizmo was not stored")
+	}
+	if gizmo.ModuleNamespace != nil || gizmo.ModuleInlineKey != nil {
+		t.Errorf("expected stored Gizmo to have no module information, got namespace %v and inline key %v",
+			gizmo.ModuleNamespace, gizmo.ModuleInlineKey)
+	}
+}
+
+func TestAnyFields(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/aliases", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/aliases", "Config", "")
+	if rep == nil {
+		t.Fatal("Config was not stored")
+	}
+	for i, tc := range []struct {
+		key    string
+		expect *Value
+	}{
+		{key: "any", expect: &Value{Type: Any}},
+		{key: "values", expect: &Value{Type: Map, MapKeys: &Value{Type: String}, Elems: &Value{Type: Any}}},
+		{key: "list", expect: &Value{Type: Array, Elems: &Value{Type: Any}}},
+		{key: "empty", expect: &Value{SameAs: "example.com/fixture/aliases.Empty"}},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		if actual := rep.StructFields[i].Value; !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected %+v, got %+v", i, tc.key, tc.expect, actual)
+		}
+	}
+
+	// the interface of our own is stored, as arbitrary JSON
+	empty, _ := db.GetTypeByName("example.com/fixture/aliases", "Empty", "")
+	if empty == nil || empty.Type != Any {
+		t.Errorf("expected Empty to be stored as %s, got %+v", Any, empty)
+	}
+}
+
+// countingStorage is a MemoryStorage which counts the
+// calls to GetTypeByName, by fully-qualified type name.
+type countingStorage struct {
+	*MemoryStorage
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (cs *countingStorage) GetTypeByName(packagePath, name, version string) (*Value, error) {
+	cs.mu.Lock()
+	cs.calls[packagePath+"."+name+"@"+version]++
+	cs.mu.Unlock()
+	return cs.MemoryStorage.GetTypeByName(packagePath, name, version)
+}
+
+func TestGetTypeByNameCalls(t *testing.T) {
+	db := &countingStorage{MemoryStorage: NewMemoryStorage(), calls: make(map[string]int)}
+	const limits = "example.com/dep.Limits@v1.2.0"
+
+	// Limits is stored first, on its own
+	if _, err := newTestDriverWithStorage(t, db).AddType("example.com/dep", "Limits", ""); err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]int{limits: 1}; !reflect.DeepEqual(db.calls, expect) {
+		t.Errorf("expected calls %v, got %v", expect, db.calls)
+	}
+
+	// then Config, which is not stored, and which refers to Limits
+	// thrice, but storage is only asked for each of them once
+	db.calls = make(map[string]int)
+	d := newTestDriverWithStorage(t, db)
+	if _, err := d.AddType("example.com/fixture/versioned", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]int{"example.com/fixture/versioned.Config@": 1, limits: 1}; !reflect.DeepEqual(db.calls, expect) {
+		t.Errorf("expected calls %v, got %v", expect, db.calls)
+	}
+
+	// the type found in storage is referred to by its versioned
+	// name, not by the name in its representation, which has no
+	// version (since VersionedTypeNames is off)
+	rep, _ := db.MemoryStorage.GetTypeByName("example.com/fixture/versioned", "Config", "")
+	if rep == nil {
+		t.Fatal("Config was not stored")
+	}
+	for _, sf := range rep.StructFields {
+		ref := sf.Value
+		if ref.Elems != nil {
+			ref = ref.Elems
+		}
+		if ref.SameAs != limits {
+			t.Errorf("field %s: expected reference to %s, got %s", sf.Key, limits, ref.SameAs)
+		}
+	}
+	if _, err := d.deepDereference(rep); err != nil {
+		t.Errorf("expected references to be resolvable, got %v", err)
+	}
+}
+
+func TestTransitiveDependencyDocs(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/versioned", "Wrapped", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// each type is stored with the version of its own module,
+	// and with the docs from the source of that version
+	for i, tc := range []struct {
+		pkgPath, typeName, version string
+		expectDoc, expectFieldDoc  string
+	}{
+		{
+			pkgPath: "example.com/dep", typeName: "Wrapper", version: "v1.2.0",
+			expectDoc: "Wrapper wraps settings from a dependency of its own.", expectFieldDoc: "The wrapped settings.",
+		},
+		{
+			pkgPath: "example.com/transitive", typeName: "Settings", version: "v0.3.0",
+			expectDoc: "Settings are settings.", expectFieldDoc: "Whether it is verbose.",
+		},
+	} {
+		rep, _ := db.GetTypeByName(tc.pkgPath, tc.typeName, tc.version)
+		if rep == nil {
+			t.Errorf("Test %d: %s.%s@%s was not stored", i, tc.pkgPath, tc.typeName, tc.version)
+			continue
+		}
+		if rep.Doc != tc.expectDoc {
+			t.Errorf("Test %d: expected doc %q, got %q", i, tc.expectDoc, rep.Doc)
+		}
+		if len(rep.StructFields) != 1 || rep.StructFields[0].Doc != tc.expectFieldDoc {
+			t.Errorf("Test %d: expected one field with doc %q, got %+v", i, tc.expectFieldDoc, rep.StructFields)
+		}
+	}
+
+	wrapper, _ := db.GetTypeByName("example.com/dep", "Wrapper", "v1.2.0")
+	if wrapper != nil && wrapper.StructFields[0].Value.SameAs != "example.com/transitive.Settings@v0.3.0" {
+		t.Errorf("expected a reference to the transitive type's version, got %+v", wrapper.StructFields[0].Value)
+	}
+}
+
+func TestVersionedTypeNames(t *testing.T) {
+	for i, tc := range []struct {
+		versioned     bool
+		expectConfig  string
+		expectLimits  string
+		expectFieldTo string
+	}{
+		{
+			versioned:     false,
+			expectConfig:  "example.com/fixture/versioned.Config",
+			expectLimits:  "example.com/dep.Limits",
+			expectFieldTo: "example.com/dep.Limits@v1.2.0",
+		},
+		{
+			// the fixture's own types have no version to include
+			versioned:     true,
+			expectConfig:  "example.com/fixture/versioned.Config",
+			expectLimits:  "example.com/dep.Limits@v1.2.0",
+			expectFieldTo: "example.com/dep.Limits@v1.2.0",
+		},
+	} {
+		d, db := newTestDriver(t)
+		d.VersionedTypeNames = tc.versioned
+		if _, err := d.AddType("example.com/fixture/versioned", "Config", ""); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		config, _ := db.GetTypeByName("example.com/fixture/versioned", "Config", "")
+		limits, _ := db.GetTypeByName("example.com/dep", "Limits", "v1.2.0")
+		if config == nil || limits == nil {
+			t.Fatalf("Test %d: expected both types to be stored, got %v and %v", i, config, limits)
+		}
+		if config.TypeName != tc.expectConfig {
+			t.Errorf("Test %d: expected Config's name %s, got %s", i, tc.expectConfig, config.TypeName)
+		}
+		if limits.TypeName != tc.expectLimits {
+			t.Errorf("Test %d: expected Limits' name %s, got %s", i, tc.expectLimits, limits.TypeName)
+		}
+		if actual := config.StructFields[0].Value.SameAs; actual != tc.expectFieldTo {
+			t.Errorf("Test %d: expected the field to refer to %s, got %s", i, tc.expectFieldTo, actual)
+		}
+	}
+}
+
+func TestUnconventionalDocs(t *testing.T) {
+	d, db := newTestDriver(t)
+	for i, tc := range []struct {
+		typeName string
+		expect   string
+	}{
+		{typeName: "Conventional", expect: "Conventional has a proper godoc."},
+		{typeName: "Trailing", expect: "Trailing is documented with a line comment."},
+		{typeName: "Separated", expect: "Separated is documented with a comment that is\nseparated from the declaration by a blank line."},
+		{typeName: "Directed", expect: "Directed is documented with a comment that is\nseparated from the declaration by a directive."},
+
+		// types in a block have the block's doc, unless they have their own
+		{typeName: "GroupedOne", expect: "These types are documented by their block."},
+		{typeName: "GroupedTwo", expect: "These types are documented by their block."},
+		{typeName: "GroupedOwn", expect: "GroupedOwn has a doc of its own."},
+
+		// comments that may be about other types, or belong
+		// to another declaration, are not the type's docs
+		{typeName: "InBlock", expect: ""},
+		{typeName: "Adjacent", expect: ""},
+	} {
+		if _, err := d.AddType("example.com/fixture/comments", tc.typeName, ""); err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.typeName, err)
+			continue
+		}
+		rep, _ := db.GetTypeByName("example.com/fixture/comments", tc.typeName, "")
+		if rep == nil {
+			t.Errorf("Test %d (%s): type was not stored", i, tc.typeName)
+			continue
+		}
+		if rep.Doc != tc.expect {
+			t.Errorf("Test %d (%s): expected doc %q, got %q", i, tc.typeName, tc.expect, rep.Doc)
+		}
+	}
+}
+
+func TestFlattenedFields(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Flattened", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Flattened", "")
+	if rep == nil {
+		t.Fatal("Flattened was not stored")
+	}
+	fields := make(map[string]*StructField)
+	for _, sf := range rep.StructFields {
+		fields[sf.Key] = sf
+	}
+
+	for i, tc := range []struct {
+		key    string
+		expect *Value
+	}{
+		// the fields of the embedded, inlined, and squashed structs
+		{key: "id", expect: &Value{Type: String}},
+		{key: "info", expect: &Value{Type: String}},
+		{key: "amount", expect: &Value{Type: Int}},
+
+		// but not the regular struct field, which is a reference
+		{key: "nested", expect: &Value{SameAs: "example.com/fixture/gizmos.Nested"}},
+		{key: "depth", expect: nil},
+
+		// nor the embedded struct that is ignored, or that has a name
+		{key: "secret", expect: nil},
+		{key: "named", expect: &Value{SameAs: "example.com/fixture/gizmos.Named"}},
+		{key: "value", expect: nil},
+
+		{key: "name", expect: &Value{Type: String}},
+	} {
+		sf, ok := fields[tc.key]
+		if tc.expect == nil {
+			if ok {
+				t.Errorf("Test %d (%s): expected no such field, got %+v", i, tc.key, sf.Value)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("Test %d (%s): expected field, got fields %v", i, tc.key, rep.StructFields)
+			continue
+		}
+		if !reflect.DeepEqual(sf.Value, tc.expect) {
+			t.Errorf("Test %d (%s): expected %+v, got %+v", i, tc.key, tc.expect, sf.Value)
+		}
+	}
+	if len(rep.StructFields) != 6 {
+		t.Errorf("expected 6 fields, got %d", len(rep.StructFields))
+	}
+}
+
+func TestMarshalerRepresentations(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/encodings", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// types are documented by their wire form, i.e. like
+	// encoding/json encodes them, not by their structure
+	for i, tc := range []struct {
+		typeName string
+		expect   Type
+	}{
+		{typeName: "Custom", expect: Any},
+		{typeName: "Text", expect: String},
+		{typeName: "PointerText", expect: String},
+		{typeName: "Both", expect: Any},
+		{typeName: "Lookalike", expect: Struct},
+	} {
+		rep, _ := db.GetTypeByName("example.com/fixture/encodings", tc.typeName, "")
+		if rep == nil {
+			t.Errorf("Test %d (%s): type was not stored", i, tc.typeName)
+			continue
+		}
+		if rep.Type != tc.expect {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.typeName, tc.expect, rep.Type)
+		}
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Maps", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Maps", "")
+	if rep == nil {
+		t.Fatal("Maps was not stored")
+	}
+
+	// keys are strings in JSON, whatever their Go type
+	for i, tc := range []struct {
+		key    string
+		expect *Value
+	}{
+		{key: "by_int", expect: &Value{Type: String, GoType: Int}},
+		{key: "by_string", expect: &Value{Type: String}},
+		{
+			key: "by_kind",
+			expect: &Value{
+				Type:       String,
+				TypeName:   "example.com/fixture/gizmos.Kind",
+				Doc:        "Kind is a kind of part.",
+				EnumValues: []string{"small", "large"},
+			},
+		},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		val := rep.StructFields[i].Value
+		if val.Type != Map {
+			t.Errorf("Test %d (%s): expected a map, got %s", i, tc.key, val.Type)
+			continue
+		}
+		if !reflect.DeepEqual(val.MapKeys, tc.expect) {
+			t.Errorf("Test %d (%s): expected map keys %+v, got %+v", i, tc.key, tc.expect, val.MapKeys)
+		}
+	}
+}
+
+func TestAnonymousElemStructs(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Anonymous", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Anonymous", "")
+	if rep == nil {
+		t.Fatal("Anonymous was not stored")
+	}
+
+	// the element structs are complete, and the docs of
+	// the containing struct stay on its fields
+	for i, tc := range []struct {
+		key         string
+		expectType  Type
+		expectDoc   string
+		expectElems []string
+	}{
+		{key: "steps", expectType: Array, expectDoc: "The steps, in order.", expectElems: []string{"name"}},
+		{key: "weights", expectType: Map, expectDoc: "The weights, by name.", expectElems: []string{"weight", "parts"}},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		field := rep.StructFields[i]
+		if field.Doc != tc.expectDoc {
+			t.Errorf("Test %d (%s): expected doc %q, got %q", i, tc.key, tc.expectDoc, field.Doc)
+		}
+		if field.Value.Type != tc.expectType || field.Value.Elems == nil {
+			t.Errorf("Test %d (%s): expected %s of structs, got %+v", i, tc.key, tc.expectType, field.Value)
+			continue
+		}
+		elems := field.Value.Elems
+		if elems.Type != Struct || elems.TypeName != "" {
+			t.Errorf("Test %d (%s): expected an anonymous struct, got %s %q", i, tc.key, elems.Type, elems.TypeName)
+		}
+		var keys []string
+		for _, elemField := range elems.StructFields {
+			keys = append(keys, elemField.Key)
+			if elemField.Doc == "" {
+				t.Errorf("Test %d (%s): expected element field %s to have a doc", i, tc.key, elemField.Key)
+			}
+		}
+		if !reflect.DeepEqual(keys, tc.expectElems) {
+			t.Errorf("Test %d (%s): expected element fields %v, got %v", i, tc.key, tc.expectElems, keys)
+		}
+	}
+}
+
+func TestFieldTypeNames(t *testing.T) {
+	d, db := newTestDriver(t)
+
+	// the declared type is recorded, whether or not the
+	// value is a named type that gets dereferenced
+	for i, tc := range []struct {
+		pkg, typeName, key string
+		expect             string
+	}{
+		{pkg: "example.com/fixture/gizmos", typeName: "Gizmo", key: "name", expect: "string"},
+		{pkg: "example.com/fixture/gizmos", typeName: "Gizmo", key: "parts", expect: "[]example.com/fixture/gizmos.Part"},
+		{pkg: "example.com/fixture/gizmos", typeName: "Maps", key: "by_kind", expect: "map[example.com/fixture/gizmos.Kind]example.com/fixture/gizmos.Part"},
+		{pkg: "example.com/fixture/encodings", typeName: "Config", key: "custom", expect: "example.com/fixture/encodings.Custom"},
+		{pkg: "example.com/fixture/encodings", typeName: "Raw", key: "plain", expect: "encoding/json.RawMessage"},
+		{pkg: "example.com/fixture/encodings", typeName: "Raw", key: "aliased", expect: "example.com/fixture/encodings.ModuleAlias"},
+		{pkg: "example.com/fixture/encodings", typeName: "Containers", key: "pointer_to_list", expect: "*[]encoding/json.RawMessage"},
+	} {
+		if _, err := d.AddType(tc.pkg, tc.typeName, ""); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		rep, _ := db.GetTypeByName(tc.pkg, tc.typeName, "")
+		if rep == nil {
+			t.Fatalf("Test %d: %s was not stored", i, tc.typeName)
+		}
+		var field *StructField
+		for _, sf := range rep.StructFields {
+			if sf.Key == tc.key {
+				field = sf
+			}
+		}
+		if field == nil {
+			t.Errorf("Test %d (%s.%s): field not found", i, tc.typeName, tc.key)
+			continue
+		}
+		if field.TypeName != tc.expect {
+			t.Errorf("Test %d (%s.%s): expected declared type %q, got %q", i, tc.typeName, tc.key, tc.expect, field.TypeName)
+		}
+	}
+}
+
+func TestRawMessageModules(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/encodings", "Raw", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/encodings", "Raw", "")
+	if rep == nil {
+		t.Fatal("Raw was not stored")
+	}
+
+	// only actual json.RawMessages (or types defined from
+	// it) are modules, not just any []byte type
+	for i, tc := range []struct {
+		key    string
+		module bool
+	}{
+		{key: "plain", module: true},
+		{key: "aliased", module: true},
+		{key: "named", module: true},
+		{key: "bytes", module: false},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		val, err := d.dereference(rep.StructFields[i].Value)
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.key, err)
+		}
+		if isModule := val.Type == Module; isModule != tc.module {
+			t.Errorf("Test %d (%s): expected module: %t, got %s", i, tc.key, tc.module, val.Type)
+		}
+		if tc.module && (val.ModuleNamespace == nil || *val.ModuleNamespace != "fixture.gizmos") {
+			t.Errorf("Test %d (%s): expected namespace fixture.gizmos, got %v", i, tc.key, val.ModuleNamespace)
+		}
+	}
+}
+
+func TestNestedModuleContainers(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/encodings", "Containers", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/encodings", "Containers", "")
+	if rep == nil {
+		t.Fatal("Containers was not stored")
+	}
+
+	// the module information is on the modules,
+	// however deep in containers they are
+	ns, inlineKey := "fixture.gizmos", "gizmo"
+	for i, tc := range []struct {
+		key    string
+		expect *Value
+	}{
+		{
+			key:    "list_of_maps",
+			expect: &Value{Type: Array, Elems: &Value{Type: ModuleMap, ModuleNamespace: &ns}},
+		},
+		{
+			key: "map_of_lists",
+			expect: &Value{Type: Map, MapKeys: &Value{Type: String},
+				Elems: &Value{Type: Array, Elems: &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey}}},
+		},
+		{
+			key:    "pointer_to_list",
+			expect: &Value{Type: Array, Elems: &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey}},
+		},
+		{
+			key:    "nested",
+			expect: &Value{Type: Array, Elems: &Value{Type: Array, Elems: &Value{Type: ModuleMap, ModuleNamespace: &ns}}},
+		},
+		{
+			// any map whose keys are strings in JSON is a module map
+			key:    "by_named_key",
+			expect: &Value{Type: ModuleMap, ModuleNamespace: &ns},
+		},
+		{
+			key:    "by_number",
+			expect: &Value{Type: ModuleMap, ModuleNamespace: &ns},
+		},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		if actual := rep.StructFields[i].Value; !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected %s, got %s", i, tc.key, dumpString(tc.expect), dumpString(actual))
+		}
+	}
+}
+
+func TestModuleListInTwoNamespaces(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/encodings", "Lists", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/encodings", "Lists", "")
+	if rep == nil {
+		t.Fatal("Lists was not stored")
+	}
+
+	// each field's modules are in its own namespace, however
+	// often and in whichever order the shared type is dereferenced
+	expect := map[string][2]string{
+		"gizmos":  {"fixture.gizmos", "gizmo"},
+		"widgets": {"fixture.aliases", "widget"},
+	}
+	for i, fieldIdx := range []int{0, 1, 0, 1} {
+		field := rep.StructFields[fieldIdx]
+		val, err := d.dereference(field.Value)
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, field.Key, err)
+		}
+		ns, inlineKey := expect[field.Key][0], expect[field.Key][1]
+		expectVal := &Value{Type: Module, ModuleNamespace: &ns, ModuleInlineKey: &inlineKey}
+		if val.Type != Array || !reflect.DeepEqual(val.Elems, expectVal) {
+			t.Errorf("Test %d (%s): expected an array of %s, got %s", i, field.Key, dumpString(expectVal), dumpString(val))
+		}
+	}
+
+	// also when the whole type is dereferenced
+	deref, err := d.deepDereference(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, field := range deref.StructFields {
+		if ns := moduleValue(field.Value).ModuleNamespace; ns == nil || *ns != expect[field.Key][0] {
+			t.Errorf("Test %d (%s): expected namespace %s, got %v", i, field.Key, expect[field.Key][0], ns)
+		}
+	}
+
+	// and the shared type itself has no namespace
+	list, _ := db.GetTypeByName("example.com/fixture/encodings", "ModuleList", "")
+	if list == nil {
+		t.Fatal("ModuleList was not stored")
+	}
+	if elem := moduleValue(list); elem.ModuleNamespace != nil || elem.ModuleInlineKey != nil {
+		t.Errorf("expected the stored ModuleList to have no module information, got %s", dumpString(list))
+	}
+}
+
+func TestSinceMarker(t *testing.T) {
+	for i, tc := range []struct {
+		marker      *regexp.Regexp
+		expectType  [2]string // doc and since version
+		expectField map[string][2]string
+	}{
+		{
+			marker:     DefaultSinceMarker,
+			expectType: [2]string{"Timed has fields that were added over time.", "v2.5"},
+			expectField: map[string][2]string{
+				"original": {"The original field.", ""},
+				"newer":    {"A newer field.\nIt is noted in the middle of its doc.", "v2.6"},
+				"newest":   {"The newest field.", "2.7.0"},
+				"custom":   {"A field with a custom marker. @since v2.8", ""},
+			},
+		},
+		{
+			marker:     regexp.MustCompile(`@since (v[0-9.]+)`),
+			expectType: [2]string{"Timed has fields that were added over time. (since v2.5)", ""},
+			expectField: map[string][2]string{
+				"original": {"The original field.", ""},
+				"newest":   {"The newest field.\n\n(Since: 2.7.0)", ""},
+				"custom":   {"A field with a custom marker.", "v2.8"},
+			},
+		},
+		{
+			// without a marker, docs are left alone
+			expectType: [2]string{"Timed has fields that were added over time. (since v2.5)", ""},
+			expectField: map[string][2]string{
+				"newer": {"A newer field. (since v2.6)\nIt is noted in the middle of its doc.", ""},
+			},
+		},
+	} {
+		d, db := newTestDriver(t)
+		d.SinceMarker = tc.marker
+		if _, err := d.AddType("example.com/fixture/gizmos", "Timed", ""); err != nil {
+			t.Fatal(err)
+		}
+		rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Timed", "")
+		if rep == nil {
+			t.Fatal("Timed was not stored")
+		}
+		if actual := [2]string{rep.Doc, rep.Since}; actual != tc.expectType {
+			t.Errorf("Test %d: expected type doc and since %q, got %q", i, tc.expectType, actual)
+		}
+		for _, sf := range rep.StructFields {
+			expect, ok := tc.expectField[sf.Key]
+			if !ok {
+				continue
+			}
+			if actual := [2]string{sf.Doc, sf.Since}; actual != expect {
+				t.Errorf("Test %d (%s): expected field doc and since %q, got %q", i, sf.Key, expect, actual)
+			}
+		}
+	}
+}
+
+func TestTypeOverrides(t *testing.T) {
+	d, db := newTestDriver(t)
+	d.TypeOverrides = map[string]*Value{
+		"example.com/fixture/gizmos.Part": {Type: String, Doc: "The name of a part."},
+	}
+	if _, err := d.AddType("example.com/fixture/gizmos", "Overridden", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Overridden", "")
+	if rep == nil {
+		t.Fatal("Overridden was not stored")
+	}
+
+	// overridden types are represented as such, by default
+	// or as configured, instead of by their structures
+	for i, tc := range []struct {
+		key        string
+		expectName string
+	}{
+		{key: "address", expectName: "net.IP"},
+		{key: "allow", expectName: "net/netip.Prefix"},
+		{key: "pattern", expectName: "regexp.Regexp"},
+		{key: "start", expectName: "time.Time"},
+		{key: "part", expectName: "example.com/fixture/gizmos.Part"},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		val := moduleValue(rep.StructFields[i].Value)
+		if val.Type != String || val.TypeName != tc.expectName || val.SameAs != "" || len(val.StructFields) > 0 {
+			t.Errorf("Test %d (%s): expected a string of type %s, got %s", i, tc.key, tc.expectName, dumpString(val))
+		}
+	}
+
+	// and their structures are not even looked at
+	for _, st := range db.allTypes() {
+		if st.TypeName != "Overridden" {
+			t.Errorf("expected only Overridden to be stored, but %s.%s is too", st.PackagePath, st.TypeName)
+		}
+	}
+}
+
+func TestCaddyTags(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Hinted", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Hinted", "")
+	if rep == nil {
+		t.Fatal("Hinted was not stored")
+	}
+
+	// all keys of the tags are kept, even those that aren't used
+	for i, tc := range []struct {
+		key    string
+		expect map[string]string
+	}{
+		{key: "timeout", expect: map[string]string{"unit": "seconds", "min": "1", "max": "60"}},
+		{key: "gizmo", expect: map[string]string{"namespace": "fixture.gizmos", "inline_key": "gizmo", "hint": "preferred"}},
+		{key: "plain", expect: nil},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		if actual := rep.StructFields[i].CaddyTags; !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%s): expected caddy tags %v, got %v", i, tc.key, tc.expect, actual)
+		}
+	}
+
+	// and the ones that are used still are
+	gizmo := rep.StructFields[1].Value
+	if gizmo.Type != Module || gizmo.ModuleNamespace == nil || *gizmo.ModuleNamespace != "fixture.gizmos" ||
+		gizmo.ModuleInlineKey == nil || *gizmo.ModuleInlineKey != "gizmo" {
+		t.Errorf("expected a module with namespace and inline key, got %s", dumpString(gizmo))
+	}
+}
+
+func TestEmbeddedInterfaces(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Embedding", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Embedding", "")
+	if rep == nil {
+		t.Fatal("Embedding was not stored")
+	}
+
+	// an embedded interface that holds arbitrary JSON is keyed by its
+	// type name; ones that can't be decoded from JSON are skipped
+	if len(rep.StructFields) != 2 || rep.StructFields[0].Key != "Payload" || rep.StructFields[1].Key != "name" {
+		t.Fatalf("expected fields Payload and name, got %s", dumpString(rep))
+	}
+	if sameAs := rep.StructFields[0].Value.SameAs; sameAs != "example.com/fixture/gizmos.Payload" {
+		t.Errorf("expected Payload to refer to its type, got %q", sameAs)
+	}
+	payload, _ := db.GetTypeByName("example.com/fixture/gizmos", "Payload", "")
+	if payload == nil || payload.Type != Any {
+		t.Errorf("expected Payload to be stored as any, got %s", dumpString(payload))
+	}
+}
+
+func TestDashFields(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Dashes", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Dashes", "")
+	if rep == nil {
+		t.Fatal("Dashes was not stored")
+	}
+
+	// as with encoding/json, "-" ignores a field, but "-," names it "-"
+	if len(rep.StructFields) != 1 || rep.StructFields[0].Key != "-" || rep.StructFields[0].Doc != `A field that is literally named "-".` {
+		t.Errorf("expected only the field named -, got %s", dumpString(rep))
+	}
+}
+
+func TestInternalPackageTypes(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/gizmos", "Limited", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// internal types, of this module and of a dependency,
+	// are documented like any other
+	for i, tc := range []struct {
+		pkg, typeName, version string
+		expectDoc, expectField string
+	}{
+		{pkg: "example.com/fixture/gizmos/internal/limits", typeName: "Limits", expectDoc: "Limits limit a gizmo.", expectField: "max_parts"},
+		{pkg: "example.com/dep/internal/quota", typeName: "Quota", version: "v1.2.0", expectDoc: "Quota is a quota.", expectField: "amount"},
+	} {
+		rep, _ := db.GetTypeByName(tc.pkg, tc.typeName, tc.version)
+		if rep == nil {
+			t.Errorf("Test %d: expected %s.%s@%s to be stored", i, tc.pkg, tc.typeName, tc.version)
+			continue
+		}
+		if rep.Doc != tc.expectDoc {
+			t.Errorf("Test %d (%s): expected doc %q, got %q", i, tc.typeName, tc.expectDoc, rep.Doc)
+		}
+		if len(rep.StructFields) != 1 || rep.StructFields[0].Key != tc.expectField || rep.StructFields[0].Doc == "" {
+			t.Errorf("Test %d (%s): expected documented field %s, got %s", i, tc.typeName, tc.expectField, dumpString(rep))
+		}
+	}
+}
+
+func TestExcludedPackages(t *testing.T) {
+	for i, tc := range []struct {
+		excluded       []string
+		expectExcluded bool
+	}{
+		{excluded: []string{"example.com/dep"}, expectExcluded: true},
+		{excluded: []string{"example.com/dep/governed/"}, expectExcluded: true},
+		{excluded: []string{"example.com/de"}, expectExcluded: false},
+		{excluded: nil, expectExcluded: false},
+	} {
+		d, db := newTestDriver(t)
+		d.ExcludedPackages = tc.excluded
+		if _, err := d.AddType("example.com/fixture/gizmos", "Limited", ""); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		rep, _ := db.GetTypeByName("example.com/fixture/gizmos", "Limited", "")
+		if rep == nil || len(rep.StructFields) != 2 {
+			t.Fatalf("Test %d: expected Limited to be stored with 2 fields, got %v", i, rep)
+		}
+		governed := rep.StructFields[1].Value
+		quota, _ := db.GetTypeByName("example.com/dep/internal/quota", "Quota", "v1.2.0")
+
+		if !tc.expectExcluded {
+			if governed.SameAs != "example.com/dep/governed.Governed@v1.2.0" || quota == nil {
+				t.Errorf("Test %d: expected the type to be documented, got %s", i, dumpString(governed))
+			}
+			continue
+		}
+
+		// an excluded type is a placeholder, and
+		// the types within it aren't documented
+		if governed.Type != Any || governed.TypeName != "example.com/dep/governed.Governed" ||
+			!strings.Contains(governed.Doc, "excluded package example.com/dep/governed") {
+			t.Errorf("Test %d: expected a placeholder, got %s", i, dumpString(governed))
+		}
+		if quota != nil {
+			t.Errorf("Test %d: expected the excluded type's field types not to be stored", i)
+		}
+
+		// which doesn't affect the other packages
+		if limits := rep.StructFields[0].Value; limits.SameAs != "example.com/fixture/gizmos/internal/limits.Limits" {
+			t.Errorf("Test %d: expected limits to be documented, got %s", i, dumpString(limits))
+		}
+	}
+}
+
+func TestStandardLibraryType(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	logFile := fakeGo(t, "")
+	template, _ := newWorkspaceTemplate(t)
+
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.Env = []string{"GOPROXY=off"}
+	d.WorkspaceTemplate = template
+	if _, err := d.AddType("example.com/dep/placement", "Placement", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the standard library's type is documented from the
+	// toolchain's source, without getting it as a module
+	point, _ := db.GetTypeByName("image", "Point", "")
+	if point == nil {
+		t.Fatal("expected image.Point to be stored without a version")
+	}
+	if !strings.HasPrefix(point.Doc, "A Point is an X, Y coordinate pair.") {
+		t.Errorf("expected the godoc of image.Point, got %q", point.Doc)
+	}
+	if point.Type != Struct || point.TypeName != "image.Point" {
+		t.Errorf("expected the struct image.Point, got %s", dumpString(point))
+	}
+	if invocations := fakeGoInvocations(t, logFile, "get "); len(invocations) > 0 {
+		t.Errorf("expected no 'get' invocations, got %v", invocations)
+	}
+}
+
+// dumpString returns the text dump of v (see DumpText).
+func dumpString(v *Value) string {
+	var sb strings.Builder
+	_ = DumpText(&sb, v)
+	return sb.String()
+}
+
+func TestTolerateFieldErrors(t *testing.T) {
+	d, db := newTestDriver(t)
+	d.TolerateFieldErrors = true
+	if _, err := d.AddType("example.com/fixture/broken", "Config", ""); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ := db.GetTypeByName("example.com/fixture/broken", "Config", "")
+	if rep == nil {
+		t.Fatal("Config was not stored")
+	}
+
+	// the broken field is a placeholder, and the rest is documented
+	for i, tc := range []struct {
+		key    string
+		expect Type
+	}{
+		{key: "name", expect: String},
+		{key: "broken", expect: Any},
+	} {
+		if i >= len(rep.StructFields) || rep.StructFields[i].Key != tc.key {
+			t.Fatalf("Test %d: expected field %s, got fields %v", i, tc.key, rep.StructFields)
+		}
+		if actual := rep.StructFields[i].Value.Type; actual != tc.expect {
+			t.Errorf("Test %d (%s): expected type %s, got %s", i, tc.key, tc.expect, actual)
+		}
+	}
+	if doc := rep.StructFields[1].Doc; doc != "A field of a type that doesn't exist." {
+		t.Errorf("expected the broken field to keep its doc, got %q", doc)
+	}
+
+	// and the error is recorded, once
+	fieldErrs := d.TakeFieldErrors()
+	if len(fieldErrs) != 1 {
+		t.Fatalf("expected 1 field error, got %d: %v", len(fieldErrs), fieldErrs)
+	}
+	if fieldErrs[0].StructType != "example.com/fixture/broken.Config" || fieldErrs[0].Field != "Broken" {
+		t.Errorf("expected an error for Config.Broken, got %v", fieldErrs[0])
+	}
+	if fieldErrs := d.TakeFieldErrors(); len(fieldErrs) != 0 {
+		t.Errorf("expected no more field errors, got %v", fieldErrs)
+	}
+}
+
+// BenchmarkDepVersionGoList reports how many times getDepVersion
+// runs 'go list' (see runGoList) to get the module versions of the
+// dependency types of a package: in a cold workspace, and in a warm
+// one, which has loaded the package, so the versions are known.
+// fieldNamedTypes returns the named types of the fields of the
+// struct types in pkg (or of their elements, for containers).
+func fieldNamedTypes(pkg *packages.Package) []*types.Named {
+	var named []*types.Named
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		structType, ok := scope.Lookup(name).Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < structType.NumFields(); i++ {
+			typ := structType.Field(i).Type()
+			for {
+				if elem, ok := typ.(interface{ Elem() types.Type }); ok {
+					typ = elem.Elem()
+					continue
+				}
+				break
+			}
+			if n, ok := unalias(typ).(*types.Named); ok {
+				named = append(named, n)
+			}
+		}
+	}
+	return named
+}
+
+func BenchmarkDepVersionGoList(b *testing.B) {
+	d, _ := newTestDriver(b)
+	warm := d.newWorkspace(d.ModuleDir, true)
+	pkgs, err := warm.getPackages("example.com/fixture/versioned", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// the named types of each field of the package's types,
+	// some of which are from the same module
+	named := fieldNamedTypes(pkgs[0])
+
+	for _, bc := range []struct {
+		name      string
+		workspace func() workspace
+	}{
+		{name: "cold", workspace: func() workspace { return d.newWorkspace(d.ModuleDir, true) }},
+		{name: "warm", workspace: func() workspace { return warm }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			logFile := fakeGo(b, "")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rb := bc.workspace().representationBuilder()
+				for _, typ := range named {
+					if _, err := rb.getDepVersion(typ); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.StopTimer()
+			calls := len(fakeGoInvocations(b, logFile, "list -json"))
+			b.ReportMetric(float64(calls)/float64(b.N), "runGoList/op")
+		})
+	}
+}
+
+func TestPreloadDepVersions(t *testing.T) {
+	logFile := fakeGo(t, "")
+	d, _ := newTestDriver(t)
+	ws := d.newWorkspace(d.ModuleDir, true)
+	pkgs, err := ws.getPackages("example.com/fixture/versioned", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.preloadDepVersions(pkgs); err != nil {
+		t.Fatal(err)
+	}
+
+	// the versions of the whole import graph are cached
+	for modPath, expect := range map[string]string{
+		"example.com/fixture":    "",
+		"example.com/dep":        "v1.2.0",
+		"example.com/transitive": "v0.3.0",
+	} {
+		if actual, ok := ws.versionCache[modPath]; !ok || actual != expect {
+			t.Errorf("expected version %q of %s to be cached, got %q (cached: %t)", expect, modPath, actual, ok)
+		}
+	}
+
+	// so no versions need to be listed one at a time
+	rb := ws.representationBuilder()
+	for _, typ := range fieldNamedTypes(pkgs[0]) {
+		if _, err := rb.getDepVersion(typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := fakeGoInvocations(t, logFile, "list -json"); len(calls) > 0 {
+		t.Errorf("expected no 'go list' of single packages, got %v", calls)
+	}
+	if calls := fakeGoInvocations(t, logFile, "list -e -json"); len(calls) > 1 {
+		t.Errorf("expected at most one 'go list' of many packages, got %v", calls)
+	}
+}
+
+func BenchmarkPreloadDepVersions(b *testing.B) {
+	d, _ := newTestDriver(b)
+	loaded := d.newWorkspace(d.ModuleDir, true)
+	pkgs, err := loaded.getPackages("example.com/fixture/versioned", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	named := fieldNamedTypes(pkgs[0])
+
+	logFile := fakeGo(b, "")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ws := d.newWorkspace(d.ModuleDir, true)
+		if err := ws.preloadDepVersions(pkgs); err != nil {
+			b.Fatal(err)
+		}
+		rb := ws.representationBuilder()
+		for _, typ := range named {
+			if _, err := rb.getDepVersion(typ); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+	single := len(fakeGoInvocations(b, logFile, "list -json"))
+	multi := len(fakeGoInvocations(b, logFile, "list -e -json"))
+	b.ReportMetric(float64(single)/float64(b.N), "runGoList/op")
+	b.ReportMetric(float64(multi)/float64(b.N), "runGoListMulti/op")
+}
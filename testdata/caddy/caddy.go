@@ -0,0 +1,31 @@
+// Package caddy stands in for core Caddy, with just enough
+// of its API for the plugins in the fixture to be built.
+package caddy
+
+import "encoding/json"
+
+// Config is the top of Caddy's configuration structure.
+type Config struct {
+	// The apps to run, keyed by name.
+	AppsRaw ModuleMap `json:"apps,omitempty" caddy:"namespace="`
+}
+
+// ModuleMap is a map of module names to their raw config.
+type ModuleMap map[string]json.RawMessage
+
+// Module is a type that is used as a Caddy module.
+type Module interface {
+	CaddyModule() ModuleInfo
+}
+
+// ModuleInfo represents a registered Caddy module.
+type ModuleInfo struct {
+	ID  ModuleID
+	New func() Module
+}
+
+// ModuleID is a string that uniquely identifies a Caddy module.
+type ModuleID string
+
+// RegisterModule registers a module.
+func RegisterModule(instance Module) {}
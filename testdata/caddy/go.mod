@@ -0,0 +1,3 @@
+module github.com/caddyserver/caddy/v2
+
+go 1.19
@@ -0,0 +1,9 @@
+// Package dep is a dependency of the fixture, which
+// is required at a version, like a published module.
+package dep
+
+// Limits are limits.
+type Limits struct {
+	// The maximum.
+	Max int `json:"max,omitempty"`
+}
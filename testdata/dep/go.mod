@@ -0,0 +1,5 @@
+module example.com/dep
+
+go 1.19
+
+require example.com/transitive v0.3.0
@@ -0,0 +1,10 @@
+// Package governed has types that are governed by quotas.
+package governed
+
+import "example.com/dep/internal/quota"
+
+// Governed has a field whose type is internal to this module.
+type Governed struct {
+	// The quota.
+	Quota quota.Quota `json:"quota,omitempty"`
+}
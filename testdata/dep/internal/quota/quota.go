@@ -0,0 +1,8 @@
+// Package quota is internal to the dependency.
+package quota
+
+// Quota is a quota.
+type Quota struct {
+	// The amount allowed.
+	Amount int `json:"amount,omitempty"`
+}
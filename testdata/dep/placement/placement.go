@@ -0,0 +1,10 @@
+// Package placement has a type from the standard library.
+package placement
+
+import "image"
+
+// Placement places something.
+type Placement struct {
+	// Where to place it.
+	Origin image.Point `json:"origin,omitempty"`
+}
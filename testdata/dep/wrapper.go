@@ -0,0 +1,9 @@
+package dep
+
+import "example.com/transitive"
+
+// Wrapper wraps settings from a dependency of its own.
+type Wrapper struct {
+	// The wrapped settings.
+	Settings transitive.Settings `json:"settings,omitempty"`
+}
@@ -0,0 +1,20 @@
+// Package aliases has fields whose types are aliases, like any.
+package aliases
+
+// Config has fields that hold arbitrary values.
+type Config struct {
+	// Anything at all.
+	Any any `json:"any,omitempty"`
+
+	// Anything at all, by name.
+	Values map[string]any `json:"values,omitempty"`
+
+	// A list of anything.
+	List []any `json:"list,omitempty"`
+
+	// Anything, through an interface of our own.
+	Empty Empty `json:"empty,omitempty"`
+}
+
+// Empty is an empty interface.
+type Empty interface{}
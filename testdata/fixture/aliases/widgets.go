@@ -0,0 +1,39 @@
+package aliases
+
+import (
+	"example.com/fixture/gizmos"
+	"example.com/fixture/registry"
+)
+
+func init() {
+	registry.RegisterModule(Widget{})
+}
+
+// Widget is a module whose fields have aliased types.
+type Widget struct {
+	// The widget's settings.
+	Settings Settings `json:"settings,omitempty"`
+
+	// The widget's parts.
+	Parts PartList `json:"parts,omitempty"`
+
+	// The widget's main part.
+	Main *MainPart `json:"main,omitempty"`
+}
+
+// Settings can be anything.
+type Settings = any
+
+// PartList is a list of gizmo parts.
+type PartList = []gizmos.Part
+
+// MainPart is a gizmo part.
+type MainPart = gizmos.Part
+
+// CaddyModule returns the module information.
+func (Widget) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.aliases.widget",
+		New: func() registry.Module { return new(Widget) },
+	}
+}
@@ -0,0 +1,16 @@
+// Package apps has types that use modules.
+package apps
+
+import "example.com/fixture/gizmos"
+
+// App holds gizmos.
+type App struct {
+	// The main gizmo.
+	Main *gizmos.Gizmo `json:"main,omitempty" caddy:"namespace=fixture.gizmos inline_key=kind"`
+
+	// More gizmos.
+	More []*gizmos.Gizmo `json:"more,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// A gizmo that is not configured as a module.
+	Plain *gizmos.Gizmo `json:"plain,omitempty"`
+}
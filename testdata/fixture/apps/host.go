@@ -0,0 +1,15 @@
+package apps
+
+import "encoding/json"
+
+// Host has slots for modules of several namespaces.
+type Host struct {
+	// The handler.
+	Handler json.RawMessage `json:"handler,omitempty" caddy:"namespace=fixture.documented inline_key=handler"`
+
+	// Handlers, by name.
+	Handlers map[string]json.RawMessage `json:"handlers,omitempty" caddy:"namespace=fixture.documented"`
+
+	// A slot for modules of a namespace that has none.
+	Unfilled json.RawMessage `json:"unfilled,omitempty" caddy:"namespace=fixture.nothing"`
+}
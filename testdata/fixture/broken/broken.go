@@ -0,0 +1,11 @@
+// Package broken has a type error, on purpose.
+package broken
+
+// Config has a field whose type is not defined.
+type Config struct {
+	// The name of the config.
+	Name string `json:"name,omitempty"`
+
+	// A field of a type that doesn't exist.
+	Broken Missing `json:"broken,omitempty"`
+}
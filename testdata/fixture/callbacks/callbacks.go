@@ -0,0 +1,30 @@
+// Package callbacks has a type with fields that
+// can't be encoded as JSON.
+package callbacks
+
+// Config has callbacks and channels.
+type Config struct {
+	// The name of the config.
+	Name string `json:"name,omitempty"`
+
+	// Called on each event; set in code only.
+	OnEvent func(string) `json:"-"`
+
+	// A callback that is not ignored by the JSON tag.
+	Handler func() error `json:"handler,omitempty"`
+
+	// A named callback.
+	Callback Callback `json:"callback,omitempty"`
+
+	// Several callbacks.
+	Hooks []func() `json:"hooks,omitempty"`
+
+	// Callbacks by name.
+	HooksByName map[string]func() `json:"hooks_by_name,omitempty"`
+
+	// Where events are sent.
+	Events chan string `json:"events,omitempty"`
+}
+
+// Callback is a callback.
+type Callback func()
@@ -0,0 +1,6 @@
+package cgo
+
+import "C"
+
+// WithCgo is only defined when cgo is enabled.
+type WithCgo struct{}
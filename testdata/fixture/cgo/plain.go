@@ -0,0 +1,8 @@
+// Package cgo has files that use cgo, some of which would
+// not be built even if cgo was enabled.
+package cgo
+
+// Plain is defined in a file that does not use cgo.
+type Plain struct {
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,8 @@
+//go:build neverbuilt
+
+package cgo
+
+import "C"
+
+// Tagged is never built, with or without cgo.
+type Tagged struct{}
@@ -0,0 +1,38 @@
+// Package comments has types that are documented in unconventional ways.
+package comments
+
+// Conventional has a proper godoc.
+type Conventional struct{}
+
+type Trailing struct{} // Trailing is documented with a line comment.
+
+// Separated is documented with a comment that is
+// separated from the declaration by a blank line.
+
+type Separated struct{}
+
+// Directed is documented with a comment that is
+// separated from the declaration by a directive.
+
+//nolint:unused
+type Directed struct{}
+
+// This comment could be about either of the types in the block.
+
+type (
+	InBlock      struct{}
+	OtherInBlock struct{}
+)
+
+// Undocumented is documented.
+type Undocumented struct{}
+type Adjacent struct{}
+
+// These types are documented by their block.
+type (
+	GroupedOne struct{}
+	GroupedTwo struct{}
+
+	// GroupedOwn has a doc of its own.
+	GroupedOwn struct{}
+)
@@ -0,0 +1,90 @@
+// Package encodings has types with custom encodings.
+package encodings
+
+// Config has fields with custom encodings.
+type Config struct {
+	// A value with a custom JSON encoding.
+	Custom Custom `json:"custom,omitempty"`
+
+	// A value that is encoded as text.
+	Text Text `json:"text,omitempty"`
+
+	// Gizmos, keyed by their module names.
+	Gizmos Gizmos `json:"gizmos,omitempty"`
+
+	// A value that is encoded as text by its pointer.
+	PointerText PointerText `json:"pointer_text,omitempty"`
+
+	// A value that is encoded as text and as JSON.
+	Both Both `json:"both,omitempty"`
+
+	// A value with a method that's named like a marshaler, but isn't one.
+	Lookalike Lookalike `json:"lookalike,omitempty"`
+}
+
+// Custom has a custom JSON encoding.
+type Custom struct {
+	Value int `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes c as a number.
+func (c Custom) MarshalJSON() ([]byte, error) {
+	return []byte{byte('0' + c.Value%10)}, nil
+}
+
+// Text is encoded as text.
+type Text struct {
+	Value string `json:"value,omitempty"`
+}
+
+// MarshalText encodes t as its value.
+func (t Text) MarshalText() ([]byte, error) {
+	return []byte(t.Value), nil
+}
+
+// Gizmos holds gizmo modules, which it encodes as
+// an object keyed by their module names.
+type Gizmos struct {
+	raw map[string][]byte
+}
+
+// MarshalJSON encodes g as {"module_name": {...}, ...}.
+func (g Gizmos) MarshalJSON() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+// PointerText is encoded as text by its pointer.
+type PointerText struct {
+	Value string `json:"value,omitempty"`
+}
+
+// MarshalText encodes t as its value.
+func (t *PointerText) MarshalText() ([]byte, error) {
+	return []byte(t.Value), nil
+}
+
+// Both has a custom JSON encoding, which is
+// used instead of its text encoding.
+type Both struct {
+	Value int `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes b as a number.
+func (b Both) MarshalJSON() ([]byte, error) {
+	return []byte{byte('0' + b.Value%10)}, nil
+}
+
+// MarshalText encodes b as a digit.
+func (b Both) MarshalText() ([]byte, error) {
+	return []byte{byte('0' + b.Value%10)}, nil
+}
+
+// Lookalike is encoded by its structure.
+type Lookalike struct {
+	Value string `json:"value,omitempty"`
+}
+
+// MarshalText does not implement encoding.TextMarshaler.
+func (l Lookalike) MarshalText() string {
+	return l.Value
+}
@@ -0,0 +1,64 @@
+package encodings
+
+import "encoding/json"
+
+// Raw has fields that hold raw JSON.
+type Raw struct {
+	// A module, as a raw message.
+	Plain json.RawMessage `json:"plain,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// A module, as an alias of a raw message.
+	Aliased ModuleAlias `json:"aliased,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// A module, as a type defined from a raw message.
+	Named NamedRaw `json:"named,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// Just bytes, which aren't a module.
+	Bytes Bytes `json:"bytes,omitempty"`
+}
+
+// ModuleAlias is an alias of json.RawMessage.
+type ModuleAlias = json.RawMessage
+
+// NamedRaw is defined from json.RawMessage.
+type NamedRaw json.RawMessage
+
+// Bytes are some bytes.
+type Bytes []byte
+
+// Containers has modules in nested containers.
+type Containers struct {
+	// A list of module maps.
+	ListOfMaps []map[string]json.RawMessage `json:"list_of_maps,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// Lists of modules, by name.
+	MapOfLists map[string][]json.RawMessage `json:"map_of_lists,omitempty" caddy:"namespace=fixture.gizmos inline_key=gizmo"`
+
+	// A pointer to a list of modules.
+	PointerToList *[]json.RawMessage `json:"pointer_to_list,omitempty" caddy:"namespace=fixture.gizmos inline_key=gizmo"`
+
+	// Lists of lists of module maps.
+	Nested [][]map[string]NamedRaw `json:"nested,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// Modules, keyed by a named string type.
+	ByNamedKey map[ModuleName]NamedRaw `json:"by_named_key,omitempty" caddy:"namespace=fixture.gizmos"`
+
+	// Modules, keyed by a number (which is a string in JSON).
+	ByNumber map[int]json.RawMessage `json:"by_number,omitempty" caddy:"namespace=fixture.gizmos"`
+}
+
+// ModuleName is the name of a module.
+type ModuleName string
+
+// Lists has two fields of the same list type, whose
+// modules are in different namespaces.
+type Lists struct {
+	// Gizmo modules.
+	Gizmos ModuleList `json:"gizmos,omitempty" caddy:"namespace=fixture.gizmos inline_key=gizmo"`
+
+	// Widget modules.
+	Widgets ModuleList `json:"widgets,omitempty" caddy:"namespace=fixture.aliases inline_key=widget"`
+}
+
+// ModuleList is a list of modules.
+type ModuleList []json.RawMessage
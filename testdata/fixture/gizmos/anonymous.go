@@ -0,0 +1,19 @@
+package gizmos
+
+// Anonymous has anonymous structs as the elements of its fields.
+type Anonymous struct {
+	// The steps, in order.
+	Steps []struct {
+		// The name of the step.
+		Name string `json:"name,omitempty"`
+	} `json:"steps,omitempty"`
+
+	// The weights, by name.
+	Weights map[string]struct {
+		// The weight.
+		Weight int `json:"weight,omitempty"`
+
+		// The parts to weigh.
+		Parts []Part `json:"parts,omitempty"`
+	} `json:"weights,omitempty"`
+}
@@ -0,0 +1,10 @@
+package gizmos
+
+// Dashes has fields with dashes in their json tags.
+type Dashes struct {
+	// Ignored by encoding/json.
+	Ignored string `json:"-"`
+
+	// A field that is literally named "-".
+	Dash string `json:"-,"`
+}
@@ -0,0 +1,24 @@
+package gizmos
+
+import "io"
+
+// Embedding embeds interfaces.
+type Embedding struct {
+	// The reader.
+	io.Reader
+
+	describer
+
+	// The payload.
+	Payload
+
+	// The name.
+	Name string `json:"name,omitempty"`
+}
+
+// Payload holds arbitrary JSON.
+type Payload any
+
+type describer interface {
+	Describe() string
+}
@@ -0,0 +1,61 @@
+package gizmos
+
+// Flattened has the fields of some other structs.
+type Flattened struct {
+	// Embedded, so its fields are flattened.
+	Base
+
+	// Embedded, but ignored by its JSON tag.
+	Hidden `json:"-"`
+
+	// Embedded, but named by its JSON tag, so it's a regular field.
+	Named `json:"named,omitempty"`
+
+	// Inlined by its JSON tag, so its fields are flattened.
+	Extra Extra `json:",inline"`
+
+	// Squashed like with mapstructure, so its fields are flattened.
+	Squashed Squashed `mapstructure:",squash"`
+
+	// A regular field, which is not flattened.
+	Nested Nested `json:"nested,omitempty"`
+
+	// The name.
+	Name string `json:"name,omitempty"`
+}
+
+// Base is embedded in Flattened.
+type Base struct {
+	// The ID.
+	ID string `json:"id,omitempty"`
+}
+
+// Extra is inlined in Flattened.
+type Extra struct {
+	// Extra information.
+	Info string `json:"info,omitempty"`
+}
+
+// Squashed is squashed into Flattened.
+type Squashed struct {
+	// How much was squashed.
+	Amount int `json:"amount,omitempty"`
+}
+
+// Nested is a regular field of Flattened.
+type Nested struct {
+	// How deep it is.
+	Depth int `json:"depth,omitempty"`
+}
+
+// Hidden is embedded in Flattened, but ignored.
+type Hidden struct {
+	// A secret.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Named is embedded in Flattened with a name.
+type Named struct {
+	// The value.
+	Value string `json:"value,omitempty"`
+}
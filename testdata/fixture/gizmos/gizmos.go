@@ -0,0 +1,31 @@
+// Package gizmos has a module.
+package gizmos
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Gizmo{})
+}
+
+// Gizmo is a module.
+type Gizmo struct {
+	// The name of the gizmo.
+	Name string `json:"name,omitempty"`
+
+	// The gizmo's parts.
+	Parts []Part `json:"parts,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Gizmo) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.gizmos.gizmo",
+		New: func() registry.Module { return new(Gizmo) },
+	}
+}
+
+// Part is a part of a gizmo.
+type Part struct {
+	// The size of the part.
+	Size int `json:"size,omitempty"`
+}
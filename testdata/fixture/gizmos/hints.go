@@ -0,0 +1,15 @@
+package gizmos
+
+import "encoding/json"
+
+// Hinted has fields with hints in their caddy struct tags.
+type Hinted struct {
+	// The timeout.
+	Timeout int `json:"timeout,omitempty" caddy:"unit=seconds min=1 max=60"`
+
+	// The gizmo to use.
+	Gizmo json.RawMessage `json:"gizmo,omitempty" caddy:"namespace=fixture.gizmos inline_key=gizmo hint=preferred"`
+
+	// A field without a caddy struct tag.
+	Plain string `json:"plain,omitempty"`
+}
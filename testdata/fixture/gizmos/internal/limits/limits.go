@@ -0,0 +1,8 @@
+// Package limits is internal to the fixture.
+package limits
+
+// Limits limit a gizmo.
+type Limits struct {
+	// The maximum number of parts.
+	MaxParts int `json:"max_parts,omitempty"`
+}
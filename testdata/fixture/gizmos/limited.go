@@ -0,0 +1,15 @@
+package gizmos
+
+import (
+	"example.com/dep/governed"
+	"example.com/fixture/gizmos/internal/limits"
+)
+
+// Limited has fields whose types are in internal packages.
+type Limited struct {
+	// Limits from this module.
+	Limits limits.Limits `json:"limits,omitempty"`
+
+	// Something governed by a dependency.
+	Governed governed.Governed `json:"governed,omitempty"`
+}
@@ -0,0 +1,22 @@
+package gizmos
+
+// Maps has maps with different kinds of keys.
+type Maps struct {
+	// Parts by number.
+	ByInt map[int]Part `json:"by_int,omitempty"`
+
+	// Parts by name.
+	ByString map[string]Part `json:"by_string,omitempty"`
+
+	// Parts by kind.
+	ByKind map[Kind]Part `json:"by_kind,omitempty"`
+}
+
+// Kind is a kind of part.
+type Kind string
+
+// The kinds of parts.
+const (
+	KindSmall Kind = "small"
+	KindLarge Kind = "large"
+)
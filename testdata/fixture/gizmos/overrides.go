@@ -0,0 +1,27 @@
+package gizmos
+
+import (
+	"net"
+	"net/netip"
+	"regexp"
+	"time"
+)
+
+// Overridden has fields of types that are
+// simpler in JSON than their structures.
+type Overridden struct {
+	// The address to listen on.
+	Address net.IP `json:"address,omitempty"`
+
+	// The networks to allow.
+	Allow []netip.Prefix `json:"allow,omitempty"`
+
+	// A pattern to match.
+	Pattern *regexp.Regexp `json:"pattern,omitempty"`
+
+	// When to start.
+	Start time.Time `json:"start,omitempty"`
+
+	// A part.
+	Part Part `json:"part,omitempty"`
+}
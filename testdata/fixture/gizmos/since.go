@@ -0,0 +1,19 @@
+package gizmos
+
+// Timed has fields that were added over time. (since v2.5)
+type Timed struct {
+	// The original field.
+	Original string `json:"original,omitempty"`
+
+	// A newer field. (since v2.6)
+	// It is noted in the middle of its doc.
+	Newer string `json:"newer,omitempty"`
+
+	// The newest field.
+	//
+	// (Since: 2.7.0)
+	Newest string `json:"newest,omitempty"`
+
+	// A field with a custom marker. @since v2.8
+	Custom string `json:"custom,omitempty"`
+}
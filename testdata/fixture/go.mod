@@ -0,0 +1,15 @@
+module example.com/fixture
+
+go 1.19
+
+require (
+	example.com/dep v1.2.0
+	example.com/transitive v0.3.0 // indirect
+	github.com/caddyserver/caddy/v2 v2.7.6
+)
+
+replace (
+	example.com/dep => ../dep
+	example.com/transitive => ../transitive
+	github.com/caddyserver/caddy/v2 => ../caddy
+)
@@ -0,0 +1,22 @@
+// Package plugin is a Caddy plugin.
+package plugin
+
+import "github.com/caddyserver/caddy/v2"
+
+func init() {
+	caddy.RegisterModule(Handler{})
+}
+
+// Handler is a module of the plugin.
+type Handler struct {
+	// The greeting to respond with.
+	Greeting string `json:"greeting,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.greeting",
+		New: func() caddy.Module { return new(Handler) },
+	}
+}
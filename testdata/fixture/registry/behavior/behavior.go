@@ -0,0 +1,29 @@
+package behavior
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Handler{})
+}
+
+// Handler has documented methods.
+type Handler struct{}
+
+// CaddyModule returns the module information.
+func (Handler) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.behavior.handler",
+		New: func() registry.Module { return new(Handler) },
+	}
+}
+
+// Provision sets up the handler.
+func (h *Handler) Provision() error { return nil }
+
+// ServeHTTP writes the response.
+func (h Handler) ServeHTTP() error { return nil }
+
+// Cleanup is not a method whose docs are collected.
+func (h *Handler) Cleanup() error { return nil }
+
+func (h *Handler) Validate() error { return nil }
@@ -0,0 +1,5 @@
+// Package behavior has a module whose behavior is
+// documented on its methods.
+//
+// This overview is attached to the package's modules.
+package behavior
@@ -0,0 +1,22 @@
+// Package computed has a module whose
+// ID is computed from its configuration.
+package computed
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Adapter{name: "json"})
+}
+
+// Adapter adapts config, in the format it is named after.
+type Adapter struct {
+	name string
+}
+
+// CaddyModule returns the module information.
+func (a Adapter) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.adapters." + a.name,
+		New: func() registry.Module { return a },
+	}
+}
@@ -0,0 +1,34 @@
+// Package constructors has modules whose New
+// functions construct various types.
+package constructors
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Declared{})
+	registry.RegisterModule(Mismatched{})
+}
+
+// Declared is constructed by a declared function.
+type Declared struct{}
+
+// CaddyModule returns the module information.
+func (Declared) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.constructors.declared",
+		New: newDeclared,
+	}
+}
+
+func newDeclared() registry.Module { return new(Declared) }
+
+// Mismatched's New function constructs another module's type.
+type Mismatched struct{}
+
+// CaddyModule returns the module information.
+func (Mismatched) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.constructors.mismatched",
+		New: func() registry.Module { return new(Declared) },
+	}
+}
@@ -0,0 +1,46 @@
+// Package degraded has modules in a package with
+// type errors, which is thus not built correctly.
+package degraded
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Intact{})
+	registry.RegisterModule(Damaged{})
+}
+
+// Intact is a module that is unaffected by the type errors.
+type Intact struct {
+	// The name.
+	Name string `json:"name,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Intact) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.degraded.intact",
+		New: func() registry.Module { return new(Intact) },
+	}
+}
+
+// Damaged is a module with a field of an undefined type.
+type Damaged struct {
+	// The name.
+	Name string `json:"name,omitempty"`
+
+	// The settings, of a type that doesn't exist.
+	Settings Undefined `json:"settings,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Damaged) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.degraded.damaged",
+		New: func() registry.Module { return new(Damaged) },
+	}
+}
+
+// count has an unrelated type error.
+func count() int {
+	return "one"
+}
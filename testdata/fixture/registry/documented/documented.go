@@ -0,0 +1,33 @@
+// Package documented has notes on its module registrations.
+package documented
+
+import "example.com/fixture/registry"
+
+func init() {
+	// Noted is best used together with other modules.
+	registry.RegisterModule(Noted{})
+
+	registry.RegisterModule(Unnoted{})
+}
+
+// Noted has a note on its registration.
+type Noted struct{}
+
+// CaddyModule returns the module information.
+func (Noted) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.documented.noted",
+		New: func() registry.Module { return new(Noted) },
+	}
+}
+
+// Unnoted has no note on its registration.
+type Unnoted struct{}
+
+// CaddyModule returns the module information.
+func (Unnoted) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.documented.unnoted",
+		New: func() registry.Module { return new(Unnoted) },
+	}
+}
@@ -0,0 +1,32 @@
+// Package generic has a module whose type
+// is an instance of a generic type.
+package generic
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Wrapper[Settings]{})
+}
+
+// Wrapper wraps some config.
+type Wrapper[T any] struct {
+	// The name of the wrapper.
+	Name string `json:"name,omitempty"`
+
+	// The wrapped config.
+	Config T `json:"config,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Wrapper[T]) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.generic.wrapper",
+		New: func() registry.Module { return new(Wrapper[T]) },
+	}
+}
+
+// Settings are the settings that are wrapped.
+type Settings struct {
+	// The timeout, in seconds.
+	Timeout int `json:"timeout,omitempty"`
+}
@@ -0,0 +1,55 @@
+// Package ids has Caddy modules whose IDs
+// are declared apart from their module info.
+package ids
+
+import (
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(Constant{})
+	caddy.RegisterModule(Variable{})
+	caddy.RegisterModule(Computed{})
+}
+
+const constantID caddy.ModuleID = "fixture.ids.constant"
+
+var variableID = caddy.ModuleID("fixture.ids.variable")
+
+var computedID = caddy.ModuleID(strings.ToLower("fixture.ids.Computed"))
+
+// Constant's ID is a typed constant.
+type Constant struct{}
+
+// CaddyModule returns the Caddy module information.
+func (Constant) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  constantID,
+		New: func() caddy.Module { return new(Constant) },
+	}
+}
+
+// Variable's ID is a package variable.
+type Variable struct{}
+
+// CaddyModule returns the Caddy module information.
+func (Variable) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  variableID,
+		New: func() caddy.Module { return new(Variable) },
+	}
+}
+
+// Computed's ID is a package variable, but it
+// is not initialized with a constant.
+type Computed struct{}
+
+// CaddyModule returns the Caddy module information.
+func (Computed) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  computedID,
+		New: func() caddy.Module { return new(Computed) },
+	}
+}
@@ -0,0 +1,35 @@
+// Package impostor registers a type that is a module of
+// the fixture's registry, but not a Caddy module.
+package impostor
+
+import (
+	"example.com/fixture/registry"
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(Genuine{})
+	registry.RegisterModule(Impostor{})
+}
+
+// Genuine is a Caddy module.
+type Genuine struct{}
+
+// CaddyModule returns the Caddy module information.
+func (Genuine) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "fixture.impostor.genuine",
+		New: func() caddy.Module { return new(Genuine) },
+	}
+}
+
+// Impostor's CaddyModule method returns the wrong type.
+type Impostor struct{}
+
+// CaddyModule returns the module information.
+func (Impostor) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.impostor.impostor",
+		New: func() registry.Module { return new(Impostor) },
+	}
+}
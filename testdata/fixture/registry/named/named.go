@@ -0,0 +1,34 @@
+// Package named has modules whose CaddyModule
+// methods have named results.
+package named
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Assigned{})
+	registry.RegisterModule(Unassigned{})
+}
+
+// Assigned assigns its module information to the
+// named result, and returns it with a bare return.
+type Assigned struct{}
+
+// CaddyModule returns the module information.
+func (Assigned) CaddyModule() (info registry.ModuleInfo) {
+	info = registry.ModuleInfo{
+		ID:  "fixture.named.assigned",
+		New: func() registry.Module { return new(Assigned) },
+	}
+	return
+}
+
+// Unassigned sets the fields of its named result one by
+// one, so its module information can't be determined.
+type Unassigned struct{}
+
+// CaddyModule returns the module information.
+func (Unassigned) CaddyModule() (info registry.ModuleInfo) {
+	info.ID = "fixture.named.unassigned"
+	info.New = func() registry.Module { return new(Unassigned) }
+	return
+}
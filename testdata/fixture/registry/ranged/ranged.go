@@ -0,0 +1,51 @@
+// Package ranged registers its modules in loops.
+package ranged
+
+import "example.com/fixture/registry"
+
+var allModules = []registry.Module{
+	First{},
+	new(Second),
+}
+
+func init() {
+	for _, mod := range allModules {
+		registry.RegisterModule(mod)
+	}
+	for _, mod := range []registry.Module{Third{}} {
+		registry.RegisterModule(mod)
+	}
+}
+
+// First is registered from a package-level slice.
+type First struct{}
+
+// CaddyModule returns the module information.
+func (First) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.ranged.first",
+		New: func() registry.Module { return new(First) },
+	}
+}
+
+// Second is registered from a package-level slice, by pointer.
+type Second struct{}
+
+// CaddyModule returns the module information.
+func (*Second) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.ranged.second",
+		New: func() registry.Module { return new(Second) },
+	}
+}
+
+// Third is registered from a slice in the loop itself.
+type Third struct{}
+
+// CaddyModule returns the module information.
+func (Third) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.ranged.third",
+		New: func() registry.Module { return new(Third) },
+	}
+}
@@ -0,0 +1,17 @@
+// Package registry registers modules like caddy.RegisterModule
+// does, so that the fixture need not depend on Caddy.
+package registry
+
+// Module is a module.
+type Module interface {
+	CaddyModule() ModuleInfo
+}
+
+// ModuleInfo describes a module.
+type ModuleInfo struct {
+	ID  string
+	New func() Module
+}
+
+// RegisterModule registers a module.
+func RegisterModule(instance Module) {}
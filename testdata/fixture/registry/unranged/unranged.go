@@ -0,0 +1,26 @@
+// Package unranged registers its modules in a loop
+// over a slice whose elements can't be known.
+package unranged
+
+import "example.com/fixture/registry"
+
+func init() {
+	for _, mod := range modules() {
+		registry.RegisterModule(mod)
+	}
+}
+
+func modules() []registry.Module {
+	return []registry.Module{Hidden{}}
+}
+
+// Hidden is registered, but that can't be told from source.
+type Hidden struct{}
+
+// CaddyModule returns the module information.
+func (Hidden) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.unranged.hidden",
+		New: func() registry.Module { return new(Hidden) },
+	}
+}
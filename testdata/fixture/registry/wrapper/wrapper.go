@@ -0,0 +1,28 @@
+// Package wrapper registers its modules with its own
+// wrapper of the registry's RegisterModule function.
+package wrapper
+
+import "example.com/fixture/registry"
+
+func init() {
+	Register(Wrapped{})
+}
+
+// Register registers a module of this plugin.
+func Register(mod registry.Module) {
+	registry.RegisterModule(mod)
+}
+
+// Wrapped is a module registered with the wrapper.
+type Wrapped struct {
+	// The name of the module.
+	Name string `json:"name,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Wrapped) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.wrapper.wrapped",
+		New: func() registry.Module { return new(Wrapped) },
+	}
+}
@@ -0,0 +1,53 @@
+// Package servers has a config structure with nested
+// structs and module fields.
+package servers
+
+import "encoding/json"
+
+// Server is a server.
+type Server struct {
+	// The addresses to listen on.
+	Listen []string `json:"listen,omitempty"`
+
+	// How long to wait, in nanoseconds.
+	Timeout int64 `json:"timeout,omitempty"`
+
+	// Whether the server is enabled.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The server's TLS settings.
+	TLS *TLS `json:"tls,omitempty"`
+
+	// The handler that handles requests.
+	Handler json.RawMessage `json:"handler,omitempty" caddy:"namespace=fixture.handlers inline_key=handler"`
+
+	// The storage module, which is named by its key.
+	Storage json.RawMessage `json:"storage,omitempty" caddy:"namespace=fixture.storage"`
+
+	// The matchers, keyed by their module name.
+	Matchers map[string]json.RawMessage `json:"matchers,omitempty" caddy:"namespace=fixture.matchers"`
+
+	// Arbitrary metadata.
+	Metadata interface{} `json:"metadata,omitempty"`
+
+	// Labels for the server.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// The server's state, which is not configured.
+	state int
+}
+
+// TLS configures TLS.
+type TLS struct {
+	// The certificates to use.
+	Certificates []Certificate `json:"certificates,omitempty"`
+
+	// The minimum protocol version.
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// Certificate is a certificate.
+type Certificate struct {
+	// The certificate file.
+	File string `json:"file,omitempty"`
+}
@@ -0,0 +1,23 @@
+//go:build fixture_extra
+
+package tagged
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Extra{})
+}
+
+// Extra is only built with the fixture_extra tag.
+type Extra struct {
+	// How much extra.
+	Amount int `json:"amount,omitempty"`
+}
+
+// CaddyModule returns the module information.
+func (Extra) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.tagged.extra",
+		New: func() registry.Module { return new(Extra) },
+	}
+}
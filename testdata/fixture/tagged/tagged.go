@@ -0,0 +1,20 @@
+// Package tagged has a module that is only
+// built with the fixture_extra build tag.
+package tagged
+
+import "example.com/fixture/registry"
+
+func init() {
+	registry.RegisterModule(Basic{})
+}
+
+// Basic is always built.
+type Basic struct{}
+
+// CaddyModule returns the module information.
+func (Basic) CaddyModule() registry.ModuleInfo {
+	return registry.ModuleInfo{
+		ID:  "fixture.tagged.basic",
+		New: func() registry.Module { return new(Basic) },
+	}
+}
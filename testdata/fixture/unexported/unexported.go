@@ -0,0 +1,25 @@
+// Package unexported has types with unexported fields.
+package unexported
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Counter counts things.
+type Counter struct {
+	// The name of the counter.
+	Name string `json:"name,omitempty"`
+
+	// The current count.
+	count int
+
+	// The counter this one was forked from, if any.
+	parent atomic.Pointer[Counter]
+
+	// Opaque data for the counter's owner.
+	data unsafe.Pointer
+
+	// The last counts, oldest first.
+	history [4]int
+}
@@ -0,0 +1,24 @@
+// Package versioned has types from a dependency that is required
+// at a version, so they are stored with that version.
+package versioned
+
+import "example.com/dep"
+
+// Config refers to the same dependency type more than once.
+type Config struct {
+	// The primary limits.
+	Primary dep.Limits `json:"primary,omitempty"`
+
+	// The secondary limits.
+	Secondary *dep.Limits `json:"secondary,omitempty"`
+
+	// All the other limits.
+	Others []dep.Limits `json:"others,omitempty"`
+}
+
+// Wrapped has a type whose own field's type comes from a package
+// that the fixture depends on only transitively.
+type Wrapped struct {
+	// The wrapper.
+	Wrapper dep.Wrapper `json:"wrapper,omitempty"`
+}
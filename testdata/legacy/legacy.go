@@ -0,0 +1,8 @@
+// Package legacy is required at a +incompatible version.
+package legacy
+
+// Options are options.
+type Options struct {
+	// The name.
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,22 @@
+// Package echo is an unpublished Caddy plugin.
+package echo
+
+import "github.com/caddyserver/caddy/v2"
+
+func init() {
+	caddy.RegisterModule(Handler{})
+}
+
+// Handler echoes requests back.
+type Handler struct {
+	// Whether to include the request headers.
+	Headers bool `json:"headers,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.echo",
+		New: func() caddy.Module { return new(Handler) },
+	}
+}
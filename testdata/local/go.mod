@@ -0,0 +1,7 @@
+module example.com/local
+
+go 1.19
+
+require github.com/caddyserver/caddy/v2 v2.7.6
+
+replace github.com/caddyserver/caddy/v2 => ../caddy
@@ -0,0 +1,28 @@
+// Package first is one of the packages of a plugin
+// with modules in several packages.
+package first
+
+import (
+	"github.com/caddyserver/caddy/v2"
+
+	// a module of another plugin, which isn't this plugin's
+	_ "example.com/local/echo"
+)
+
+func init() {
+	caddy.RegisterModule(First{})
+}
+
+// First is the first module.
+type First struct {
+	// A name.
+	Name string `json:"name,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (First) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "multi.first",
+		New: func() caddy.Module { return new(First) },
+	}
+}
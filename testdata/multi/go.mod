@@ -0,0 +1,13 @@
+module example.com/multi
+
+go 1.19
+
+require (
+	example.com/local v0.0.0
+	github.com/caddyserver/caddy/v2 v2.7.6
+)
+
+replace (
+	example.com/local => ../local
+	github.com/caddyserver/caddy/v2 => ../caddy
+)
@@ -0,0 +1,20 @@
+// Package nested is in a module nested within
+// the plugin's, so it is not part of the plugin.
+package nested
+
+import "github.com/caddyserver/caddy/v2"
+
+func init() {
+	caddy.RegisterModule(Nested{})
+}
+
+// Nested is a module of the nested module.
+type Nested struct{}
+
+// CaddyModule returns the Caddy module information.
+func (Nested) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "multi.nested",
+		New: func() caddy.Module { return new(Nested) },
+	}
+}
@@ -0,0 +1,22 @@
+// Package deeper is another package of the plugin.
+package deeper
+
+import "github.com/caddyserver/caddy/v2"
+
+func init() {
+	caddy.RegisterModule(Second{})
+}
+
+// Second is the second module.
+type Second struct {
+	// A size.
+	Size int `json:"size,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Second) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "multi.second",
+		New: func() caddy.Module { return new(Second) },
+	}
+}
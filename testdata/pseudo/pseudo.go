@@ -0,0 +1,8 @@
+// Package pseudo is required at a pseudo-version.
+package pseudo
+
+// Options are options.
+type Options struct {
+	// The size.
+	Size int `json:"size,omitempty"`
+}
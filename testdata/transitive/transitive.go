@@ -0,0 +1,9 @@
+// Package transitive is a dependency of the fixture's
+// dependency, which the fixture does not import itself.
+package transitive
+
+// Settings are settings.
+type Settings struct {
+	// Whether it is verbose.
+	Verbose bool `json:"verbose,omitempty"`
+}
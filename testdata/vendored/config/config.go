@@ -0,0 +1,10 @@
+// Package config is in a module which vendors its dependencies.
+package config
+
+import "example.com/dep"
+
+// Config uses a type from a vendored module.
+type Config struct {
+	// The limits to apply.
+	Limits dep.Limits `json:"limits,omitempty"`
+}
@@ -0,0 +1,12 @@
+module example.com/vendored
+
+go 1.19
+
+require example.com/dep v1.2.0
+
+require example.com/transitive v0.3.0 // indirect
+
+replace (
+	example.com/dep => ../dep
+	example.com/transitive => ../transitive
+)
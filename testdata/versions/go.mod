@@ -0,0 +1,13 @@
+module example.com/versions
+
+go 1.19
+
+require (
+	example.com/legacy v2.0.0+incompatible
+	example.com/pseudo v0.0.0-20220101000000-abcdef123456
+)
+
+replace (
+	example.com/legacy => ../legacy
+	example.com/pseudo => ../pseudo
+)
@@ -0,0 +1,17 @@
+// Package versions uses types from modules that
+// are required at unusual versions.
+package versions
+
+import (
+	"example.com/legacy"
+	"example.com/pseudo"
+)
+
+// Config is a config.
+type Config struct {
+	// Legacy options.
+	Legacy legacy.Options `json:"legacy,omitempty"`
+
+	// Pseudo options.
+	Pseudo *pseudo.Options `json:"pseudo,omitempty"`
+}
@@ -0,0 +1,29 @@
+// Package core is an unpublished module, with a
+// Caddy app that is configured by other modules.
+package core
+
+import "github.com/caddyserver/caddy/v2"
+
+func init() {
+	caddy.RegisterModule(App{})
+}
+
+// App is an app of the core module.
+type App struct {
+	// The default settings.
+	Defaults Settings `json:"defaults,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (App) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "workspace",
+		New: func() caddy.Module { return new(App) },
+	}
+}
+
+// Settings are settings shared by the modules of the workspace.
+type Settings struct {
+	// The timeout, in seconds.
+	Timeout int `json:"timeout,omitempty"`
+}
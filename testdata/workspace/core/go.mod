@@ -0,0 +1,5 @@
+module example.com/workspace/core
+
+go 1.19
+
+require github.com/caddyserver/caddy/v2 v2.7.6
@@ -0,0 +1,8 @@
+module example.com/workspace/plugin
+
+go 1.19
+
+require (
+	example.com/workspace/core v0.1.0
+	github.com/caddyserver/caddy/v2 v2.7.6
+)
@@ -0,0 +1,26 @@
+// Package plugin is an unpublished module, which
+// depends on another one in the same workspace.
+package plugin
+
+import (
+	"example.com/workspace/core"
+	"github.com/caddyserver/caddy/v2"
+)
+
+func init() {
+	caddy.RegisterModule(Handler{})
+}
+
+// Handler is a module of the plugin.
+type Handler struct {
+	// Settings that override the app's defaults.
+	Settings core.Settings `json:"settings,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "workspace.handlers.plugin",
+		New: func() caddy.Module { return new(Handler) },
+	}
+}
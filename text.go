@@ -0,0 +1,106 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DumpText writes a line-oriented text rendering of the value tree v
+// to w. Each value is on its own line, indented according to its depth
+// in the tree, and shows the value's key (for struct fields), type,
+// type name, and other information, followed by a summary of its docs.
+// The output is deterministic, so it is suitable for diffing when
+// reviewing changes to docs. SameAs references are not followed; to
+// dump the complete structure, v should be deeply dereferenced first.
+func DumpText(w io.Writer, v *Value) error {
+	return dumpText(w, v, "", "", 0)
+}
+
+func dumpText(w io.Writer, v *Value, label, doc string, depth int) error {
+	if v == nil {
+		return nil
+	}
+	if doc == "" {
+		doc = v.Doc
+	}
+	line := strings.Repeat("  ", depth) + label + describeValue(v)
+	if summary := docSummary(doc); summary != "" {
+		line += " -- " + summary
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, sf := range v.StructFields {
+		label := sf.Key + ": "
+		if sf.Unexported {
+			label = sf.Key + " (unexported): "
+		}
+		if err := dumpText(w, sf.Value, label, sf.Doc, depth+1); err != nil {
+			return err
+		}
+	}
+	if err := dumpText(w, v.MapKeys, "(keys): ", "", depth+1); err != nil {
+		return err
+	}
+	return dumpText(w, v.Elems, "(elems): ", "", depth+1)
+}
+
+// describeValue returns a one-line description of
+// v, not including its docs or any nested values.
+func describeValue(v *Value) string {
+	var parts []string
+	if v.Type != "" {
+		parts = append(parts, string(v.Type))
+	}
+	if v.GoType != "" {
+		parts = append(parts, "(go: "+string(v.GoType)+")")
+	}
+	if v.TypeName != "" {
+		parts = append(parts, v.TypeName)
+	}
+	if v.SameAs != "" {
+		parts = append(parts, "same_as="+v.SameAs)
+	}
+	if v.ModuleNamespace != nil {
+		parts = append(parts, fmt.Sprintf("namespace=%q", *v.ModuleNamespace))
+	}
+	if v.ModuleInlineKey != nil {
+		parts = append(parts, fmt.Sprintf("inline_key=%q", *v.ModuleInlineKey))
+	}
+	if len(v.EnumValues) > 0 {
+		parts = append(parts, "enum="+strings.Join(v.EnumValues, "|"))
+	}
+	if len(parts) == 0 {
+		return "(any)"
+	}
+	return strings.Join(parts, " ")
+}
+
+// docSummary returns the first sentence (or paragraph,
+// if shorter) of doc, all on one line.
+func docSummary(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if para := strings.Index(doc, "\n\n"); para >= 0 {
+		doc = doc[:para]
+	}
+	doc = strings.Join(strings.Fields(doc), " ")
+	if end := strings.Index(doc, ". "); end >= 0 {
+		doc = doc[:end+1]
+	}
+	return doc
+}
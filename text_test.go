@@ -0,0 +1,85 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpText(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.AddType("example.com/fixture/servers", "Server", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddType("example.com/fixture/gizmos", "Maps", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		typeName string
+		deep     bool
+		golden   string
+	}{
+		{typeName: "example.com/fixture/servers.Server", deep: true, golden: "server.txt"},
+		{typeName: "example.com/fixture/gizmos.Maps", deep: true, golden: "maps.txt"},
+
+		// references are not followed if not dereferenced
+		{typeName: "example.com/fixture/gizmos.Maps", golden: "maps_shallow.txt"},
+	} {
+		var val *Value
+		var err error
+		if tc.deep {
+			val, err = d.ResolveType(tc.typeName, "")
+		} else {
+			pkgPath, typeName := SplitLastDot(tc.typeName)
+			val, err = db.GetTypeByName(pkgPath, typeName, "")
+		}
+		if err != nil {
+			t.Errorf("Test %d (%s): %v", i, tc.typeName, err)
+			continue
+		}
+		var actual bytes.Buffer
+		if err := DumpText(&actual, val); err != nil {
+			t.Fatal(err)
+		}
+
+		goldenFile := filepath.Join("testdata", "text", tc.golden)
+		if *updateGoldenFiles {
+			if err := ioutil.WriteFile(goldenFile, actual.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expect, err := ioutil.ReadFile(goldenFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(actual.Bytes(), expect) {
+			t.Errorf("Test %d (%s): expected dump:\n%s\ngot:\n%s", i, tc.typeName, expect, actual.Bytes())
+		}
+
+		// and the dump is the same every time
+		var again bytes.Buffer
+		if err := DumpText(&again, val); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again.Bytes(), actual.Bytes()) {
+			t.Errorf("Test %d (%s): expected the same dump again, got:\n%s", i, tc.typeName, again.Bytes())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// UpdateModule re-documents the Caddy module with the given ID, which is
+// registered when the package(s) at packagePattern and version are imported,
+// without re-documenting anything else: the module's type, and the types it
+// refers to (directly or indirectly), are built from scratch and stored,
+// but only if they are missing from storage or their representations
+// changed (as told by their fingerprints); other stored types are left
+// alone. Of the packages that are loaded, only those which register the
+// module are built. It returns the updated module.
+func (d *Driver) UpdateModule(moduleID, packagePattern, version string) (*CaddyModule, error) {
+	fresh := NewMemoryStorage()
+	shadow := d.withStorage(fresh)
+	ws, err := shadow.openWorkspace()
+	if err != nil {
+		return nil, fmt.Errorf("opening workspace: %w", err)
+	}
+	defer ws.Close()
+
+	var mod *CaddyModule
+	err = shadow.loadModulesOf(ws, packagePattern, version, shadow.registersModule(moduleID), func(m CaddyModule) error {
+		if m.Name == moduleID && mod == nil {
+			mod = &m
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading modules: %w", err)
+	}
+	if mod == nil {
+		return nil, fmt.Errorf("%w: %s is not registered by %s@%s", ErrModuleNotFound, moduleID, packagePattern, version)
+	}
+
+	if err := d.storeChangedTypes(fresh, mod.Representation, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+
+	// associate the type with the module, like a regular load does
+	for _, st := range fresh.allTypes() {
+		if st.ModuleName != moduleID {
+			continue
+		}
+		pkg := fresh.modulePkgs[fresh.key(st.PackagePath, st.TypeName, st.Version)]
+		if err := d.setCaddyModuleName(pkg, st.TypeName, st.Version, moduleID); err != nil {
+			return nil, fmt.Errorf("saving Caddy module name to type: %w", err)
+		}
+	}
+
+	return mod, nil
+}
+
+// registersModule returns a function which reports whether a package
+// registers the Caddy module with the given ID, judging by its source,
+// so that only the packages which do are built. If the module
+// IDs may be changed by the driver's ModuleFilter, or if the source
+// can't be inspected, every package is reported to register it.
+func (d *Driver) registersModule(moduleID string) func(*packages.Package) bool {
+	return func(pkg *packages.Package) bool {
+		if d.ModuleFilter != nil {
+			return true
+		}
+		modDecls, _, err := d.findCaddyModuleIdents(pkg)
+		if err != nil {
+			return true // loading the package will report the error
+		}
+		for _, modDecl := range modDecls {
+			if modDecl.id == moduleID {
+				return true
+			}
+			for _, id := range modDecl.computedIDs {
+				if id == moduleID {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// storeChangedTypes stores the types in fresh which val refers to, and the
+// types that they refer to in turn, in the driver's storage, if they are
+// not stored already with the same representation. Types in seen (keyed by
+// their SameAs references) are skipped; seen is updated.
+func (d *Driver) storeChangedTypes(fresh *MemoryStorage, val *Value, seen map[string]bool) error {
+	if val == nil {
+		return nil
+	}
+	if sameAs := val.SameAs; sameAs != "" && !seen[sameAs] {
+		seen[sameAs] = true
+		fqtn, version := splitVersion(sameAs)
+		pkgPath, typeName := splitTypeName(fqtn)
+		freshType, _ := fresh.GetTypeByName(pkgPath, typeName, version)
+		if freshType != nil {
+			stored, err := d.db.GetTypeByName(pkgPath, typeName, version)
+			if err != nil {
+				return fmt.Errorf("getting stored type %s: %w", sameAs, err)
+			}
+			if stored == nil || stored.Fingerprint() != freshType.Fingerprint() {
+				if err := d.storeType(pkgPath, typeName, version, freshType); err != nil {
+					return fmt.Errorf("storing type %s: %w", sameAs, err)
+				}
+				d.mu.Lock()
+				delete(d.discoveredTypes, sameAs)
+				d.mu.Unlock()
+			}
+			if err := d.storeChangedTypes(fresh, freshType, seen); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sf := range val.StructFields {
+		if err := d.storeChangedTypes(fresh, sf.Value, seen); err != nil {
+			return err
+		}
+	}
+	if err := d.storeChangedTypes(fresh, val.MapKeys, seen); err != nil {
+		return err
+	}
+	return d.storeChangedTypes(fresh, val.Elems, seen)
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateModule(t *testing.T) {
+	d, db := newTestDriver(t)
+	if _, err := d.LoadModulesFromImportingPackage("example.com/fixture/aliases", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// both modules' stored types become stale
+	for _, typeName := range []struct{ pkgPath, name string }{
+		{"example.com/fixture/gizmos", "Gizmo"},
+		{"example.com/fixture/aliases", "Widget"},
+	} {
+		stale, _ := db.GetTypeByName(typeName.pkgPath, typeName.name, "")
+		if stale == nil {
+			t.Fatalf("%s was not stored", typeName.name)
+		}
+		stale = stale.Clone()
+		stale.Doc = "Stale."
+		if err := db.StoreType(typeName.pkgPath, typeName.name, "", stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var discovered, stored []string
+	d.OnProgress = func(event ProgressEvent) {
+		switch event.Kind {
+		case ProgressModuleDiscovered:
+			discovered = append(discovered, event.ModuleName)
+		case ProgressTypeStored:
+			stored = append(stored, event.PackagePath+"."+event.TypeName)
+		}
+	}
+	mod, err := d.UpdateModule("fixture.aliases.widget", "example.com/fixture/aliases", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Name != "fixture.aliases.widget" {
+		t.Errorf("expected the widget module, got %s", mod.Name)
+	}
+
+	// only the package of the updated module was built, and
+	// only its changed type was stored again (to the driver's
+	// storage; first, every type is stored to a fresh one)
+	if expect := []string{"fixture.aliases.widget"}; !reflect.DeepEqual(discovered, expect) {
+		t.Errorf("expected to discover only %v, got %v", expect, discovered)
+	}
+	if n := len(stored); n == 0 || stored[n-1] != "example.com/fixture/aliases.Widget" {
+		t.Errorf("expected Widget to be stored last, got %v", stored)
+	}
+	for _, typeName := range stored {
+		if typeName == "example.com/fixture/gizmos.Gizmo" {
+			t.Errorf("expected Gizmo not to be built, got %v", stored)
+		}
+	}
+
+	widget, _ := db.GetTypeByName("example.com/fixture/aliases", "Widget", "")
+	if widget == nil || widget.Doc != "Widget is a module whose fields have aliased types." {
+		t.Errorf("expected Widget to be updated, got %+v", widget)
+	}
+	gizmo, _ := db.GetTypeByName("example.com/fixture/gizmos", "Gizmo", "")
+	if gizmo == nil || gizmo.Doc != "Stale." {
+		t.Errorf("expected Gizmo to be left untouched, got %+v", gizmo)
+	}
+	if mods, _ := db.GetTypesByCaddyModuleID("fixture.gizmos.gizmo"); len(mods) != 1 {
+		t.Errorf("expected the gizmo module to still be stored, got %v", mods)
+	}
+}
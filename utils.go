@@ -26,10 +26,9 @@ import (
 // If there is no dot, then before will be empty string and after
 // will be the input. Examples:
 //
-//     "github.com/caddyserver/caddy/v2.Config" => ("github.com/caddyserver/caddy/v2", "Config")
-//     "http.handlers.file_server"              => ("http.handlers", "file_server")
-//     "http"                                   => ("", "http")
-//
+//	"github.com/caddyserver/caddy/v2.Config" => ("github.com/caddyserver/caddy/v2", "Config")
+//	"http.handlers.file_server"              => ("http.handlers", "file_server")
+//	"http"                                   => ("", "http")
 func SplitLastDot(input string) (before, after string) {
 	lastDot := strings.LastIndex(input, ".")
 	if lastDot < 0 {
@@ -40,27 +39,267 @@ func SplitLastDot(input string) (before, after string) {
 	return
 }
 
+// ParseModuleID splits the Caddy module ID id into its namespace,
+// which is everything before the last dot, and its name, which is
+// the last label. A top-level (or app) module ID has no dot, so it
+// is in the root namespace, which is empty string. Examples:
+//
+//	"http.handlers.file_server" => ("http.handlers", "file_server")
+//	"tls"                       => ("", "tls")
+//	""                          => ("", "")
+//
+// See JoinModuleID for the reverse.
+func ParseModuleID(id string) (namespace, name string) {
+	return SplitLastDot(id)
+}
+
+// JoinModuleID returns the ID of the module with the given name in
+// namespace. In the root namespace (empty string), the ID is just
+// the name.
+func JoinModuleID(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// normalizeDoc cleans up doc text so that it renders consistently: it
+// trims trailing whitespace from each line, collapses consecutive blank
+// lines into one, and trims leading and trailing whitespace. Indentation
+// at the start of lines is preserved, since it denotes code blocks.
+func normalizeDoc(doc string) string {
+	lines := strings.Split(doc, "\n")
+	normalized := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" && len(normalized) > 0 && normalized[len(normalized)-1] == "" {
+			continue
+		}
+		normalized = append(normalized, line)
+	}
+	return strings.TrimSpace(strings.Join(normalized, "\n"))
+}
+
+// joinDocs joins the non-empty docs, in order, as
+// separate paragraphs, and normalizes the result. A
+// doc which is already part of the result (e.g. a
+// field doc that was joined with its value's doc
+// before) is not included again.
+func joinDocs(docs ...string) string {
+	var paragraphs []string
+	for _, doc := range docs {
+		if doc = normalizeDoc(doc); doc != "" && !containsDoc(paragraphs, doc) {
+			paragraphs = append(paragraphs, doc)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// containsDoc returns true if doc is one of paragraphs,
+// or a run of consecutive ones (as a joined doc would be).
+func containsDoc(paragraphs []string, doc string) bool {
+	joined := "\n\n" + strings.Join(paragraphs, "\n\n") + "\n\n"
+	return strings.Contains(joined, "\n\n"+doc+"\n\n")
+}
+
+// SourceURL returns a URL to view file, which is relative to the root
+// of the Go module at modulePath, at the given module version. Only
+// well-known source hosts are supported (github.com, gitlab.com, and
+// bitbucket.org); for other hosts, or if version or file is empty,
+// empty string is returned. Examples:
+//
+//	("github.com/caddyserver/caddy/v2", "v2.6.0", "caddy.go")
+//	    => "https://github.com/caddyserver/caddy/blob/v2.6.0/caddy.go"
+//	("github.com/foo/bar/sub", "v0.0.0-20220101000000-abcdef123456", "x.go")
+//	    => "https://github.com/foo/bar/blob/abcdef123456/sub/x.go"
+func SourceURL(modulePath, version, file string) string {
+	if version == "" || file == "" {
+		return ""
+	}
+
+	parts := strings.Split(modulePath, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	repoURL := "https://" + strings.Join(parts[:3], "/")
+
+	// any remaining path elements are the module's directory within the
+	// repo, except for a major version suffix, which (usually) is not
+	subdirParts := parts[3:]
+	if len(subdirParts) > 0 && isMajorVersionSuffix(subdirParts[len(subdirParts)-1]) {
+		subdirParts = subdirParts[:len(subdirParts)-1]
+	}
+	subdir := strings.Join(subdirParts, "/")
+
+	// pseudo-versions refer to a commit; otherwise it's a tag, which is
+	// prefixed by the subdirectory for modules not at the repo root
+	ref := strings.TrimSuffix(version, "+incompatible")
+	if commit := pseudoVersionCommit(ref); commit != "" {
+		ref = commit
+	} else if subdir != "" {
+		ref = subdir + "/" + ref
+	}
+
+	filePath := file
+	if subdir != "" {
+		filePath = subdir + "/" + file
+	}
+
+	switch parts[0] {
+	case "github.com":
+		return repoURL + "/blob/" + ref + "/" + filePath
+	case "gitlab.com":
+		return repoURL + "/-/blob/" + ref + "/" + filePath
+	case "bitbucket.org":
+		return repoURL + "/src/" + ref + "/" + filePath
+	}
+	return ""
+}
+
+// isMajorVersionSuffix returns true if elem is a module
+// path's major version suffix, like "v2".
+func isMajorVersionSuffix(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pseudoVersionCommit returns the commit hash in version
+// if it is a pseudo-version (like "v0.0.0-20191109021931-daa7c04131f5");
+// otherwise it returns empty string.
+func pseudoVersionCommit(version string) string {
+	parts := strings.Split(version, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	commit, timestamp := parts[len(parts)-1], parts[len(parts)-2]
+	if len(commit) != 12 || len(timestamp) < 14 {
+		return ""
+	}
+	timestamp = timestamp[len(timestamp)-14:]
+	for _, r := range timestamp {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	for _, r := range commit {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return ""
+		}
+	}
+	return commit
+}
+
 // ConfigPathParts splits configPath by its separator, the forward
-// slash (/). It also trims leading and trailing slashes.
+// slash (/). Empty segments, such as those from leading, trailing,
+// or repeated slashes, are omitted; for example, "/apps//http/"
+// yields ["apps", "http"]. A segment that contains a slash itself
+// (e.g. a map key that is a file path) escapes it with a backslash,
+// as does a segment that contains a backslash; for example,
+// "files/\/etc\/caddy" yields ["files", "/etc/caddy"]. See
+// JoinConfigPath for the reverse.
 func ConfigPathParts(configPath string) []string {
-	return strings.Split(strings.Trim(configPath, "/"), "/")
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(configPath); i++ {
+		switch c := configPath[i]; {
+		case c == '\\' && i+1 < len(configPath):
+			i++
+			part.WriteByte(configPath[i])
+		case c == '/':
+			if part.Len() > 0 {
+				parts = append(parts, part.String())
+				part.Reset()
+			}
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if part.Len() > 0 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// JoinConfigPath joins parts into a config path, escaping
+// any slashes and backslashes in them, so that
+// ConfigPathParts returns the same parts again.
+func JoinConfigPath(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = escapeConfigPathPart(part)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// escapeConfigPathPart escapes the slashes and backslashes in
+// part, so that it can be a single segment of a config path.
+func escapeConfigPathPart(part string) string {
+	return configPathEscaper.Replace(part)
+}
+
+var configPathEscaper = strings.NewReplacer(`\`, `\\`, "/", `\/`)
+
+// baseTypeName returns typeName without its version,
+// if it has one (i.e. "fqtn@version" becomes "fqtn").
+func baseTypeName(typeName string) string {
+	name, _ := splitVersion(typeName)
+	return name
+}
+
+// splitVersion splits key, which is of the form "name@version" (or
+// just "name"), into its name and version. Keys are split at the first
+// "@", since package paths and type names (even with type arguments)
+// can't contain one, whereas versions may contain more than dots and
+// digits: e.g. "v2.0.0+incompatible", "v1.2.3-pre+build.1", or the
+// pseudo-version "v0.0.0-20220101000000-abcdef123456".
+func splitVersion(key string) (name, version string) {
+	name, version, _ = strings.Cut(key, "@")
+	return name, version
 }
 
 // jsonNameFromTag takes as input the value of an entire struct
-// tag and returns the JSON name of the field, and true if the
-// name is not "-". If the name is "-" (field ignored/excluded
-// by the encoding/json package), then false is returned.
+// tag and returns the JSON name of the field, and true unless
+// the field is ignored/excluded by the encoding/json package,
+// which is when its json tag is exactly "-". As with encoding/json,
+// a tag of "-," (or "-" followed by options) means that the field
+// is literally named "-", so it is not ignored.
 func jsonNameFromTag(tagStr string) (string, bool) {
-	jsonName := reflect.StructTag(tagStr).Get("json")
-	if commaIdx := strings.Index(jsonName, ","); commaIdx > 0 {
-		jsonName = strings.TrimSpace(jsonName[:commaIdx])
-	}
-	if jsonName == "-" {
+	jsonTag := reflect.StructTag(tagStr).Get("json")
+	if jsonTag == "-" {
 		return "", false
 	}
+	jsonName := jsonTag
+	if commaIdx := strings.Index(jsonName, ","); commaIdx >= 0 {
+		jsonName = strings.TrimSpace(jsonName[:commaIdx])
+	}
 	return jsonName, true
 }
 
+// inlineFromTag takes as input the value of an entire struct
+// tag and returns true if the field is explicitly inlined into
+// its parent struct, with an "inline" JSON option or with the
+// mapstructure-style "squash" option, e.g. `json:",inline"`.
+func inlineFromTag(tagStr string) bool {
+	tag := reflect.StructTag(tagStr)
+	for _, key := range []string{"json", "mapstructure"} {
+		opts := strings.Split(tag.Get(key), ",")
+		for _, opt := range opts[1:] {
+			opt = strings.TrimSpace(opt)
+			if opt == "inline" || opt == "squash" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // caddyTagFields parses tagStr which is expected to be the
 // value of an entire struct tag, and returns the individual
 // key-value pairs of the "caddy:" tag.
@@ -69,6 +308,15 @@ func caddyTagFields(tagStr string) (map[string]string, error) {
 	return caddy.ParseStructTag(caddyTag)
 }
 
+// caddyTags returns the parsed caddy tag fields ctf for
+// a StructField, or nil if the tag has no fields.
+func caddyTags(ctf map[string]string) map[string]string {
+	if len(ctf) == 0 {
+		return nil
+	}
+	return ctf
+}
+
 // fullyQualifiedTypeName returns the fully-qualified
 // type name of typ. It must be a named type.
 func fullyQualifiedTypeName(typ types.Type) string {
@@ -90,11 +338,65 @@ func typePackageAndName(typ types.Type) (pkgPath, typeName string) {
 	return "", ""
 }
 
-// localTypeName returns the local type name of typ,
-// which must be a named type.
+// typeArgsSuffix returns the type arguments of typ in brackets, e.g.
+// "[string, example.com/foo.Bar]", if typ is an instance of a generic
+// type; otherwise it returns empty string. The type arguments are part
+// of the identity of an instance, so they are part of its stored name.
+func typeArgsSuffix(typ types.Type) string {
+	nt, ok := typ.(*types.Named)
+	if !ok || nt.TypeArgs().Len() == 0 {
+		return ""
+	}
+	args := make([]string, nt.TypeArgs().Len())
+	for i := range args {
+		args[i] = types.TypeString(nt.TypeArgs().At(i), nil)
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// splitTypeName splits the fully-qualified type name fqtn into its
+// package path and type name. Unlike SplitLastDot, it is not confused
+// by the type arguments of a generic type's instance, which may have
+// dots in them, e.g. "example.com/foo.Box[example.com/bar.Baz]".
+func splitTypeName(fqtn string) (pkgPath, typeName string) {
+	base := fqtn
+	if i := strings.Index(fqtn, "["); i >= 0 {
+		base = fqtn[:i]
+	}
+	lastDot := strings.LastIndex(base, ".")
+	if lastDot < 0 {
+		return "", fqtn
+	}
+	return fqtn[:lastDot], fqtn[lastDot+1:]
+}
+
+// isStandardPackage returns true if the package at pkgPath is in
+// the standard library, which we can tell because the first element
+// of its path has no dot. Such packages come with the toolchain, so
+// they have no module version and need not (and can't) be gotten.
+func isStandardPackage(pkgPath string) bool {
+	firstElem := strings.SplitN(pkgPath, "/", 2)[0]
+	return !strings.Contains(firstElem, ".")
+}
+
+// isInternalPackage returns true if the package at pkgPath is an
+// internal package, i.e. if its path has an "internal" element,
+// which means that only packages in the tree rooted at the parent
+// of that element can import it.
+func isInternalPackage(pkgPath string) bool {
+	for _, elem := range strings.Split(pkgPath, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// localTypeName returns the local type name of typ (with
+// its type arguments, if any), which must be a named type.
 func localTypeName(typ types.Type) string {
 	if nt, ok := typ.(*types.Named); ok {
-		return nt.Obj().Name()
+		return nt.Obj().Name() + typeArgsSuffix(nt)
 	}
 	return ""
 }
@@ -0,0 +1,282 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigPathParts(t *testing.T) {
+	for i, tc := range []struct {
+		input  string
+		expect []string
+	}{
+		{input: "", expect: nil},
+		{input: "/", expect: nil},
+		{input: "//", expect: nil},
+		{input: "apps", expect: []string{"apps"}},
+		{input: "apps/http/servers", expect: []string{"apps", "http", "servers"}},
+
+		// empty segments are omitted
+		{input: "apps//http", expect: []string{"apps", "http"}},
+		{input: "/apps//http/", expect: []string{"apps", "http"}},
+		{input: "apps///http//servers/", expect: []string{"apps", "http", "servers"}},
+		{input: "apps/http/servers//", expect: []string{"apps", "http", "servers"}},
+	} {
+		actual := ConfigPathParts(tc.input)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%q): expected %q, got %q", i, tc.input, tc.expect, actual)
+		}
+	}
+}
+
+func TestConfigPathPartsEscaped(t *testing.T) {
+	for i, tc := range []struct {
+		input  string
+		expect []string
+	}{
+		{input: `files/\/etc\/caddy`, expect: []string{"files", "/etc/caddy"}},
+		{input: `a\\b/c`, expect: []string{`a\b`, "c"}},
+		{input: `a\\/b`, expect: []string{`a\`, "b"}},
+		{input: `\/`, expect: []string{"/"}},
+		{input: `a\`, expect: []string{`a\`}},
+		{input: `a\b`, expect: []string{"ab"}},
+	} {
+		actual := ConfigPathParts(tc.input)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%q): expected %q, got %q", i, tc.input, tc.expect, actual)
+		}
+	}
+}
+
+func TestJoinConfigPath(t *testing.T) {
+	for i, tc := range []struct {
+		parts  []string
+		expect string
+	}{
+		{parts: nil, expect: ""},
+		{parts: []string{"apps", "http"}, expect: "apps/http"},
+		{parts: []string{"files", "/etc/caddy"}, expect: `files/\/etc\/caddy`},
+		{parts: []string{`a\b`, "c"}, expect: `a\\b/c`},
+		{parts: []string{`a\`, "b"}, expect: `a\\/b`},
+		{parts: []string{`\/`}, expect: `\\\/`},
+	} {
+		actual := JoinConfigPath(tc.parts...)
+		if actual != tc.expect {
+			t.Errorf("Test %d: expected %q, got %q", i, tc.expect, actual)
+		}
+
+		// splitting the joined path yields the same parts
+		if parts := ConfigPathParts(actual); len(tc.parts) > 0 && !reflect.DeepEqual(parts, tc.parts) {
+			t.Errorf("Test %d: expected %q to split into %q, got %q", i, actual, tc.parts, parts)
+		}
+	}
+}
+
+func TestSourceURL(t *testing.T) {
+	for i, tc := range []struct {
+		modulePath string
+		version    string
+		file       string
+		expect     string
+	}{
+		{
+			modulePath: "github.com/caddyserver/caddy/v2",
+			version:    "v2.6.0",
+			file:       "caddy.go",
+			expect:     "https://github.com/caddyserver/caddy/blob/v2.6.0/caddy.go",
+		},
+		{
+			modulePath: "github.com/foo/bar",
+			version:    "v1.2.3",
+			file:       "handler/handler.go",
+			expect:     "https://github.com/foo/bar/blob/v1.2.3/handler/handler.go",
+		},
+		{
+			// a module in a subdirectory of its repo has prefixed tags
+			modulePath: "github.com/foo/bar/sub",
+			version:    "v0.3.0",
+			file:       "x.go",
+			expect:     "https://github.com/foo/bar/blob/sub/v0.3.0/sub/x.go",
+		},
+		{
+			modulePath: "github.com/foo/bar/sub",
+			version:    "v0.0.0-20220101000000-abcdef123456",
+			file:       "x.go",
+			expect:     "https://github.com/foo/bar/blob/abcdef123456/sub/x.go",
+		},
+		{
+			modulePath: "github.com/foo/bar",
+			version:    "v2.0.0+incompatible",
+			file:       "x.go",
+			expect:     "https://github.com/foo/bar/blob/v2.0.0/x.go",
+		},
+		{
+			modulePath: "gitlab.com/foo/bar",
+			version:    "v1.0.0",
+			file:       "x.go",
+			expect:     "https://gitlab.com/foo/bar/-/blob/v1.0.0/x.go",
+		},
+
+		// other hosts are not known, so there is no URL
+		{modulePath: "example.com/foo/bar", version: "v1.0.0", file: "x.go", expect: ""},
+		{modulePath: "go.example.org/bar", version: "v1.0.0", file: "x.go", expect: ""},
+
+		// neither is there one for a module replaced by a local
+		// directory, which has no version
+		{modulePath: "github.com/foo/bar", version: "", file: "x.go", expect: ""},
+		{modulePath: "github.com/foo/bar", version: "v1.0.0", file: "", expect: ""},
+	} {
+		actual := SourceURL(tc.modulePath, tc.version, tc.file)
+		if actual != tc.expect {
+			t.Errorf("Test %d (%s@%s %s): expected %q, got %q", i, tc.modulePath, tc.version, tc.file, tc.expect, actual)
+		}
+	}
+}
+
+func TestNormalizeDoc(t *testing.T) {
+	for i, tc := range []struct {
+		input  string
+		expect string
+	}{
+		{input: "", expect: ""},
+		{input: " \n\t\n", expect: ""},
+		{input: "One line.\n", expect: "One line."},
+		{input: "\n\n  Leading and trailing.  \n\n", expect: "Leading and trailing."},
+
+		// trailing whitespace is trimmed from each line
+		{input: "First. \t\nSecond.\r\n", expect: "First.\nSecond."},
+
+		// blank lines are collapsed, even if they have whitespace
+		{input: "First.\n\n\n\nSecond.", expect: "First.\n\nSecond."},
+		{input: "First.\n  \n\t\n \nSecond.", expect: "First.\n\nSecond."},
+
+		// but indentation is preserved, since it denotes code blocks
+		{input: "Example:\n\n\t{\n\t\t\"a\": 1\n\t}\n", expect: "Example:\n\n\t{\n\t\t\"a\": 1\n\t}"},
+	} {
+		if actual := normalizeDoc(tc.input); actual != tc.expect {
+			t.Errorf("Test %d (%q): expected %q, got %q", i, tc.input, tc.expect, actual)
+		}
+	}
+}
+
+func TestJoinDocs(t *testing.T) {
+	for i, tc := range []struct {
+		input  []string
+		expect string
+	}{
+		{input: nil, expect: ""},
+		{input: []string{"", " \n"}, expect: ""},
+		{input: []string{"Field doc.\n", "\n\nType doc.  \n\n\n"}, expect: "Field doc.\n\nType doc."},
+
+		// empty docs don't add blank lines
+		{input: []string{"Field doc.", "", "Type doc."}, expect: "Field doc.\n\nType doc."},
+
+		// and docs which are already included are not repeated
+		{input: []string{"Type doc.", "Type doc.\n"}, expect: "Type doc."},
+		{input: []string{"Field doc.\n\nType doc.", "Type doc."}, expect: "Field doc.\n\nType doc."},
+	} {
+		if actual := joinDocs(tc.input...); actual != tc.expect {
+			t.Errorf("Test %d (%q): expected %q, got %q", i, tc.input, tc.expect, actual)
+		}
+	}
+}
+
+func TestJSONNameFromTag(t *testing.T) {
+	for i, tc := range []struct {
+		tag        string
+		expectName string
+		expectOK   bool
+	}{
+		{tag: ``, expectName: "", expectOK: true},
+		{tag: `json:"name"`, expectName: "name", expectOK: true},
+		{tag: `json:"name,omitempty"`, expectName: "name", expectOK: true},
+		{tag: `json:",omitempty"`, expectName: "", expectOK: true},
+		{tag: `json:",inline"`, expectName: "", expectOK: true},
+		{tag: `json:"-"`, expectName: "", expectOK: false},
+		{tag: `json:"-,"`, expectName: "-", expectOK: true},
+		{tag: `json:"-,omitempty"`, expectName: "-", expectOK: true},
+		{tag: `json:"name" caddy:"namespace=http.handlers"`, expectName: "name", expectOK: true},
+		{tag: `caddy:"namespace=http.handlers"`, expectName: "", expectOK: true},
+	} {
+		name, ok := jsonNameFromTag(tc.tag)
+		if name != tc.expectName || ok != tc.expectOK {
+			t.Errorf("Test %d (%s): expected (%q, %t), got (%q, %t)", i, tc.tag, tc.expectName, tc.expectOK, name, ok)
+		}
+	}
+}
+
+func TestParseModuleID(t *testing.T) {
+	for i, tc := range []struct {
+		id              string
+		expectNamespace string
+		expectName      string
+	}{
+		{id: "", expectNamespace: "", expectName: ""},
+		{id: "tls", expectNamespace: "", expectName: "tls"},
+		{id: "http.handlers.file_server", expectNamespace: "http.handlers", expectName: "file_server"},
+		{id: "caddy.adapters.caddyfile", expectNamespace: "caddy.adapters", expectName: "caddyfile"},
+	} {
+		namespace, name := ParseModuleID(tc.id)
+		if namespace != tc.expectNamespace || name != tc.expectName {
+			t.Errorf("Test %d (%s): expected (%q, %q), got (%q, %q)", i, tc.id, tc.expectNamespace, tc.expectName, namespace, name)
+		}
+
+		// joining the parts yields the same ID again
+		if actual := JoinModuleID(namespace, name); actual != tc.id {
+			t.Errorf("Test %d: expected JoinModuleID to yield %q, got %q", i, tc.id, actual)
+		}
+	}
+}
+
+func TestSplitTypeName(t *testing.T) {
+	for i, tc := range []struct {
+		fqtn          string
+		expectPkgPath string
+		expectName    string
+	}{
+		{fqtn: "Config", expectPkgPath: "", expectName: "Config"},
+		{fqtn: "github.com/caddyserver/caddy/v2.Config", expectPkgPath: "github.com/caddyserver/caddy/v2", expectName: "Config"},
+		{fqtn: "time.Duration", expectPkgPath: "time", expectName: "Duration"},
+		{fqtn: "example.com/foo.Box[example.com/bar.Baz]", expectPkgPath: "example.com/foo", expectName: "Box[example.com/bar.Baz]"},
+		{fqtn: "example.com/foo.Pair[example.com/bar.A, example.com/bar.B]", expectPkgPath: "example.com/foo", expectName: "Pair[example.com/bar.A, example.com/bar.B]"},
+	} {
+		pkgPath, name := splitTypeName(tc.fqtn)
+		if pkgPath != tc.expectPkgPath || name != tc.expectName {
+			t.Errorf("Test %d (%s): expected (%q, %q), got (%q, %q)", i, tc.fqtn, tc.expectPkgPath, tc.expectName, pkgPath, name)
+		}
+	}
+}
+
+func TestSplitVersion(t *testing.T) {
+	for i, tc := range []struct {
+		key           string
+		expectName    string
+		expectVersion string
+	}{
+		{key: "example.com/foo.Bar", expectName: "example.com/foo.Bar", expectVersion: ""},
+		{key: "example.com/foo.Bar@v1.2.3", expectName: "example.com/foo.Bar", expectVersion: "v1.2.3"},
+		{key: "example.com/foo.Bar@v2.0.0+incompatible", expectName: "example.com/foo.Bar", expectVersion: "v2.0.0+incompatible"},
+		{key: "example.com/foo.Bar@v1.2.3-pre+build.1", expectName: "example.com/foo.Bar", expectVersion: "v1.2.3-pre+build.1"},
+		{key: "example.com/foo.Bar@v0.0.0-20220101000000-abcdef123456", expectName: "example.com/foo.Bar", expectVersion: "v0.0.0-20220101000000-abcdef123456"},
+		{key: "example.com/foo.Box[example.com/bar.Baz]@v1.0.0", expectName: "example.com/foo.Box[example.com/bar.Baz]", expectVersion: "v1.0.0"},
+	} {
+		name, version := splitVersion(tc.key)
+		if name != tc.expectName || version != tc.expectVersion {
+			t.Errorf("Test %d (%s): expected (%q, %q), got (%q, %q)", i, tc.key, tc.expectName, tc.expectVersion, name, version)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a problem with a config value.
+type ValidationError struct {
+	// The config path of the value with the problem.
+	Path string `json:"path"`
+
+	// What is wrong with the value.
+	Message string `json:"message"`
+}
+
+func (ve ValidationError) Error() string {
+	return ve.Path + ": " + ve.Message
+}
+
+// ValidateAtPath validates fragment, which is the JSON config at
+// configPath within a Config of the given version, against the
+// type at that path. If the path ends at a module, the module is
+// resolved by its name in the fragment, if it is inline, or else
+// by the last part of the path. Any problems with the fragment are
+// returned as validation errors; a non-nil error means validation
+// could not be performed at all.
+func (d *Driver) ValidateAtPath(configPath, version string, fragment json.RawMessage) ([]ValidationError, error) {
+	start, err := d.configType(version)
+	if err != nil {
+		return nil, err
+	}
+	val, _, err := d.TraverseTypeAt(configPath, start, version)
+	if err != nil {
+		return nil, fmt.Errorf("traversing type: %w", err)
+	}
+	var errs []ValidationError
+	if err := d.validate(strings.Trim(configPath, "/"), val, "", fragment, &errs); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// validate validates data against val, which is at path, and appends
+// any problems to errs. If inlineKey is not empty, it is the key which
+// names the module the value belongs to, so it is allowed in structs.
+func (d *Driver) validate(path string, val *Value, inlineKey string, data json.RawMessage, errs *[]ValidationError) error {
+	if val == nil {
+		return nil
+	}
+	val, err := d.dereference(val)
+	if err != nil {
+		return fmt.Errorf("dereferencing type at %s: %w", path, err)
+	}
+
+	invalid := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil // same as omitting the value
+	}
+
+	switch val.Type {
+	case Bool:
+		var b bool
+		if json.Unmarshal(data, &b) != nil {
+			invalid("expected a boolean")
+		}
+
+	case Int, Uint, Float:
+		var num json.Number
+		if json.Unmarshal(data, &num) != nil {
+			invalid("expected a number")
+			return nil
+		}
+		if msg := checkNumber(val.Type, num.String()); msg != "" {
+			invalid("%s", msg)
+		}
+
+	case String:
+		var s string
+		if json.Unmarshal(data, &s) != nil {
+			invalid("expected a string")
+		}
+
+	case Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			invalid("expected an object")
+			return nil
+		}
+		if val.ModuleInlineKey != nil && inlineKey == "" {
+			inlineKey = *val.ModuleInlineKey
+		}
+	keys:
+		for _, key := range sortedKeys(obj) {
+			if key == inlineKey {
+				continue
+			}
+			for _, sf := range val.StructFields {
+				if sf.Key == key && !sf.Unexported {
+					if err := d.validate(joinPath(path, key), sf.Value, "", obj[key], errs); err != nil {
+						return err
+					}
+					continue keys
+				}
+			}
+			*errs = append(*errs, ValidationError{Path: joinPath(path, key), Message: "unknown field"})
+		}
+
+	case Array:
+		var arr []json.RawMessage
+		if json.Unmarshal(data, &arr) != nil {
+			invalid("expected an array")
+			return nil
+		}
+		for i, elem := range arr {
+			if err := d.validate(joinPath(path, strconv.Itoa(i)), val.Elems, "", elem, errs); err != nil {
+				return err
+			}
+		}
+
+	case Map:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			invalid("expected an object")
+			return nil
+		}
+		for _, key := range sortedKeys(obj) {
+			if val.MapKeys != nil && val.MapKeys.GoType != "" {
+				if msg := checkNumber(val.MapKeys.GoType, key); msg != "" {
+					*errs = append(*errs, ValidationError{Path: joinPath(path, key), Message: "invalid key: " + msg})
+				}
+			}
+			if err := d.validate(joinPath(path, key), val.Elems, "", obj[key], errs); err != nil {
+				return err
+			}
+		}
+
+	case Module:
+		if val.ModuleInlineKey == nil {
+			// the module's name is not in its config,
+			// so there is nothing we can resolve it by
+			return nil
+		}
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			invalid("expected an object")
+			return nil
+		}
+		var moduleName string
+		if json.Unmarshal(obj[*val.ModuleInlineKey], &moduleName) != nil || moduleName == "" {
+			invalid("missing module name in '%s'", *val.ModuleInlineKey)
+			return nil
+		}
+		return d.validateModule(path, val, moduleName, *val.ModuleInlineKey, data, errs)
+
+	case ModuleMap:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil {
+			invalid("expected an object")
+			return nil
+		}
+		var modInlineKey string
+		if val.ModuleInlineKey != nil {
+			modInlineKey = *val.ModuleInlineKey
+		}
+		for _, key := range sortedKeys(obj) {
+			if err := d.validateModule(joinPath(path, key), val, key, modInlineKey, obj[key], errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateModule validates data as the config of the module named
+// moduleName in the namespace of slot, which is the module or module
+// map value in which it appears.
+func (d *Driver) validateModule(path string, slot *Value, moduleName, inlineKey string, data json.RawMessage, errs *[]ValidationError) error {
+	var namespace string
+	if slot.ModuleNamespace != nil {
+		namespace = *slot.ModuleNamespace
+	}
+	moduleID := JoinModuleID(namespace, moduleName)
+	vals, err := d.db.GetTypesByCaddyModuleID(moduleID)
+	if err != nil {
+		return fmt.Errorf("loading type for module %s: %w", moduleID, err)
+	}
+	if len(vals) == 0 {
+		*errs = append(*errs, ValidationError{Path: path, Message: "unknown module: " + moduleID})
+		return nil
+	}
+	return d.validate(path, vals[0], inlineKey, data, errs)
+}
+
+// checkNumber returns a description of why s is not
+// a valid number of type typ, or empty string if it is.
+func checkNumber(typ Type, s string) string {
+	var err error
+	switch typ {
+	case Int:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case Uint:
+		_, err = strconv.ParseUint(s, 10, 64)
+	case Float:
+		_, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil {
+		return fmt.Sprintf("expected %s, got %s", typ, s)
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of obj in sorted
+// order, so that errors are reported consistently.
+func sortedKeys(obj map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
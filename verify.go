@@ -0,0 +1,172 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Verify checks the stored docs for the modules registered when the
+// package(s) at packagePattern and version are imported against their
+// source code. The modules are discovered and their types represented
+// from scratch, exactly like LoadModulesFromImportingPackage does, but
+// without writing to storage; the results are then compared with what
+// is stored. It returns the discrepancies that were found, if any.
+// Stored modules that are no longer registered (ModuleNotRegistered)
+// are only found if the storage can list its types; see
+// ListTypesForPackage.
+func (d *Driver) Verify(packagePattern, version string) ([]Discrepancy, error) {
+	fresh := NewMemoryStorage()
+	shadow := d.withStorage(fresh)
+	if _, err := shadow.LoadModulesFromImportingPackage(packagePattern, version); err != nil {
+		return nil, fmt.Errorf("discovering modules: %w", err)
+	}
+
+	var discrepancies []Discrepancy
+
+	// every type that was represented should be stored the same
+	for _, st := range fresh.allTypes() {
+		moduleName := fresh.moduleNames[fresh.key(st.PackagePath, st.TypeName, st.Version)]
+		stored, err := d.db.GetTypeByName(st.PackagePath, st.TypeName, st.Version)
+		if err != nil {
+			return nil, fmt.Errorf("getting stored type %s.%s@%s: %w", st.PackagePath, st.TypeName, st.Version, err)
+		}
+		discrepancy := Discrepancy{
+			PackagePath: st.PackagePath,
+			TypeName:    st.TypeName,
+			Version:     st.Version,
+			ModuleName:  moduleName,
+		}
+		if stored == nil {
+			discrepancy.Kind = TypeNotStored
+			discrepancies = append(discrepancies, discrepancy)
+			continue
+		}
+		if stored.Fingerprint() != st.Representation.Fingerprint() {
+			discrepancy.Kind = RepresentationChanged
+			discrepancies = append(discrepancies, discrepancy)
+		}
+		if moduleName == "" {
+			continue
+		}
+		storedModules, err := d.getTypesByCaddyModuleID(moduleName, st.Version)
+		if err != nil {
+			return nil, fmt.Errorf("getting stored types of module %s: %w", moduleName, err)
+		}
+		if len(storedModules) == 0 {
+			discrepancy.Kind = ModuleNotStored
+			discrepancies = append(discrepancies, discrepancy)
+		}
+	}
+
+	// and stored modules of the same packages and versions
+	// should still be registered (if that can be told)
+	if d.canListTypes() {
+		versions := make(map[string]map[string]bool)
+		for _, st := range fresh.allTypes() {
+			if versions[st.PackagePath] == nil {
+				versions[st.PackagePath] = make(map[string]bool)
+			}
+			versions[st.PackagePath][st.Version] = true
+		}
+		for pkgPath, pkgVersions := range versions {
+			storedTypes, err := d.ListTypesForPackage(pkgPath)
+			if err != nil {
+				return nil, err
+			}
+			for _, st := range storedTypes {
+				if st.ModuleName == "" || !pkgVersions[st.Version] {
+					continue
+				}
+				if fresh.moduleNames[fresh.key(st.PackagePath, st.TypeName, st.Version)] != st.ModuleName {
+					discrepancies = append(discrepancies, Discrepancy{
+						Kind:        ModuleNotRegistered,
+						PackagePath: st.PackagePath,
+						TypeName:    st.TypeName,
+						Version:     st.Version,
+						ModuleName:  st.ModuleName,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(discrepancies, func(i, j int) bool {
+		a, b := discrepancies[i], discrepancies[j]
+		if a.PackagePath != b.PackagePath {
+			return a.PackagePath < b.PackagePath
+		}
+		if a.TypeName != b.TypeName {
+			return a.TypeName < b.TypeName
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Kind < b.Kind
+	})
+
+	return discrepancies, nil
+}
+
+// withStorage returns a copy of the driver, with the same
+// configuration, but which uses db for storage and has
+// its own cache and state.
+func (d *Driver) withStorage(db Storage) *Driver {
+	d.mu.RLock()
+	shadow := *d
+	d.mu.RUnlock()
+	shadow.db = db
+	shadow.mu = new(sync.RWMutex)
+	shadow.discoveredTypes = make(map[string]*Value)
+	shadow.resolvedCoreVersion = ""
+	shadow.resolvedVersion = ""
+	shadow.fieldErrors = nil
+	shadow.diagnostics = nil
+	return &shadow
+}
+
+// Discrepancy is a difference between the stored docs and the source code.
+type Discrepancy struct {
+	Kind DiscrepancyKind `json:"kind"`
+
+	// The type, and the name of its Caddy module (if any).
+	PackagePath string `json:"package_path"`
+	TypeName    string `json:"type_name"`
+	Version     string `json:"version,omitempty"`
+	ModuleName  string `json:"module_name,omitempty"`
+}
+
+// DiscrepancyKind is a kind of Discrepancy.
+type DiscrepancyKind string
+
+// Kinds of discrepancies.
+const (
+	// The type is in the source, but not stored.
+	TypeNotStored DiscrepancyKind = "type_not_stored"
+
+	// The type's stored representation differs from
+	// the one built from the source.
+	RepresentationChanged DiscrepancyKind = "representation_changed"
+
+	// The module is registered in the source, but
+	// no type is stored for its module ID.
+	ModuleNotStored DiscrepancyKind = "module_not_stored"
+
+	// The type is stored as a module, but the
+	// source does not register it as that module.
+	ModuleNotRegistered DiscrepancyKind = "module_not_registered"
+)
@@ -0,0 +1,118 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// unnamedModulesStorage is a MemoryStorage which does
+// not associate types with their module names.
+type unnamedModulesStorage struct {
+	*MemoryStorage
+}
+
+func (unnamedModulesStorage) SetCaddyModuleNameAt(pkg *packages.Package, typeName, version, modName string) error {
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	const pkgPath = "example.com/fixture/gizmos"
+
+	for i, tc := range []struct {
+		// the storage to store types in, or else memory
+		storage func() Storage
+
+		// whether to store the package's types first
+		store bool
+
+		// changes the stored types before verifying
+		change func(*testing.T, Storage)
+
+		expect []Discrepancy
+	}{
+		{
+			store: true,
+		},
+		{
+			expect: []Discrepancy{
+				{Kind: TypeNotStored, PackagePath: pkgPath, TypeName: "Gizmo", ModuleName: "fixture.gizmos.gizmo"},
+				{Kind: TypeNotStored, PackagePath: pkgPath, TypeName: "Part"},
+			},
+		},
+		{
+			store: true,
+			change: func(t *testing.T, db Storage) {
+				rep, err := db.GetTypeByName(pkgPath, "Part", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				rep = rep.Clone()
+				rep.Doc = "Part is out of date."
+				if err := db.StoreType(pkgPath, "Part", "", rep); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: []Discrepancy{
+				{Kind: RepresentationChanged, PackagePath: pkgPath, TypeName: "Part"},
+			},
+		},
+		{
+			storage: func() Storage { return unnamedModulesStorage{NewMemoryStorage()} },
+			store:   true,
+			expect: []Discrepancy{
+				{Kind: ModuleNotStored, PackagePath: pkgPath, TypeName: "Gizmo", ModuleName: "fixture.gizmos.gizmo"},
+			},
+		},
+		{
+			store: true,
+			change: func(t *testing.T, db Storage) {
+				pkg := &packages.Package{PkgPath: pkgPath}
+				if err := db.SetCaddyModuleName(pkg, "Part", "fixture.gizmos.part"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: []Discrepancy{
+				{Kind: ModuleNotRegistered, PackagePath: pkgPath, TypeName: "Part", ModuleName: "fixture.gizmos.part"},
+			},
+		},
+	} {
+		var db Storage = NewMemoryStorage()
+		if tc.storage != nil {
+			db = tc.storage()
+		}
+		d := newTestDriverWithStorage(t, db)
+		if tc.store {
+			if _, err := d.LoadModulesFromImportingPackage(pkgPath, ""); err != nil {
+				t.Fatalf("Test %d: loading modules: %v", i, err)
+			}
+		}
+		if tc.change != nil {
+			tc.change(t, db)
+		}
+
+		actual, err := d.Verify(pkgPath, "")
+		if err != nil {
+			t.Errorf("Test %d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d: expected discrepancies %+v, got %+v", i, tc.expect, actual)
+		}
+	}
+}
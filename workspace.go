@@ -15,13 +15,21 @@
 package moduledoc
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -31,8 +39,23 @@ type workspace struct {
 	dir    string
 	driver *Driver
 
-	// a memory of whether we already ran 'go get' for a package
-	goGets map[string]struct{}
+	// the go commands run for the workspace, and the
+	// loading of packages, are canceled when ctx is
+	ctx context.Context
+
+	// whether dir is an existing module directory rather than
+	// a temporary one we created; if so, we do not download
+	// modules or remove the directory when done
+	existing bool
+
+	// a memory of whether we already ran 'go get' for a package's
+	// module, and at which version; keyed by module path
+	goGets map[string]string
+
+	// the versions that version queries given to 'go get' which
+	// are not versions themselves (like branch names or commit
+	// hashes) resolved to, keyed by "pattern@query"
+	versionQueries map[string]string
 
 	// stores the mapping of package pattern inputs to the
 	// list of resulting package names; for example:
@@ -44,35 +67,191 @@ type workspace struct {
 	// a cache of parsed packages, keyed by package name/ID/path
 	// and its version.
 	parsedPackages map[string]*packages.Package
+
+	// a cache of the versions of modules in use by the workspace,
+	// keyed by module path (or package path, for packages in the
+	// standard library); shared by all representation builders
+	versionCache map[string]string
 }
 
 func (d *Driver) openWorkspace() (workspace, error) {
-	tempDir, err := ioutil.TempDir("", "caddy_docsys_")
+	return d.openWorkspaceContext(context.Background())
+}
+
+// openWorkspaceContext is like openWorkspace, but the
+// workspace's go commands are canceled when ctx is.
+func (d *Driver) openWorkspaceContext(ctx context.Context) (workspace, error) {
+	if d.ModuleDir != "" {
+		d.progress(ProgressEvent{Kind: ProgressWorkspaceOpened, Dir: d.ModuleDir})
+		ws := d.newWorkspace(d.ModuleDir, true)
+		ws.ctx = ctx
+		return ws, nil
+	}
+
+	tempDir, err := ioutil.TempDir(d.WorkspaceBaseDir, "caddy_docsys_")
 	if err != nil {
 		return workspace{}, err
 	}
 
-	cmd := exec.Command("go", "mod", "init", "temp/docsys")
-	cmd.Dir = tempDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
+	ws := d.newWorkspace(tempDir, false)
+	ws.ctx = ctx
+
+	if d.WorkspaceTemplate != "" {
+		if err := ws.copyTemplate(d.WorkspaceTemplate); err != nil {
+			os.RemoveAll(tempDir)
+			return workspace{}, err
+		}
+	} else {
+		cmd := exec.CommandContext(ctx, "go", "mod", "init", "temp/docsys")
+		cmd.Dir = tempDir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return workspace{}, fmt.Errorf("exec %v: %v", cmd.Args, err)
+		}
+	}
+
+	if len(d.WorkspaceModules) > 0 {
+		if err := ws.useLocalModules(d.WorkspaceModules); err != nil {
+			os.RemoveAll(tempDir)
+			return workspace{}, err
+		}
+	}
+	d.progress(ProgressEvent{Kind: ProgressWorkspaceOpened, Dir: tempDir})
+	return ws, nil
+}
+
+// copyTemplate copies the contents of the template directory into the
+// workspace, and marks the modules its go.mod requires as already
+// gotten (at their required versions), since they are in the build list.
+func (ws workspace) copyTemplate(templateDir string) error {
+	templateDir, err := filepath.Abs(templateDir)
+	if err != nil {
+		return fmt.Errorf("resolving workspace template %s: %w", templateDir, err)
+	}
+	err = filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(ws.dir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, contents, info.Mode().Perm())
+	})
+	if err != nil {
+		return fmt.Errorf("copying workspace template %s: %w", templateDir, err)
+	}
+
+	goMod, err := ws.readGoMod(ws.dir)
+	if err != nil {
+		return err
+	}
+	for _, req := range goMod.Require {
+		ws.goGets[req.Path] = req.Version
+	}
+	return nil
+}
+
+// useLocalModules creates a go.work file for the workspace which uses
+// the modules in dirs along with the workspace's own module, so that
+// those modules, and modules that depend on them, resolve each other
+// from their local copies. They have no versions, and they need not
+// be downloaded, so they are marked as already gotten.
+func (ws workspace) useLocalModules(dirs []string) error {
+	args := []string{"work", "init", "."}
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("resolving module directory %s: %w", dir, err)
+		}
+		modPath, err := ws.localModulePath(absDir)
+		if err != nil {
+			return err
+		}
+		ws.goGets[modPath] = ""
+		args = append(args, absDir)
+	}
+	cmd := exec.CommandContext(ws.ctx, "go", args...)
+	cmd.Dir = ws.dir
+	cmd.Env = ws.env()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("exec %v: %v: %s", cmd.Args, err, out)
+	}
+	return nil
+}
+
+// localModulePath returns the path of the module in dir, from its go.mod.
+func (ws workspace) localModulePath(dir string) (string, error) {
+	goMod, err := ws.readGoMod(dir)
 	if err != nil {
-		os.RemoveAll(tempDir)
-		return workspace{}, fmt.Errorf("exec %v: %v", cmd.Args, err)
+		return "", err
+	}
+	if goMod.Module.Path == "" {
+		return "", fmt.Errorf("go.mod of %s has no module path", dir)
 	}
+	return goMod.Module.Path, nil
+}
 
+// goModFile is the subset of 'go mod edit -json' output that we use.
+type goModFile struct {
+	Module struct {
+		Path string
+	}
+	Require []struct {
+		Path    string
+		Version string
+	}
+}
+
+// readGoMod reads the go.mod file of the module in dir.
+func (ws workspace) readGoMod(dir string) (goModFile, error) {
+	var goMod goModFile
+	cmd := exec.CommandContext(ws.ctx, "go", "mod", "edit", "-json")
+	cmd.Dir = dir
+	cmd.Env = ws.env()
+	out, err := cmd.Output()
+	if err != nil {
+		return goMod, fmt.Errorf("exec %v in %s: %v", cmd.Args, dir, err)
+	}
+	if err := json.Unmarshal(out, &goMod); err != nil {
+		return goMod, fmt.Errorf("decoding go.mod of %s: %v", dir, err)
+	}
+	return goMod, nil
+}
+
+func (d *Driver) newWorkspace(dir string, existing bool) workspace {
 	return workspace{
 		mu:              new(sync.RWMutex),
-		dir:             tempDir,
+		dir:             dir,
 		driver:          d,
-		goGets:          make(map[string]struct{}),
+		ctx:             context.Background(),
+		existing:        existing,
+		goGets:          make(map[string]string),
+		versionQueries:  make(map[string]string),
 		packagePatterns: make(map[string][]string),
 		parsedPackages:  make(map[string]*packages.Package),
-	}, nil
+		versionCache:    make(map[string]string),
+	}
 }
 
 func (ws workspace) Close() error {
+	if ws.existing {
+		return nil
+	}
 	return os.RemoveAll(ws.dir)
 }
 
@@ -87,6 +266,12 @@ func (ws *workspace) getPackages(packagePattern, version string) ([]*packages.Pa
 		return nil, fmt.Errorf("package path is empty")
 	}
 
+	// if the version is a query we already resolved, use the
+	// resolved version, which is what the packages are cached by
+	ws.mu.RLock()
+	version = ws.resolveVersionQuery(packagePattern, version)
+	ws.mu.RUnlock()
+
 	pkgKey := packagePattern
 	if version != "" {
 		pkgKey += "@" + version
@@ -101,42 +286,43 @@ func (ws *workspace) getPackages(packagePattern, version string) ([]*packages.Pa
 	// properly (https://golang.org/issue/40728) - only need to do it once per workspace
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
-	if !ws.alreadyGotModule(packagePattern) {
-		cmd := exec.Command("go", "get", pkgKey)
-		cmd.Dir = ws.dir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
-			return nil, fmt.Errorf("exec %v: %v", cmd.Args, err)
-		}
-
-		// remember that we 'go got' this package's module, so we don't have to do it again
-		pkgInfo, err := runGoList(ws.dir, packagePattern)
-		if err != nil {
-			return nil, fmt.Errorf("listing package to get module: %v", err)
-		}
-		ws.goGets[pkgInfo.Module.Path] = struct{}{}
+	if err := ws.getModule(packagePattern, version); err != nil {
+		return nil, err
 	}
 
 	// finally, load and parse the package
 	cfg := &packages.Config{
-		Dir: ws.dir,
-		Mode: packages.NeedSyntax |
+		Context: ws.ctx,
+		Dir:     ws.dir,
+		Mode: packages.NeedName |
+			packages.NeedSyntax |
 			packages.NeedImports |
 			packages.NeedDeps |
 			packages.NeedTypes |
 			packages.NeedModule |
-			packages.NeedTypesInfo,
-
-		// on Linux, leaving CGO_ENABLED to the default value of 1 would
-		// cause an error: "could not import C (no metadata for C)", but
-		// only on Linux... on my Mac it worked fine either way (ca. 2020)
-		Env: append(os.Environ(), "CGO_ENABLED=0"),
+			packages.NeedTypesInfo |
+			packages.NeedFiles,
+		BuildFlags: ws.buildFlags(),
+		Env:        ws.env(),
 	}
 	pkgs, err := packages.Load(cfg, packagePattern)
 	if err != nil {
-		return nil, fmt.Errorf("packages.Load: %v", err)
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+	for _, pkg := range pkgs {
+		event := ProgressEvent{Kind: ProgressPackageLoaded, PackagePath: pkg.PkgPath}
+		if pkg.Module != nil {
+			event.Version = pkg.Module.Version
+		}
+		ws.driver.progress(event)
+	}
+
+	// a pattern that matches nothing (or only packages without any Go
+	// files to build, e.g. with only tests) has nothing to document; and
+	// don't cache that, since a later load (e.g. of a different version)
+	// might well succeed
+	if !anyBuildablePackage(pkgs) {
+		return nil, fmt.Errorf("%w: pattern '%s' (version '%s')", ErrNoPackages, packagePattern, version)
 	}
 
 	// generate and cache the list of top-level packages from the single input pattern;
@@ -166,9 +352,18 @@ func (ws *workspace) getPackages(packagePattern, version string) ([]*packages.Pa
 			if i > 0 {
 				prefix = "\n"
 			}
-			log.Printf("[WARNING] Load '%s': found error while visiting package on import graph %s: %v - skipping",
+			ws.driver.logger().Printf("[WARNING] Load '%s': found error while visiting package on import graph %s: %v - skipping",
 				packagePattern, prefix, e)
 		}
+
+		// files that use cgo are excluded when cgo is disabled, so
+		// the docs for this package might be incomplete; say so
+		if !ws.driver.EnableCgo {
+			if cgoFiles := ws.skippedCgoFiles(pkg); len(cgoFiles) > 0 {
+				ws.driver.logger().Printf("[WARNING] Load '%s': package %s has files that require cgo, which were skipped because cgo is disabled; docs may be incomplete: %v",
+					packagePattern, pkg.ID, cgoFiles)
+			}
+		}
 	})
 	if err != nil {
 		return nil, err
@@ -177,6 +372,151 @@ func (ws *workspace) getPackages(packagePattern, version string) ([]*packages.Pa
 	return pkgs, nil
 }
 
+// anyBuildablePackage returns true if any of pkgs has Go files to build.
+func anyBuildablePackage(pkgs []*packages.Package) bool {
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getModule downloads the module of the package(s) at packagePattern
+// and version into the workspace, unless it already has it. The
+// caller must hold a write lock on ws.mu.
+func (ws *workspace) getModule(packagePattern, version string) error {
+	version = ws.resolveVersionQuery(packagePattern, version)
+	if ws.existing || ws.alreadyGotModule(packagePattern, version) {
+		return nil
+	}
+
+	// standard library packages are loaded from the toolchain's own
+	// source (GOROOT), so there is no module to get; 'go get' would
+	// fail on their paths
+	if isStandardPackage(packagePattern) {
+		return nil
+	}
+	if ws.vendorMode() {
+		return fmt.Errorf("cannot get %s in vendor mode (-mod=vendor): modules can only be loaded from the vendor directory of an existing module (see ModuleDir)", packagePattern)
+	}
+
+	pkgKey := packagePattern
+	if version != "" {
+		pkgKey += "@" + version
+	}
+	err := ws.goGet(pkgKey)
+	if err != nil {
+		return err
+	}
+
+	// remember that we 'go got' this package's module, so we don't have to do it again
+	pkgInfo, err := ws.runGoList(packagePattern)
+	if err != nil {
+		return fmt.Errorf("listing package to get module: %w", err)
+	}
+	ws.goGets[pkgInfo.Module.Path] = pkgInfo.Module.Version
+	if version != "" && version != pkgInfo.Module.Version {
+		ws.versionQueries[pkgKey] = pkgInfo.Module.Version
+	}
+	ws.driver.progress(ProgressEvent{
+		Kind:        ProgressModuleGotten,
+		PackagePath: packagePattern,
+		Version:     pkgInfo.Module.Version,
+	})
+	return nil
+}
+
+// resolveVersionQuery returns the version that version, when given to
+// 'go get' with packagePattern, resolved to, if it is a version query
+// (like a branch name or a commit hash) that was already resolved;
+// otherwise it returns version as-is. The caller must hold a lock on
+// ws.mu.
+func (ws *workspace) resolveVersionQuery(packagePattern, version string) string {
+	if resolved, ok := ws.versionQueries[packagePattern+"@"+version]; ok {
+		return resolved
+	}
+	return version
+}
+
+// goGet runs 'go get' for pkgKey. If configured, each attempt is
+// subject to a timeout, and failures that look transient (i.e. not
+// an error about the module or package itself) will be retried with
+// exponential backoff, until the workspace's context is canceled.
+func (ws workspace) goGet(pkgKey string) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		stderr, err := ws.goGetOnce(pkgKey)
+		if err == nil {
+			return nil
+		}
+		if attempt >= ws.driver.GoGetRetries || isPermanentGoGetError(stderr) || ws.ctx.Err() != nil {
+			return err
+		}
+		ws.driver.logger().Printf("[WARNING] go get %s failed (attempt %d of %d), retrying in %s: %v",
+			pkgKey, attempt+1, ws.driver.GoGetRetries+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ws.ctx.Done():
+			return ws.ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// goGetOnce runs 'go get' for pkgKey a single time, returning
+// what the command wrote to stderr along with any error.
+func (ws workspace) goGetOnce(pkgKey string) (string, error) {
+	ctx := ws.ctx
+	if ws.driver.GoGetTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ws.driver.GoGetTimeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	args := append([]string{"get"}, ws.buildFlags()...)
+	cmd := exec.CommandContext(ctx, "go", append(args, pkgKey)...)
+	cmd.Dir = ws.dir
+	cmd.Env = ws.env()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	err := cmd.Run()
+	if err := ws.ctx.Err(); err != nil {
+		return stderr.String(), fmt.Errorf("exec %v: %w", cmd.Args, err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return stderr.String(), fmt.Errorf("exec %v: timed out after %s", cmd.Args, ws.driver.GoGetTimeout)
+	}
+	if err != nil {
+		return stderr.String(), fmt.Errorf("exec %v: %v", cmd.Args, err)
+	}
+	return stderr.String(), nil
+}
+
+// isPermanentGoGetError returns true if the stderr output of
+// 'go get' indicates that the requested module, version, or
+// package does not exist (or is invalid), as opposed to some
+// (probably transient) network or proxy error; retrying such
+// errors is futile.
+func isPermanentGoGetError(stderr string) bool {
+	for _, msg := range []string{
+		"no matching versions",
+		"unknown revision",
+		"invalid version",
+		"malformed module path",
+		"does not contain package",
+		"cannot find module providing package",
+		"404 Not Found",
+		"410 Gone",
+	} {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+	return false
+}
+
 // cachedPackages returns the packages cached for the package keyed by
 // pkgKey (which may be in either "pattern" or "pattern@version" form).
 // If not cached, it will return nil or empty list.
@@ -202,11 +542,12 @@ func (ws *workspace) cachedPackages(pkgKey string) []*packages.Package {
 	pkgs := make([]*packages.Package, len(pkgList))
 	for i, pkgKey := range pkgList {
 		pkg, ok := ws.parsedPackages[pkgKey]
-		if !ok {
+		if !ok || len(pkg.Syntax) == 0 {
 			// one of the packages (whether the only package
 			// being requested, or one of them after expansion)
-			// is not cached, so we should not return anything
-			// or the caller will assume we had them all
+			// is not cached (or was only cached as a dependency
+			// without its syntax trees), so we should not return
+			// anything or the caller will assume we had them all
 			return nil
 		}
 		pkgs[i] = pkg
@@ -215,6 +556,125 @@ func (ws *workspace) cachedPackages(pkgKey string) []*packages.Package {
 	return pkgs
 }
 
+// env returns the environment to use for go commands and
+// when loading packages, according to the driver's config.
+func (ws workspace) env() []string {
+	// on Linux, leaving CGO_ENABLED to the default value of 1 would
+	// cause an error: "could not import C (no metadata for C)", but
+	// only on Linux... on my Mac it worked fine either way (ca. 2020)
+	cgoEnabled := "0"
+	if ws.driver.EnableCgo {
+		cgoEnabled = "1"
+	}
+	env := append(os.Environ(), "CGO_ENABLED="+cgoEnabled)
+	return append(env, ws.driver.Env...)
+}
+
+// skippedCgoFiles returns the files of pkg that were ignored by
+// the build and which import "C", i.e. the files that would be
+// part of the package if cgo was enabled. Files that are excluded
+// for other reasons (like build constraints or GOOS/GOARCH file
+// name suffixes) are not returned, nor are any files for packages
+// in the standard library, which only use cgo for alternatives to
+// their pure-Go implementations.
+func (ws workspace) skippedCgoFiles(pkg *packages.Package) []string {
+	if isStandardPackage(pkg.PkgPath) {
+		return nil
+	}
+	ctxt := ws.cgoBuildContext()
+	var cgoFiles []string
+	fset := token.NewFileSet()
+	for _, filename := range pkg.IgnoredFiles {
+		if !strings.HasSuffix(filename, ".go") {
+			continue
+		}
+		dir, name := filepath.Split(filename)
+		if match, err := ctxt.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, imp := range file.Imports {
+			if imp.Path.Value == `"C"` {
+				cgoFiles = append(cgoFiles, filename)
+				break
+			}
+		}
+	}
+	return cgoFiles
+}
+
+// cgoBuildContext returns the context that packages are built
+// with, according to the driver's config, but with cgo enabled.
+func (ws workspace) cgoBuildContext() build.Context {
+	ctxt := build.Default
+	ctxt.CgoEnabled = true
+	ctxt.BuildTags = ws.driver.BuildTags
+	for _, kv := range ws.env() {
+		if strings.HasPrefix(kv, "GOOS=") {
+			ctxt.GOOS = strings.TrimPrefix(kv, "GOOS=")
+		} else if strings.HasPrefix(kv, "GOARCH=") {
+			ctxt.GOARCH = strings.TrimPrefix(kv, "GOARCH=")
+		}
+	}
+	return ctxt
+}
+
+// buildFlags returns the build flags to use with go commands
+// and when loading packages, according to the driver's config.
+func (ws workspace) buildFlags() []string {
+	var flags []string
+	if len(ws.driver.BuildTags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(ws.driver.BuildTags, ","))
+	}
+	if ws.vendorMode() {
+		flags = append(flags, "-mod=vendor")
+	}
+	return flags
+}
+
+// vendorMode returns true if packages are loaded from the vendor
+// directory of the existing module, rather than the module cache:
+// either because the module ships its dependencies in a vendor dir
+// (unless it is part of a go.work, which is incompatible), or because
+// GOFLAGS says so. In vendor mode, nothing can be downloaded; module
+// versions come from the vendored module graph (vendor/modules.txt).
+func (ws workspace) vendorMode() bool {
+	if mode, ok := goFlagsModMode(ws.env()); ok {
+		return mode == "vendor"
+	}
+	if !ws.existing {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(ws.dir, "go.work"))
+	goWork := err == nil
+	info, err := os.Stat(filepath.Join(ws.dir, "vendor"))
+	return err == nil && info.IsDir() && !goWork
+}
+
+// goFlagsModMode returns the value of the -mod flag in the GOFLAGS
+// of env, and true if it is set. As with the go command, the last
+// GOFLAGS in env, and the last -mod flag within it, takes effect.
+func goFlagsModMode(env []string) (string, bool) {
+	var goFlags string
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOFLAGS=") {
+			goFlags = strings.TrimPrefix(kv, "GOFLAGS=")
+		}
+	}
+	var mode string
+	var found bool
+	for _, flag := range strings.Fields(goFlags) {
+		flag = strings.TrimPrefix(strings.TrimPrefix(flag, "-"), "-")
+		if strings.HasPrefix(flag, "mod=") {
+			mode, found = strings.TrimPrefix(flag, "mod="), true
+		}
+	}
+	return mode, found
+}
+
 func packageKey(pkg *packages.Package) string {
 	pkgKey := pkg.ID
 	if pkg.Module != nil && pkg.Module.Version != "" {
@@ -223,12 +683,16 @@ func packageKey(pkg *packages.Package) string {
 	return pkgKey
 }
 
-func (ws workspace) alreadyGotModule(packagePath string) bool {
+// alreadyGotModule returns true if 'go get' was already run for the
+// module containing packagePath. If version is not empty, the module
+// must have been gotten at that version; otherwise we need to get it
+// again, or we would load a different version than what was asked for.
+func (ws workspace) alreadyGotModule(packagePath, version string) bool {
 	parts := strings.Split(packagePath, "/")
 	for i := len(parts); i > 0; i-- {
 		parent := strings.Join(parts[:i], "/")
-		if _, ok := ws.goGets[parent]; ok {
-			return true
+		if gotVersion, ok := ws.goGets[parent]; ok {
+			return version == "" || version == gotVersion
 		}
 	}
 	return false
@@ -236,7 +700,7 @@ func (ws workspace) alreadyGotModule(packagePath string) bool {
 
 func (ws workspace) representationBuilder() representationBuilder {
 	return representationBuilder{
-		ws:           ws,
-		versionCache: make(map[string]string),
+		ws:          ws,
+		absentTypes: make(map[string]bool),
 	}
 }
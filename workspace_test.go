@@ -0,0 +1,502 @@
+// Copyright 2019 Matthew Holt
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moduledoc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestEnvCgoEnabled(t *testing.T) {
+	for i, tc := range []struct {
+		enableCgo bool
+		env       []string
+		expect    string
+	}{
+		{enableCgo: false, expect: "0"},
+		{enableCgo: true, expect: "1"},
+		{enableCgo: false, env: []string{"CGO_ENABLED=1"}, expect: "1"},
+	} {
+		d, _ := newTestDriver(t)
+		d.EnableCgo = tc.enableCgo
+		d.Env = tc.env
+
+		// as with the go command, the last value in env takes effect
+		var actual string
+		for _, kv := range d.newWorkspace(d.ModuleDir, true).env() {
+			if strings.HasPrefix(kv, "CGO_ENABLED=") {
+				actual = strings.TrimPrefix(kv, "CGO_ENABLED=")
+			}
+		}
+		if actual != tc.expect {
+			t.Errorf("Test %d: expected CGO_ENABLED=%s, got %s", i, tc.expect, actual)
+		}
+	}
+}
+
+func TestSkippedCgoFiles(t *testing.T) {
+	d, _ := newTestDriver(t)
+	ws := d.newWorkspace(d.ModuleDir, true)
+
+	pkgs, err := ws.getPackages("example.com/fixture/cgo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(pkgs))
+	}
+
+	// with cgo disabled, both files that import "C" are ignored, but
+	// only one of them would be built if cgo was enabled
+	var ignored []string
+	for _, filename := range pkgs[0].IgnoredFiles {
+		ignored = append(ignored, filepath.Base(filename))
+	}
+	if expect := []string{"cgo.go", "tagged.go"}; !reflect.DeepEqual(ignored, expect) {
+		t.Errorf("expected ignored files %v, got %v", expect, ignored)
+	}
+	var skipped []string
+	for _, filename := range ws.skippedCgoFiles(pkgs[0]) {
+		skipped = append(skipped, filepath.Base(filename))
+	}
+	if expect := []string{"cgo.go"}; !reflect.DeepEqual(skipped, expect) {
+		t.Errorf("expected skipped cgo files %v, got %v", expect, skipped)
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	for i, tc := range []struct {
+		tags   []string
+		expect []string
+	}{
+		{tags: nil, expect: []string{"fixture.tagged.basic"}},
+		{tags: []string{"other"}, expect: []string{"fixture.tagged.basic"}},
+		{tags: []string{"other", "fixture_extra"}, expect: []string{"fixture.tagged.basic", "fixture.tagged.extra"}},
+	} {
+		d, db := newTestDriver(t)
+		d.BuildTags = tc.tags
+		mods, err := d.LoadModulesFromImportingPackage("example.com/fixture/tagged", "")
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		var actual []string
+		for _, mod := range mods {
+			actual = append(actual, mod.Name)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("Test %d (%v): expected modules %v, got %v", i, tc.tags, tc.expect, actual)
+		}
+
+		// the tagged type is documented too
+		extra, _ := db.GetTypeByName("example.com/fixture/tagged", "Extra", "")
+		if stored := extra != nil; stored != (len(tc.expect) == 2) {
+			t.Errorf("Test %d (%v): expected Extra to be stored: %t, got %t", i, tc.tags, !stored, stored)
+		}
+	}
+}
+
+// fakeGo puts a fake go command first in PATH for the rest of the
+// test, and returns the file to which it logs the arguments of each
+// invocation (one line per invocation). The fake runs the shell code
+// in script (in which $LOG is the log file, which already has the
+// current invocation) and then the real go command, if the script
+// does not exit first.
+func fakeGo(t testing.TB, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go command is a shell script")
+	}
+	realGo, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "invocations.log")
+	fake := fmt.Sprintf("#!/bin/sh\nLOG=%q\necho \"$*\" >> \"$LOG\"\n%s\nexec %q \"$@\"\n", logFile, script, realGo)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+// fakeGoInvocations returns the logged invocations of the fake
+// go command (see fakeGo) whose arguments start with prefix.
+func fakeGoInvocations(t testing.TB, logFile, prefix string) []string {
+	t.Helper()
+	data, err := ioutil.ReadFile(logFile)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var invocations []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && strings.HasPrefix(line, prefix) {
+			invocations = append(invocations, line)
+		}
+	}
+	return invocations
+}
+
+func TestGoGetRetries(t *testing.T) {
+	for i, tc := range []struct {
+		failures  int
+		stderr    string
+		retries   int
+		expectErr bool
+
+		// how many times 'go get' is expected to run
+		expectAttempts int
+	}{
+		{failures: 0, stderr: "dial tcp: i/o timeout", retries: 3, expectAttempts: 1},
+		{failures: 2, stderr: "dial tcp: i/o timeout", retries: 3, expectAttempts: 3},
+		{failures: 5, stderr: "dial tcp: i/o timeout", retries: 1, expectAttempts: 2, expectErr: true},
+		{failures: 5, stderr: "dial tcp: i/o timeout", retries: 0, expectAttempts: 1, expectErr: true},
+
+		// a module that doesn't exist is not worth retrying
+		{failures: 1, stderr: "go: example.com/foo@v1.0.0: no matching versions for query", retries: 3, expectAttempts: 1, expectErr: true},
+		{failures: 1, stderr: "reading example.com/foo/@v/v1.0.0.info: 404 Not Found", retries: 3, expectAttempts: 1, expectErr: true},
+	} {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			// the fake fails the first N times go get runs, then succeeds
+			// (without getting anything; the module doesn't exist)
+			logFile := fakeGo(t, fmt.Sprintf(`if [ "$1" = get ]; then
+	if [ "$(grep -c '^get ' "$LOG")" -le %d ]; then echo %q >&2; exit 1; fi
+	exit 0
+fi`, tc.failures, tc.stderr))
+
+			var logs bytes.Buffer
+			d, _ := newTestDriver(t)
+			d.Logger = log.New(&logs, "", 0)
+			d.GoGetRetries = tc.retries
+			ws := d.newWorkspace(t.TempDir(), false)
+
+			err := ws.goGet("example.com/foo@v1.0.0")
+			if tc.expectErr && err == nil {
+				t.Errorf("expected error, got none")
+			} else if !tc.expectErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if attempts := len(fakeGoInvocations(t, logFile, "get ")); attempts != tc.expectAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.expectAttempts, attempts)
+			}
+
+			// each retry is logged
+			if retries := strings.Count(logs.String(), "retrying"); retries != tc.expectAttempts-1 {
+				t.Errorf("expected %d retries to be logged, got %d: %s", tc.expectAttempts-1, retries, logs.String())
+			}
+		})
+	}
+}
+
+func TestVendoredModuleDir(t *testing.T) {
+	// the shell's GOFLAGS would otherwise apply to the go commands
+	t.Setenv("GOFLAGS", "")
+
+	dir, err := filepath.Abs(filepath.Join("testdata", "vendored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		name string
+		env  []string
+	}{
+		{name: "configured", env: []string{"GOFLAGS=-mod=vendor", "GOPROXY=off"}},
+
+		// a module dir with a vendor directory is loaded in vendor mode
+		{name: "detected", env: []string{"GOPROXY=off"}},
+	} {
+		d, db := newTestDriver(t)
+		d.ModuleDir = dir
+		d.Env = tc.env
+
+		ws := d.newWorkspace(d.ModuleDir, true)
+		if !ws.vendorMode() {
+			t.Errorf("Test %d (%s): expected vendor mode", i, tc.name)
+		}
+		if flags := ws.buildFlags(); !reflect.DeepEqual(flags, []string{"-mod=vendor"}) {
+			t.Errorf("Test %d (%s): expected build flags [-mod=vendor], got %v", i, tc.name, flags)
+		}
+		pkgs, err := ws.getPackages("example.com/vendored/config", "")
+		if err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.name, err)
+		}
+
+		// the dependency is loaded from the vendor directory
+		dep := pkgs[0].Imports["example.com/dep"]
+		if dep == nil || len(dep.GoFiles) == 0 {
+			t.Fatalf("Test %d (%s): expected example.com/dep to be loaded, got %+v", i, tc.name, dep)
+		}
+		if rel, _ := filepath.Rel(dir, dep.GoFiles[0]); !strings.HasPrefix(filepath.ToSlash(rel), "vendor/example.com/dep/") {
+			t.Errorf("Test %d (%s): expected example.com/dep to be loaded from the vendor directory, got %s", i, tc.name, dep.GoFiles[0])
+		}
+
+		// and its types are documented at the vendored version
+		if _, err := d.AddType("example.com/vendored/config", "Config", ""); err != nil {
+			t.Fatalf("Test %d (%s): %v", i, tc.name, err)
+		}
+		if rep, _ := db.GetTypeByName("example.com/dep", "Limits", "v1.2.0"); rep == nil {
+			t.Errorf("Test %d (%s): expected example.com/dep.Limits to be stored at v1.2.0", i, tc.name)
+		}
+	}
+}
+
+func TestVendorModeRefusesGet(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	// a new workspace has no vendor directory to load from
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.Env = []string{"GOFLAGS=-mod=vendor", "GOPROXY=off"}
+	_, err := d.AddType("example.com/dep", "Limits", "v1.2.0")
+	if err == nil || !strings.Contains(err.Error(), "cannot get example.com/dep in vendor mode") {
+		t.Errorf("expected an error about vendor mode, got %v", err)
+	}
+}
+
+func TestWorkspaceModules(t *testing.T) {
+	// the shell's GOFLAGS would otherwise apply to the go commands
+	t.Setenv("GOFLAGS", "")
+
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.Env = []string{"GOPROXY=off"}
+	d.WorkspaceModules = []string{
+		filepath.Join("testdata", "caddy"),
+		filepath.Join("testdata", "workspace", "core"),
+		filepath.Join("testdata", "workspace", "plugin"),
+	}
+
+	// the plugin resolves the core module, which is not
+	// published, from the workspace
+	mods, err := d.LoadModulesFromImportingPackage("example.com/workspace/plugin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, mod := range mods {
+		names = append(names, mod.Name)
+	}
+	sort.Strings(names)
+	if expect := []string{"workspace", "workspace.handlers.plugin"}; !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected modules %v, got %v", expect, names)
+	}
+
+	// and both modules' types are stored, unversioned
+	for i, tc := range []struct {
+		pkg, typeName string
+	}{
+		{pkg: "example.com/workspace/plugin", typeName: "Handler"},
+		{pkg: "example.com/workspace/core", typeName: "App"},
+		{pkg: "example.com/workspace/core", typeName: "Settings"},
+	} {
+		if rep, _ := db.GetTypeByName(tc.pkg, tc.typeName, ""); rep == nil {
+			t.Errorf("Test %d: expected %s.%s to be stored", i, tc.pkg, tc.typeName)
+		}
+	}
+	if actual := d.ResolvedVersion(); actual != "" {
+		t.Errorf("expected no resolved version, got %q", actual)
+	}
+}
+
+func TestNoPackages(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	// a module which has no packages yet, except one with only tests
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"go.mod":                      "module example.com/later\n\ngo 1.19\n",
+		"testsonly/testsonly_test.go": "package testsonly\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := New(NewMemoryStorage())
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.ModuleDir = dir
+	ws := d.newWorkspace(dir, true)
+
+	for i, pattern := range []string{"example.com/later/...", "example.com/later/testsonly"} {
+		_, err := ws.getPackages(pattern, "")
+		if !errors.Is(err, ErrNoPackages) {
+			t.Errorf("Test %d (%s): expected ErrNoPackages, got %v", i, pattern, err)
+		} else if !strings.Contains(err.Error(), pattern) {
+			t.Errorf("Test %d (%s): expected the error to name the pattern, got %v", i, pattern, err)
+		}
+	}
+
+	// the empty expansion is not cached, so once
+	// there is a package, the pattern matches it
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "pkg", "pkg.go"), []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := ws.getPackages("example.com/later/...", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buildable []string
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) > 0 {
+			buildable = append(buildable, pkg.PkgPath)
+		}
+	}
+	if expect := []string{"example.com/later/pkg"}; !reflect.DeepEqual(buildable, expect) {
+		t.Errorf("expected buildable packages %v, got %v", expect, buildable)
+	}
+}
+
+// newWorkspaceTemplate returns a workspace template whose go.mod
+// requires the dependency (and its transitive dependency) at their
+// local replacements, so that workspaces built from it need not get
+// any module; it also returns the contents of the go.mod.
+func newWorkspaceTemplate(t *testing.T) (dir, goMod string) {
+	t.Helper()
+	depDir, err := filepath.Abs(filepath.Join("testdata", "dep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transitiveDir, err := filepath.Abs(filepath.Join("testdata", "transitive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = t.TempDir()
+	goMod = fmt.Sprintf(`module temp/docsys
+
+go 1.19
+
+require (
+	example.com/dep v1.2.0
+	example.com/transitive v0.3.0 // indirect
+)
+
+replace (
+	example.com/dep => %s
+	example.com/transitive => %s
+)
+`, depDir, transitiveDir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, goMod
+}
+
+func TestWorkspaceTemplate(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	logFile := fakeGo(t, "")
+
+	// a template that already requires the dependency
+	template, goMod := newWorkspaceTemplate(t)
+
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.Env = []string{"GOPROXY=off"}
+	d.WorkspaceTemplate = template
+	var workspaceDir string
+	d.OnProgress = func(event ProgressEvent) {
+		if event.Kind == ProgressWorkspaceOpened {
+			workspaceDir = event.Dir
+		}
+	}
+
+	if _, err := d.AddType("example.com/dep", "Limits", ""); err != nil {
+		t.Fatal(err)
+	}
+	if rep, _ := db.GetTypeByName("example.com/dep", "Limits", "v1.2.0"); rep == nil {
+		t.Error("expected example.com/dep.Limits to be stored at the template's version")
+	}
+
+	// the workspace is a copy of the template, so no module is
+	// initialized, and what the template requires isn't gotten
+	for _, prefix := range []string{"mod init", "get "} {
+		if invocations := fakeGoInvocations(t, logFile, prefix); len(invocations) > 0 {
+			t.Errorf("expected no '%s' invocations, got %v", prefix, invocations)
+		}
+	}
+
+	// and only the copy is removed afterwards
+	if workspaceDir == "" || workspaceDir == template {
+		t.Fatalf("expected a workspace apart from the template, got %q", workspaceDir)
+	}
+	if _, err := os.Stat(workspaceDir); !os.IsNotExist(err) {
+		t.Errorf("expected the workspace to be removed, got %v", err)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(template, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != goMod {
+		t.Errorf("expected the template to be unchanged, got:\n%s", contents)
+	}
+	if entries, err := ioutil.ReadDir(template); err != nil || len(entries) != 1 {
+		t.Errorf("expected only go.mod in the template, got %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestWorkspaceBaseDir(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	template, _ := newWorkspaceTemplate(t)
+	baseDir := t.TempDir()
+
+	db := NewMemoryStorage()
+	d := New(db)
+	d.Logger = log.New(ioutil.Discard, "", 0)
+	d.Env = []string{"GOPROXY=off"}
+	d.WorkspaceTemplate = template
+	d.WorkspaceBaseDir = baseDir
+	var workspaceDir string
+	d.OnProgress = func(event ProgressEvent) {
+		if event.Kind == ProgressWorkspaceOpened {
+			workspaceDir = event.Dir
+			if _, err := os.Stat(filepath.Join(workspaceDir, "go.mod")); err != nil {
+				t.Errorf("expected the workspace to have a go.mod, got %v", err)
+			}
+		}
+	}
+
+	if _, err := d.AddType("example.com/dep", "Limits", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// the workspace was created beneath the base dir,
+	// and removed from it afterwards
+	if filepath.Dir(workspaceDir) != baseDir {
+		t.Errorf("expected the workspace to be in %s, got %q", baseDir, workspaceDir)
+	}
+	if entries, err := ioutil.ReadDir(baseDir); err != nil || len(entries) != 0 {
+		t.Errorf("expected the base dir to be empty, got %d entries (%v)", len(entries), err)
+	}
+}